@@ -0,0 +1,202 @@
+package channel
+
+import (
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"image"
+	"image/draw"
+)
+
+// Source selects the value of a channel of a swizzled image: one of the channels of the image, or a constant.
+// The zero value is the constant zero.
+type Source struct {
+	channel int // 1 based channel index, 0 for a constant
+	value   float64
+}
+
+var (
+	// Red, Green, Blue and Alpha select a channel of the image, with ordinary (non premultiplied) color values.
+	Red   = Source{channel: 1}
+	Green = Source{channel: 2}
+	Blue  = Source{channel: 3}
+	Alpha = Source{channel: 4}
+
+	// Zero and One are the constants 0.0 and 1.0.
+	Zero = Source{}
+	One  = Source{value: 1.0}
+)
+
+// Fill returns the source with the constant value v.
+func Fill(v float64) Source {
+	return Source{value: v}
+}
+
+func (s Source) pick(c floatcolor.NRGBAF64) float64 {
+	switch s.channel {
+	case 1:
+		return c.R
+	case 2:
+		return c.G
+	case 3:
+		return c.B
+	case 4:
+		return c.A
+	default:
+		return s.value
+	}
+}
+
+// Constant returns an image with the value v everywhere, to fill a channel with a constant in Merge.
+func Constant(v float64) image.Image {
+	return image.NewUniform(floatcolor.GrayF64{Y: v})
+}
+
+// nrgbaf64Image is implemented by all float image types, see the typed accessors of package floatimage.
+type nrgbaf64Image interface {
+	floatimage.FloatImage
+	draw.Image
+	NRGBAF64At(x, y int) floatcolor.NRGBAF64
+	SetNRGBAF64(x, y int, c floatcolor.NRGBAF64)
+}
+
+// reader returns a function reading pixels of img as floatcolor.NRGBAF64, directly for float images.
+func reader(img image.Image) func(x, y int) floatcolor.NRGBAF64 {
+	if p, ok := img.(nrgbaf64Image); ok {
+		return p.NRGBAF64At
+	}
+	return func(x, y int) floatcolor.NRGBAF64 {
+		return floatcolor.NRGBAF64Model.Convert(img.At(x, y)).(floatcolor.NRGBAF64)
+	}
+}
+
+// planeReader returns a function reading the values of a single channel plane, directly for GrayF64 images.
+// Other images give their gray value (see floatcolor.GrayF64Model), and a nil plane gives fill.
+func planeReader(plane image.Image, fill float64) func(x, y int) float64 {
+	switch p := plane.(type) {
+	case nil:
+		return func(x, y int) float64 { return fill }
+	case *floatimage.GrayF64:
+		return func(x, y int) float64 { return p.GrayF64At(x, y).Y }
+	default:
+		return func(x, y int) float64 {
+			return floatcolor.GrayF64Model.Convert(plane.At(x, y)).(floatcolor.GrayF64).Y
+		}
+	}
+}
+
+// Split returns the red, green, blue and alpha channels of the image as separate GrayF64 images with the bounds of the image.
+// The color channels hold ordinary (non premultiplied) values, whatever the image stores, so e.g. the alpha plane
+// is the mask of the image. Premultiplied pixels with zero alpha give zero color values. Gray images give three
+// identical planes and an opaque alpha plane.
+func Split(img image.Image) [4]*floatimage.GrayF64 {
+	return split(img, false)
+}
+
+// SplitPremultiplied is like Split, but the color channels hold values premultiplied with alpha.
+func SplitPremultiplied(img image.Image) [4]*floatimage.GrayF64 {
+	return split(img, true)
+}
+
+func split(img image.Image, premultiplied bool) [4]*floatimage.GrayF64 {
+	r := img.Bounds()
+	var planes [4]*floatimage.GrayF64
+	for i := range planes {
+		planes[i] = floatimage.NewGrayF64WithBounds(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+	}
+
+	at := reader(img)
+	floatimage.ForEachBand(r, 0, func(band image.Rectangle) {
+		for y := band.Min.Y; y < band.Max.Y; y++ {
+			rows := [4][]float64{planes[0].Row(y), planes[1].Row(y), planes[2].Row(y), planes[3].Row(y)}
+			for x := band.Min.X; x < band.Max.X; x++ {
+				c := at(x, y)
+				if premultiplied {
+					c.R, c.G, c.B = floatcolor.Premultiply(c.R, c.G, c.B, c.A)
+				}
+				i := x - r.Min.X
+				rows[0][i], rows[1][i], rows[2][i], rows[3][i] = c.R, c.G, c.B, c.A
+			}
+		}
+	})
+
+	return planes
+}
+
+// Merge returns a new float image of the same type as like (see floatimage.NewLike) with the red, green, blue and
+// alpha channels taken from the given planes, which hold ordinary (non premultiplied) values. The planes are usually
+// GrayF64 images, e.g. from Split, other images contribute their gray value. A nil plane fills its channel with zero
+// (one for alpha), Constant fills it with any value. Merging into premultiplied image types multiplies the color
+// values with alpha, merging into a gray image gives the luminance of the color composited over black.
+//
+// The bounds of the result are the intersection of the bounds of the planes, or the bounds of like if all planes
+// are nil or constant. Like only needs to be of the wanted type, e.g. floatimage.NewRGBAF32(0, 0).
+func Merge(like image.Image, red, green, blue, alpha image.Image) floatimage.FloatImage {
+	return merge(like, [4]image.Image{red, green, blue, alpha}, false)
+}
+
+// MergePremultiplied is like Merge, but the color planes hold values premultiplied with alpha, e.g. from SplitPremultiplied.
+// Merging into image types with ordinary alpha divides the color values by alpha following the zero alpha policy
+// (see floatcolor.SetZeroAlphaPolicy), pixels with zero alpha become transparent black.
+func MergePremultiplied(like image.Image, red, green, blue, alpha image.Image) floatimage.FloatImage {
+	return merge(like, [4]image.Image{red, green, blue, alpha}, true)
+}
+
+func merge(like image.Image, planes [4]image.Image, premultiplied bool) floatimage.FloatImage {
+	var r image.Rectangle
+	bounded := false
+	var values [4]func(x, y int) float64
+	for i, plane := range planes {
+		if _, uniform := plane.(*image.Uniform); plane != nil && !uniform {
+			if bounded {
+				r = r.Intersect(plane.Bounds())
+			} else {
+				r, bounded = plane.Bounds(), true
+			}
+		}
+		fill := 0.0
+		if i == 3 {
+			fill = 1.0
+		}
+		values[i] = planeReader(plane, fill)
+	}
+	if !bounded {
+		r = like.Bounds()
+	}
+
+	result := floatimage.NewLike(like, r).(nrgbaf64Image)
+	floatimage.ForEachBand(r, 0, func(band image.Rectangle) {
+		for y := band.Min.Y; y < band.Max.Y; y++ {
+			for x := band.Min.X; x < band.Max.X; x++ {
+				c := floatcolor.NRGBAF64{R: values[0](x, y), G: values[1](x, y), B: values[2](x, y), A: values[3](x, y)}
+				if premultiplied {
+					c.R, c.G, c.B = floatcolor.Unpremultiply(c.R, c.G, c.B, c.A)
+				}
+				result.SetNRGBAF64(x, y, c)
+			}
+		}
+	})
+
+	return result
+}
+
+// Swizzle returns a new float image of the same type as img (see floatimage.NewLike) whose red, green, blue and alpha
+// channels are taken from the given sources, e.g. Swizzle(img, Blue, Green, Red, Alpha) swaps red and blue and
+// Swizzle(img, Red, Green, Blue, One) makes the image opaque. Channels are shuffled with ordinary (non premultiplied) values,
+// so premultiplied images are unpremultiplied before and premultiplied with the new alpha after the shuffle.
+// Gray images are read as opaque gray colors and written as the luminance of the swizzled color.
+func Swizzle(img image.Image, red, green, blue, alpha Source) floatimage.FloatImage {
+	r := img.Bounds()
+	at := reader(img)
+	result := floatimage.NewLike(img, r).(nrgbaf64Image)
+
+	floatimage.ForEachBand(r, 0, func(band image.Rectangle) {
+		for y := band.Min.Y; y < band.Max.Y; y++ {
+			for x := band.Min.X; x < band.Max.X; x++ {
+				c := at(x, y)
+				result.SetNRGBAF64(x, y, floatcolor.NRGBAF64{R: red.pick(c), G: green.pick(c), B: blue.pick(c), A: alpha.pick(c)})
+			}
+		}
+	})
+
+	return result
+}
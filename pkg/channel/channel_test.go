@@ -0,0 +1,148 @@
+package channel
+
+import (
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func testImage() *floatimage.NRGBAF64 {
+	img := floatimage.NewNRGBAF64WithBounds(-2, 3, 5, 7)
+	for i := range img.Pix {
+		img.Pix[i] = float64(i%17) / 16
+	}
+	return img
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-12
+}
+
+// nearColor compares colors with float32 precision.
+func nearColor(c1, c2 floatcolor.NRGBAF64) bool {
+	return math.Abs(c1.R-c2.R) < 1e-6 && math.Abs(c1.G-c2.G) < 1e-6 && math.Abs(c1.B-c2.B) < 1e-6 && math.Abs(c1.A-c2.A) < 1e-6
+}
+
+func TestSplitMerge(t *testing.T) {
+	img := testImage()
+	planes := Split(img)
+
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			c := img.NRGBAF64At(x, y)
+			if planes[0].GrayF64At(x, y).Y != c.R || planes[1].GrayF64At(x, y).Y != c.G ||
+				planes[2].GrayF64At(x, y).Y != c.B || planes[3].GrayF64At(x, y).Y != c.A {
+				t.Fatalf("expected channels of %+v at (%d, %d)", c, x, y)
+			}
+		}
+	}
+
+	merged := Merge(img, planes[0], planes[1], planes[2], planes[3]).(*floatimage.NRGBAF64)
+	if merged.Rect != img.Rect {
+		t.Fatalf("expected bounds %v but got %v", img.Rect, merged.Rect)
+	}
+	for i := range img.Pix {
+		if merged.Pix[i] != img.Pix[i] {
+			t.Fatalf("expected split and merged image to be the same at %d", i)
+		}
+	}
+
+	// Split and merge premultiplied images
+	premultiplied := floatimage.FromRGBAF64(floatimage.ToRGBAF64(img), floatimage.NewRGBAF32(0, 0))
+	planes = Split(premultiplied)
+	merged = Merge(img, planes[0], planes[1], planes[2], planes[3]).(*floatimage.NRGBAF64)
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			c, m := img.NRGBAF64At(x, y), merged.NRGBAF64At(x, y)
+			if c.A != 0 && (math.Abs(c.R-m.R) > 1e-6 || math.Abs(c.G-m.G) > 1e-6 || math.Abs(c.B-m.B) > 1e-6 || math.Abs(c.A-m.A) > 1e-6) {
+				t.Fatalf("expected unpremultiplied planes of %+v at (%d, %d) but got %+v", c, x, y, m)
+			}
+		}
+	}
+
+	planes = SplitPremultiplied(img)
+	rgba := MergePremultiplied(floatimage.NewRGBAF64(0, 0), planes[0], planes[1], planes[2], planes[3]).(*floatimage.RGBAF64)
+	expected := floatimage.ToRGBAF64(img)
+	for i := range expected.Pix {
+		if !near(rgba.Pix[i], expected.Pix[i]) {
+			t.Fatalf("expected premultiplied planes to be merged as they are at %d", i)
+		}
+	}
+}
+
+func TestMergeFill(t *testing.T) {
+	img := testImage()
+	planes := Split(img)
+	mask := floatimage.NewGrayF64WithBounds(0, 0, 10, 10)
+	for i := range mask.Pix {
+		mask.Pix[i] = 0.5
+	}
+
+	// Gray image with constant red and blue, merged into a premultiplied image
+	merged := Merge(floatimage.NewRGBAF64(0, 0), Constant(0.25), planes[1], nil, mask).(*floatimage.RGBAF64)
+	if merged.Rect != image.Rect(0, 3, 5, 7) {
+		t.Fatalf("expected intersection of the plane bounds but got %v", merged.Rect)
+	}
+	c := img.NRGBAF64At(1, 4)
+	if m := merged.RGBAF64At(1, 4); !near(m.R, 0.125) || !near(m.G, c.G*0.5) || m.B != 0 || m.A != 0.5 {
+		t.Errorf("expected color premultiplied with the mask but got %+v", m)
+	}
+
+	// Missing alpha is opaque, other images contribute their gray value
+	gray := Merge(img, image.NewUniform(color.White), nil, nil, nil).(*floatimage.NRGBAF64)
+	if m := gray.NRGBAF64At(-2, 3); m != (floatcolor.NRGBAF64{R: 1.0, A: 1.0}) {
+		t.Errorf("expected opaque red but got %+v", m)
+	}
+
+	// Premultiplied planes with zero alpha give zero color
+	transparent := MergePremultiplied(img, Constant(0.5), nil, nil, Constant(0)).(*floatimage.NRGBAF64)
+	if m := transparent.NRGBAF64At(0, 4); m != (floatcolor.NRGBAF64{}) {
+		t.Errorf("expected transparent black but got %+v", m)
+	}
+}
+
+func TestSwizzle(t *testing.T) {
+	img := testImage()
+
+	swapped := Swizzle(img, Blue, Green, Red, Alpha).(*floatimage.NRGBAF64)
+	opaque := Swizzle(img, Red, Green, Blue, One).(*floatimage.NRGBAF64)
+	mask := Swizzle(img, Alpha, Alpha, Alpha, Fill(0.75)).(*floatimage.NRGBAF64)
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			c := img.NRGBAF64At(x, y)
+			if s := swapped.NRGBAF64At(x, y); s != (floatcolor.NRGBAF64{R: c.B, G: c.G, B: c.R, A: c.A}) {
+				t.Fatalf("expected red and blue swapped at (%d, %d) but got %+v", x, y, s)
+			}
+			if o := opaque.NRGBAF64At(x, y); o != (floatcolor.NRGBAF64{R: c.R, G: c.G, B: c.B, A: 1.0}) {
+				t.Fatalf("expected opaque color at (%d, %d) but got %+v", x, y, o)
+			}
+			if m := mask.NRGBAF64At(x, y); m != (floatcolor.NRGBAF64{R: c.A, G: c.A, B: c.A, A: 0.75}) {
+				t.Fatalf("expected alpha mask at (%d, %d) but got %+v", x, y, m)
+			}
+		}
+	}
+
+	// Premultiplied images are shuffled with ordinary values
+	premultiplied := floatimage.NewRGBAF32(1, 1)
+	premultiplied.SetNRGBAF64(0, 0, floatcolor.NRGBAF64{R: 0.2, G: 0.4, B: 0.8, A: 0.5})
+	result := Swizzle(premultiplied, Alpha, Zero, Red, One).(*floatimage.RGBAF32)
+	if c := result.NRGBAF64At(0, 0); !nearColor(c, floatcolor.NRGBAF64{R: 0.5, B: 0.2, A: 1.0}) {
+		t.Errorf("expected unpremultiplied channels to be shuffled but got %+v", c)
+	}
+
+	// Tiled images give tiled images
+	tiled := Swizzle(floatimage.NewTiledFrom(img, 2, 2), Blue, Green, Red, Alpha).(*floatimage.Tiled)
+	if c := tiled.NRGBAF64At(img.Rect.Min.X, img.Rect.Min.Y); c != swapped.NRGBAF64At(img.Rect.Min.X, img.Rect.Min.Y) {
+		t.Errorf("expected tiled image to be swizzled like the row-major image but got %+v", c)
+	}
+
+	// Gray images are written as the luminance of the swizzled color
+	gray := floatimage.NewGrayF64(1, 1)
+	gray.Pix[0] = 0.5
+	if c := Swizzle(gray, Red, Zero, Zero, One).(*floatimage.GrayF64).GrayF64At(0, 0); !near(c.Y, 0.5*0.2126) {
+		t.Errorf("expected luminance of red but got %v", c.Y)
+	}
+}
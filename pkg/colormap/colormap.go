@@ -0,0 +1,211 @@
+package colormap
+
+import (
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"fmt"
+	"image"
+	"math"
+)
+
+// Channel indices to select which channel of a float image to visualize.
+// Single channel images (floatimage.GrayF64) always use their only channel.
+const (
+	Red   = 0
+	Green = 1
+	Blue  = 2
+	Alpha = 3
+)
+
+// tableSize is odd so that the center of diverging colormaps is an exact table entry.
+const tableSize = 257
+
+// Colormap maps scalar values in the range [0.0, 1.0] to colors.
+// Colors are gamma encoded sRGB values, ready to be written to an ordinary image file.
+type Colormap struct {
+	// Name is the conventional name of the colormap.
+	Name string
+	// Diverging is true for colormaps with a neutral color in the middle, intended for data around a center value.
+	Diverging bool
+
+	table [tableSize][3]float64
+}
+
+// Range is the scalar value range that is mapped onto a colormap.
+// Min is mapped to position 0.0 and Max to position 1.0 of the colormap.
+type Range struct {
+	Min, Max float64
+}
+
+func newColormap(name string, diverging bool, f func(t float64) (r, g, b float64)) *Colormap {
+	cm := &Colormap{Name: name, Diverging: diverging}
+	for i := range cm.table {
+		r, g, b := f(float64(i) / (tableSize - 1))
+		cm.table[i] = [3]float64{clamp01(r), clamp01(g), clamp01(b)}
+	}
+
+	return cm
+}
+
+// All returns all predefined colormaps.
+func All() []*Colormap {
+	return []*Colormap{Viridis, Magma, Inferno, Plasma, Cividis, Turbo, CoolWarm, RdBu}
+}
+
+// At returns the colormap color at the position t in [0.0, 1.0].
+// Positions outside the range are clamped. NaN gives a fully transparent color.
+func (cm *Colormap) At(t float64) floatcolor.NRGBAF64 {
+	if math.IsNaN(t) {
+		return floatcolor.NRGBAF64{}
+	}
+
+	f := clamp01(t) * (tableSize - 1)
+	i := int(f)
+	if i >= tableSize-1 {
+		i = tableSize - 2
+	}
+	u := f - float64(i)
+
+	c0, c1 := cm.table[i], cm.table[i+1]
+	return floatcolor.NRGBAF64{R: c0[0]*(1-u) + c1[0]*u, G: c0[1]*(1-u) + c1[1]*u, B: c0[2]*(1-u) + c1[2]*u, A: 1.0}
+}
+
+// Map returns the colormap color for the value v in the given range.
+func (cm *Colormap) Map(v float64, r Range) floatcolor.NRGBAF64 {
+	if r.Max == r.Min {
+		return cm.At(0.5)
+	}
+
+	return cm.At((v - r.Min) / (r.Max - r.Min))
+}
+
+// Apply maps one channel of an image onto the colormap using the given value range.
+// Pixels with NaN values become fully transparent. It panics if channel is not one of Red, Green, Blue and Alpha.
+func (cm *Colormap) Apply(img image.Image, channel int, r Range) *floatimage.NRGBAF32 {
+	checkChannel(channel)
+	bounds := img.Bounds()
+	result := floatimage.NewNRGBAF32WithBounds(bounds.Min.X, bounds.Min.Y, bounds.Max.X, bounds.Max.Y)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := cm.Map(channelValue(img, channel, x, y), r)
+
+			i := result.PixOffset(x, y)
+			s := result.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+			s[0], s[1], s[2], s[3] = float32(c.R), float32(c.G), float32(c.B), float32(c.A)
+		}
+	}
+
+	return result
+}
+
+// ApplyRGBA maps one channel of an image onto the colormap using the given value range
+// and returns the result as an ordinary 8 bit per channel image.
+func (cm *Colormap) ApplyRGBA(img image.Image, channel int, r Range) *image.RGBA {
+	return cm.Apply(img, channel, r).AsRGBA()
+}
+
+// ApplyAuto maps one channel of an image onto the colormap using the value range of the channel (see AutoRange).
+// Diverging colormaps use a range symmetric around zero.
+func (cm *Colormap) ApplyAuto(img image.Image, channel int) *floatimage.NRGBAF32 {
+	r := AutoRange(img, channel)
+	if cm.Diverging {
+		r = r.Symmetric()
+	}
+
+	return cm.Apply(img, channel, r)
+}
+
+// Legend returns an image of the colormap as a color bar.
+// A bar wider than it is tall runs from the range minimum at the left to the maximum at the right,
+// otherwise it runs from the minimum at the bottom to the maximum at the top.
+func (cm *Colormap) Legend(width, height int) *floatimage.NRGBAF32 {
+	legend := floatimage.NewNRGBAF32(width, height)
+	horizontal := width >= height
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var t float64
+			if horizontal {
+				t = (float64(x) + 0.5) / float64(width)
+			} else {
+				t = 1.0 - (float64(y)+0.5)/float64(height)
+			}
+
+			c := cm.At(t)
+			i := legend.PixOffset(x, y)
+			s := legend.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+			s[0], s[1], s[2], s[3] = float32(c.R), float32(c.G), float32(c.B), float32(c.A)
+		}
+	}
+
+	return legend
+}
+
+// AutoRange returns the range of the finite values of one channel of an image.
+// NaN and infinite values are ignored. An image without finite values gives the range [0.0, 1.0].
+// It panics if channel is not one of Red, Green, Blue and Alpha.
+func AutoRange(img image.Image, channel int) Range {
+	checkChannel(channel)
+	bounds := img.Bounds()
+	r := Range{Min: math.Inf(1), Max: math.Inf(-1)}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			v := channelValue(img, channel, x, y)
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			r.Min = math.Min(r.Min, v)
+			r.Max = math.Max(r.Max, v)
+		}
+	}
+
+	if r.Min > r.Max {
+		return Range{Min: 0.0, Max: 1.0}
+	}
+
+	return r
+}
+
+// Symmetric returns the smallest range centered around zero that contains the range.
+func (r Range) Symmetric() Range {
+	m := math.Max(math.Abs(r.Min), math.Abs(r.Max))
+	return Range{Min: -m, Max: m}
+}
+
+// checkChannel panics if channel is not one of Red, Green, Blue and Alpha.
+func checkChannel(channel int) {
+	if channel < Red || channel > Alpha {
+		panic(fmt.Sprintf("colormap: channel %d out of range [0, 3]", channel))
+	}
+}
+
+// channelValue returns the raw value of one channel of the pixel at (x, y).
+// Float images are read directly, other images are converted to NRGBAF64 values.
+func channelValue(img image.Image, channel int, x, y int) float64 {
+	switch p := img.(type) {
+	case *floatimage.GrayF64:
+		return p.Pix[p.PixOffset(x, y)]
+	case *floatimage.NRGBAF64:
+		return p.Pix[p.PixOffset(x, y)+channel]
+	case *floatimage.NRGBAF32:
+		return float64(p.Pix[p.PixOffset(x, y)+channel])
+	case *floatimage.RGBAF64:
+		return p.Pix[p.PixOffset(x, y)+channel]
+	case *floatimage.RGBAF32:
+		return float64(p.Pix[p.PixOffset(x, y)+channel])
+	}
+
+	c := floatcolor.NRGBAF64Model.Convert(img.At(x, y)).(floatcolor.NRGBAF64)
+	return [4]float64{c.R, c.G, c.B, c.A}[channel]
+}
+
+func clamp01(v float64) float64 {
+	if v < 0.0 {
+		return 0.0
+	} else if v > 1.0 {
+		return 1.0
+	}
+	return v
+}
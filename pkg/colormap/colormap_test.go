@@ -0,0 +1,132 @@
+package colormap
+
+import (
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"math"
+	"testing"
+)
+
+func almostEqual(c floatcolor.NRGBAF64, rgb [3]float64, tolerance float64) bool {
+	return math.Abs(c.R-rgb[0]) <= tolerance && math.Abs(c.G-rgb[1]) <= tolerance && math.Abs(c.B-rgb[2]) <= tolerance
+}
+
+func TestReferenceColors(t *testing.T) {
+	tests := []struct {
+		colormap *Colormap
+		t        float64
+		expected [3]float64
+	}{
+		{Viridis, 0.0, [3]float64{0.267004, 0.004874, 0.329415}},
+		{Viridis, 0.5, [3]float64{0.127568, 0.566949, 0.550556}},
+		{Viridis, 1.0, [3]float64{0.993248, 0.906157, 0.143936}},
+		{Magma, 0.0, [3]float64{0.001462, 0.000466, 0.013866}},
+		{Magma, 1.0, [3]float64{0.987053, 0.991438, 0.749504}},
+		{Inferno, 0.0, [3]float64{0.001462, 0.000466, 0.013866}},
+		{Inferno, 1.0, [3]float64{0.988362, 0.998364, 0.644924}},
+		{Plasma, 0.0, [3]float64{0.050383, 0.029803, 0.527975}},
+		{Plasma, 1.0, [3]float64{0.940015, 0.975158, 0.131326}},
+		{Cividis, 0.0, [3]float64{0.0, 0.135112, 0.304751}},
+		{Cividis, 1.0, [3]float64{0.995737, 0.909344, 0.217772}},
+		{CoolWarm, 0.0, [3]float64{0.230, 0.299, 0.754}},
+		{CoolWarm, 0.5, [3]float64{0.865, 0.865, 0.865}},
+		{CoolWarm, 1.0, [3]float64{0.706, 0.016, 0.150}},
+		{RdBu, 0.0, [3]float64{0x67 / 255.0, 0x00 / 255.0, 0x1f / 255.0}},
+		{RdBu, 0.5, [3]float64{0xf7 / 255.0, 0xf7 / 255.0, 0xf7 / 255.0}},
+		{RdBu, 1.0, [3]float64{0x05 / 255.0, 0x30 / 255.0, 0x61 / 255.0}},
+	}
+
+	for _, test := range tests {
+		if c := test.colormap.At(test.t); !almostEqual(c, test.expected, 0.02) {
+			t.Errorf("%s at %v: expected %v but got %+v", test.colormap.Name, test.t, test.expected, c)
+		}
+	}
+}
+
+func TestTurboIsRainbow(t *testing.T) {
+	if c := Turbo.At(0.15); c.B < c.R || c.B < c.G {
+		t.Errorf("expected turbo to be blue at 0.15 but got %+v", c)
+	}
+	if c := Turbo.At(0.45); c.G < c.R || c.G < c.B {
+		t.Errorf("expected turbo to be green at 0.45 but got %+v", c)
+	}
+	if c := Turbo.At(0.9); c.R < c.G || c.R < c.B {
+		t.Errorf("expected turbo to be red at 0.9 but got %+v", c)
+	}
+}
+
+func TestSequentialLightnessIsMonotonic(t *testing.T) {
+	for _, cm := range []*Colormap{Viridis, Magma, Inferno, Plasma, Cividis} {
+		previous := -1.0
+		for i := 0; i <= 32; i++ {
+			c := cm.At(float64(i) / 32)
+			L, _, _ := floatcolor.LinearRGBToOKLab(floatcolor.SRGBToLinear(c.R), floatcolor.SRGBToLinear(c.G), floatcolor.SRGBToLinear(c.B))
+			if L < previous {
+				t.Errorf("%s: lightness decreases at %v", cm.Name, float64(i)/32)
+				break
+			}
+			previous = L
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	img := floatimage.NewGrayF64(4, 1)
+	copy(img.Pix, []float64{-2.0, 0.0, 6.0, math.NaN()})
+
+	r := AutoRange(img, 0)
+	if r.Min != -2.0 || r.Max != 6.0 {
+		t.Errorf("unexpected auto range %+v", r)
+	}
+
+	result := Viridis.Apply(img, 0, r)
+	if c := result.At(0, 0).(floatcolor.NRGBAF32); !almostEqual(floatcolor.NRGBAF64Model.Convert(c).(floatcolor.NRGBAF64), Viridis.table[0], 1e-6) {
+		t.Errorf("expected minimum to map to the first colormap color but got %+v", c)
+	}
+	if c := result.At(3, 0).(floatcolor.NRGBAF32); c.A != 0 {
+		t.Errorf("expected NaN to be transparent but got %+v", c)
+	}
+
+	diverging := RdBu.ApplyAuto(img, 0)
+	if c := diverging.At(1, 0).(floatcolor.NRGBAF32); c.R != c.G || c.G != c.B {
+		t.Errorf("expected zero to map to the neutral mid color but got %+v", c)
+	}
+
+	rgba := floatimage.NewNRGBAF64(2, 1)
+	rgba.Pix[1], rgba.Pix[5] = 10.0, 20.0
+	if r := AutoRange(rgba, Green); r.Min != 10.0 || r.Max != 20.0 {
+		t.Errorf("unexpected green channel range %+v", r)
+	}
+}
+
+func TestInvalidChannel(t *testing.T) {
+	img := floatimage.NewNRGBAF64(2, 1)
+	for _, channel := range []int{-1, 4, 7} {
+		for name, f := range map[string]func(){
+			"Apply":     func() { Viridis.Apply(img, channel, Range{Min: 0, Max: 1}) },
+			"ApplyAuto": func() { Viridis.ApplyAuto(img, channel) },
+			"AutoRange": func() { AutoRange(img, channel) },
+		} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("%s: expected a panic for channel %d", name, channel)
+					}
+				}()
+				f()
+			}()
+		}
+	}
+}
+
+func TestLegend(t *testing.T) {
+	horizontal := Magma.Legend(256, 16)
+	if c := horizontal.At(0, 8).(floatcolor.NRGBAF32); c.R > 0.05 {
+		t.Errorf("expected dark left end but got %+v", c)
+	}
+
+	vertical := Magma.Legend(16, 256)
+	if c := vertical.At(8, 0).(floatcolor.NRGBAF32); c.R < 0.95 {
+		t.Errorf("expected bright top end but got %+v", c)
+	}
+}
@@ -0,0 +1,242 @@
+package colormap
+
+import (
+	"floatimage/pkg/floatcolor"
+	"math"
+)
+
+var (
+	// Viridis is the perceptually uniform sequential colormap from matplotlib (dark blue to yellow).
+	Viridis = newPolynomialColormap("viridis", [7][3]float64{
+		{0.2777273272234177, 0.005407344544966578, 0.3340998053353061},
+		{0.1050930431085774, 1.404613529898575, 1.384590162594685},
+		{-0.3308618287255563, 0.214847559468213, 0.09509516302823659},
+		{-4.634230498983486, -5.799100973351585, -19.33244095627987},
+		{6.228269936347081, 14.17993336680509, 56.69055260068105},
+		{4.776384997670288, -13.74514537774601, -65.35303263337234},
+		{-5.435455855934631, 4.645852612178535, 26.3124352495832},
+	})
+
+	// Magma is the perceptually uniform sequential colormap from matplotlib (black via purple to light yellow).
+	Magma = newPolynomialColormap("magma", [7][3]float64{
+		{-0.002136485053939582, -0.000749655052795221, -0.005386127855323933},
+		{0.2516605407371642, 0.6775232436837668, 2.494026599312351},
+		{8.353717279216625, -3.577719514958484, 0.3144679030132573},
+		{-27.66873308576866, 14.26473078096533, -13.64921318813922},
+		{52.17613981234068, -27.94360607168351, 12.94416944238394},
+		{-50.76852536473588, 29.04658282127291, 4.23415299384598},
+		{18.65570506591883, -11.48977351997711, -5.601961508734096},
+	})
+
+	// Inferno is the perceptually uniform sequential colormap from matplotlib (black via red to light yellow).
+	Inferno = newPolynomialColormap("inferno", [7][3]float64{
+		{0.0002189403691192265, 0.001651004631001012, -0.01948089843709184},
+		{0.1065134194856116, 0.5639564367884091, 3.932712388889277},
+		{11.60249308247187, -3.972853965665698, -15.9423941062914},
+		{-41.70399613139459, 17.43639888205313, 44.35414519872813},
+		{77.162935699427, -33.40235894210092, -81.80730925738993},
+		{-71.31942824499214, 32.62606426397723, 73.20951985803202},
+		{25.13112622477341, -12.24266895238567, -23.07032500287172},
+	})
+
+	// Plasma is the perceptually uniform sequential colormap from matplotlib (blue via magenta to yellow).
+	Plasma = newPolynomialColormap("plasma", [7][3]float64{
+		{0.05873234392399702, 0.02333670892565664, 0.5433401826748754},
+		{2.176514634195958, 0.2383834171260182, 0.7539604599784036},
+		{-2.689460476458034, -7.455851135738909, 3.110799939717086},
+		{6.130348345893603, 42.3461881477227, -28.51885465332158},
+		{-11.10743619062271, -82.66631109428045, 60.13984767418263},
+		{10.02306557647065, 71.41361770095349, -54.07218655560067},
+		{-3.658713842777788, -22.93153465461149, 18.19190778539828},
+	})
+
+	// Cividis is the sequential colormap from matplotlib optimized for color vision deficiency (dark blue via gray to yellow).
+	Cividis = newSegmentedColormap("cividis", false, [][3]float64{
+		{0x00, 0x22, 0x4e},
+		{0x41, 0x4d, 0x6b},
+		{0x7c, 0x7b, 0x78},
+		{0xbc, 0xaf, 0x6f},
+		{0xfe, 0xe8, 0x38},
+	})
+
+	// Turbo is the improved rainbow colormap from Google (dark blue via green to dark red).
+	Turbo = newColormap("turbo", false, turbo)
+
+	// CoolWarm is the diverging colormap by Kenneth Moreland (blue via light gray to red).
+	CoolWarm = newColormap("coolwarm", true, func(t float64) (r, g, b float64) {
+		return divergingMsh([3]float64{0.230, 0.299, 0.754}, [3]float64{0.706, 0.016, 0.150}, t)
+	})
+
+	// RdBu is the diverging ColorBrewer colormap (dark red via white to dark blue).
+	RdBu = newSegmentedColormap("RdBu", true, [][3]float64{
+		{0x67, 0x00, 0x1f},
+		{0xb2, 0x18, 0x2b},
+		{0xd6, 0x60, 0x4d},
+		{0xf4, 0xa5, 0x82},
+		{0xfd, 0xdb, 0xc7},
+		{0xf7, 0xf7, 0xf7},
+		{0xd1, 0xe5, 0xf0},
+		{0x92, 0xc5, 0xde},
+		{0x43, 0x93, 0xc3},
+		{0x21, 0x66, 0xac},
+		{0x05, 0x30, 0x61},
+	})
+)
+
+// newPolynomialColormap creates a sequential colormap from a sixth degree polynomial fit
+// (coefficients in increasing degree) of the reference colormap.
+func newPolynomialColormap(name string, c [7][3]float64) *Colormap {
+	return newColormap(name, false, func(t float64) (r, g, b float64) {
+		var v [3]float64
+		for i := 6; i >= 0; i-- {
+			for j := 0; j < 3; j++ {
+				v[j] = v[j]*t + c[i][j]
+			}
+		}
+		return v[0], v[1], v[2]
+	})
+}
+
+// newSegmentedColormap creates a colormap from evenly spaced 8 bit sRGB colors that are linearly interpolated.
+func newSegmentedColormap(name string, diverging bool, colors [][3]float64) *Colormap {
+	return newColormap(name, diverging, func(t float64) (r, g, b float64) {
+		f := t * float64(len(colors)-1)
+		i := int(f)
+		if i >= len(colors)-1 {
+			i = len(colors) - 2
+		}
+		u := f - float64(i)
+
+		c0, c1 := colors[i], colors[i+1]
+		conv := 1.0 / 0xff
+		return (c0[0]*(1-u) + c1[0]*u) * conv, (c0[1]*(1-u) + c1[1]*u) * conv, (c0[2]*(1-u) + c1[2]*u) * conv
+	})
+}
+
+// turbo is the polynomial approximation of the Turbo colormap published by Google.
+func turbo(t float64) (r, g, b float64) {
+	t2, t3 := t*t, t*t*t
+	t4, t5 := t2*t2, t2*t3
+
+	r = 0.13572138 + 4.61539260*t - 42.66032258*t2 + 132.13108234*t3 - 152.94239396*t4 + 59.28637943*t5
+	g = 0.09140261 + 2.19418839*t + 4.84296658*t2 - 14.18503333*t3 + 4.27729857*t4 + 2.82956604*t5
+	b = 0.10667330 + 12.64194608*t - 60.58204836*t2 + 110.36276771*t3 - 89.90310912*t4 + 27.34824973*t5
+	return r, g, b
+}
+
+// divergingMsh interpolates between two sRGB colors in the Msh color space (polar CIELAB)
+// with an unsaturated white-ish mid point, as described by Kenneth Moreland in
+// "Diverging Color Maps for Scientific Visualization".
+func divergingMsh(rgb1, rgb2 [3]float64, t float64) (r, g, b float64) {
+	msh1 := labToMsh(srgbToLab(rgb1))
+	msh2 := labToMsh(srgbToLab(rgb2))
+
+	// Place an unsaturated white in the middle if the end points are saturated and distinct.
+	if msh1[1] > 0.05 && msh2[1] > 0.05 && angleDifference(msh1[2], msh2[2]) > math.Pi/3 {
+		mid := math.Max(math.Max(msh1[0], msh2[0]), 88.0)
+		if t < 0.5 {
+			msh2 = [3]float64{mid, 0, 0}
+			t = 2 * t
+		} else {
+			msh1 = [3]float64{mid, 0, 0}
+			t = 2*t - 1
+		}
+	}
+
+	// Adjust the hue of an unsaturated color to the saturated one.
+	if msh1[1] < 0.05 && msh2[1] > 0.05 {
+		msh1[2] = adjustHue(msh2, msh1[0])
+	} else if msh2[1] < 0.05 && msh1[1] > 0.05 {
+		msh2[2] = adjustHue(msh1, msh2[0])
+	}
+
+	var msh [3]float64
+	for i := range msh {
+		msh[i] = msh1[i]*(1-t) + msh2[i]*t
+	}
+
+	rgb := labToSRGB(mshToLab(msh))
+	return rgb[0], rgb[1], rgb[2]
+}
+
+func angleDifference(a1, a2 float64) float64 {
+	d := math.Abs(a1 - a2)
+	if d > math.Pi {
+		d = 2*math.Pi - d
+	}
+	return d
+}
+
+func adjustHue(msh [3]float64, unsaturatedM float64) float64 {
+	if msh[0] >= unsaturatedM {
+		return msh[2]
+	}
+
+	hueSpin := msh[1] * math.Sqrt(unsaturatedM*unsaturatedM-msh[0]*msh[0]) / (msh[0] * math.Sin(msh[1]))
+	if msh[2] > -math.Pi/3 {
+		return msh[2] + hueSpin
+	}
+	return msh[2] - hueSpin
+}
+
+func labToMsh(lab [3]float64) [3]float64 {
+	m := math.Sqrt(lab[0]*lab[0] + lab[1]*lab[1] + lab[2]*lab[2])
+	return [3]float64{m, math.Acos(lab[0] / m), math.Atan2(lab[2], lab[1])}
+}
+
+func mshToLab(msh [3]float64) [3]float64 {
+	return [3]float64{
+		msh[0] * math.Cos(msh[1]),
+		msh[0] * math.Sin(msh[1]) * math.Cos(msh[2]),
+		msh[0] * math.Sin(msh[1]) * math.Sin(msh[2]),
+	}
+}
+
+// D65 reference white for the CIELAB conversions
+var whiteD65 = [3]float64{0.95047, 1.0, 1.08883}
+
+func srgbToLab(rgb [3]float64) [3]float64 {
+	var lin [3]float64
+	for i, v := range rgb {
+		lin[i] = floatcolor.SRGBToLinear(v)
+	}
+
+	xyz := [3]float64{
+		0.4124564*lin[0] + 0.3575761*lin[1] + 0.1804375*lin[2],
+		0.2126729*lin[0] + 0.7151522*lin[1] + 0.0721750*lin[2],
+		0.0193339*lin[0] + 0.1191920*lin[1] + 0.9503041*lin[2],
+	}
+
+	f := func(v float64) float64 {
+		if v > 216.0/24389.0 {
+			return math.Cbrt(v)
+		}
+		return (24389.0/27.0*v + 16) / 116
+	}
+
+	fx, fy, fz := f(xyz[0]/whiteD65[0]), f(xyz[1]/whiteD65[1]), f(xyz[2]/whiteD65[2])
+	return [3]float64{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+}
+
+func labToSRGB(lab [3]float64) [3]float64 {
+	fy := (lab[0] + 16) / 116
+	fx := fy + lab[1]/500
+	fz := fy - lab[2]/200
+
+	finv := func(v float64) float64 {
+		if v3 := v * v * v; v3 > 216.0/24389.0 {
+			return v3
+		}
+		return (116*v - 16) * 27.0 / 24389.0
+	}
+
+	x, y, z := finv(fx)*whiteD65[0], finv(fy)*whiteD65[1], finv(fz)*whiteD65[2]
+
+	lin := [3]float64{
+		3.2404542*x - 1.5371385*y - 0.4985314*z,
+		-0.9692660*x + 1.8760108*y + 0.0415560*z,
+		0.0556434*x - 0.2040259*y + 1.0572252*z,
+	}
+
+	return [3]float64{floatcolor.LinearToSRGB(lin[0]), floatcolor.LinearToSRGB(lin[1]), floatcolor.LinearToSRGB(lin[2])}
+}
@@ -0,0 +1,142 @@
+package envmap
+
+import (
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"image"
+	"math"
+	"sort"
+)
+
+// Distribution is an importance sampling distribution of directions for an equirectangular environment map,
+// proportional to the luminance of the environment map. It is used to sample the directions of the light
+// from an environment map (HDR light probe) where most of the light comes from.
+//
+// The distribution is piecewise constant per pixel. The luminance of each pixel is weighted by sin(theta),
+// the relative solid angle of its row, as rows near the poles cover a smaller part of the sphere.
+// Directions are sampled from a marginal distribution over the rows and a conditional distribution within each row.
+type Distribution struct {
+	width, height int
+	conditional   []distribution1D
+	marginal      distribution1D
+}
+
+// NewDistribution creates the importance sampling distribution of an equirectangular environment map.
+// Negative luminance values are treated as zero. An environment map without any light gives a uniform distribution.
+func NewDistribution(img image.Image) *Distribution {
+	src := floatimage.ToRGBAF64(img)
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+
+	d := &Distribution{width: width, height: height, conditional: make([]distribution1D, height)}
+	rowIntegrals := make([]float64, height)
+
+	for y := 0; y < height; y++ {
+		sinTheta := math.Sin(math.Pi * (float64(y) + 0.5) / float64(height))
+
+		row := make([]float64, width)
+		for x := 0; x < width; x++ {
+			i := y*src.Stride + x*4
+			p := src.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+			row[x] = math.Max(0, floatcolor.Luminance(p[0], p[1], p[2])) * sinTheta
+		}
+
+		d.conditional[y] = newDistribution1D(row)
+		rowIntegrals[y] = d.conditional[y].integral
+	}
+	d.marginal = newDistribution1D(rowIntegrals)
+
+	return d
+}
+
+// Sample returns a direction sampled from the distribution, for two uniformly distributed random numbers in [0, 1),
+// together with the probability density of the direction (with respect to solid angle).
+// The probability density is zero for (the extremely unlikely) directions straight up or down, and for all directions
+// of the distribution of an empty environment map, which maps u1 and u2 to a direction like Equirectangular does.
+func (d *Distribution) Sample(u1, u2 float64) (direction Vector, pdf float64) {
+	if d.width == 0 || d.height == 0 {
+		direction, _ = Equirectangular{}.Direction(u1, u2)
+		return direction, 0
+	}
+
+	v, pdfV, row := d.marginal.sample(u2)
+	u, pdfU, _ := d.conditional[row].sample(u1)
+
+	direction, _ = Equirectangular{}.Direction(u, v)
+
+	sinTheta := math.Sin(v * math.Pi)
+	if sinTheta == 0 {
+		return direction, 0
+	}
+	return direction, pdfU * pdfV / (2 * math.Pi * math.Pi * sinTheta)
+}
+
+// PDF returns the probability density (with respect to solid angle) of sampling the direction.
+func (d *Distribution) PDF(direction Vector) float64 {
+	u, v := Equirectangular{}.UV(direction)
+	sinTheta := math.Sin(v * math.Pi)
+	if sinTheta == 0 || d.width == 0 || d.height == 0 {
+		return 0
+	}
+
+	x := clampIndex(int(u*float64(d.width)), d.width)
+	y := clampIndex(int(v*float64(d.height)), d.height)
+
+	return d.conditional[y].pdf(x) * d.marginal.pdf(y) / (2 * math.Pi * math.Pi * sinTheta)
+}
+
+// distribution1D is a piecewise constant one dimensional distribution over [0, 1).
+type distribution1D struct {
+	f        []float64
+	cdf      []float64
+	integral float64
+}
+
+func newDistribution1D(f []float64) distribution1D {
+	n := len(f)
+	cdf := make([]float64, n+1)
+	for i, v := range f {
+		cdf[i+1] = cdf[i] + v/float64(n)
+	}
+
+	integral := cdf[n]
+	for i := 1; i <= n; i++ {
+		if integral == 0 {
+			cdf[i] = float64(i) / float64(n)
+		} else {
+			cdf[i] /= integral
+		}
+	}
+
+	return distribution1D{f: f, cdf: cdf, integral: integral}
+}
+
+// pdf returns the probability density of the segment i.
+func (d distribution1D) pdf(i int) float64 {
+	if d.integral == 0 {
+		return 1.0
+	}
+	return d.f[i] / d.integral
+}
+
+// sample returns a value in [0, 1) sampled from the distribution, its probability density and its segment.
+func (d distribution1D) sample(u float64) (x float64, pdf float64, segment int) {
+	n := len(d.f)
+	segment = clampIndex(sort.Search(len(d.cdf), func(i int) bool { return d.cdf[i] > u })-1, n)
+
+	du := u - d.cdf[segment]
+	if width := d.cdf[segment+1] - d.cdf[segment]; width > 0 {
+		du /= width
+	}
+
+	return (float64(segment) + du) / float64(n), d.pdf(segment), segment
+}
+
+func clampIndex(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+	if i >= n {
+		return n - 1
+	}
+	return i
+}
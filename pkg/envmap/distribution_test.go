@@ -0,0 +1,147 @@
+package envmap
+
+import (
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"floatimage/pkg/sampler"
+	"image"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// testEnvironment returns a small equirectangular environment with a dim sky, a dark ground and a bright sun.
+func testEnvironment() *floatimage.NRGBAF32 {
+	img := floatimage.NewNRGBAF32(16, 8)
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			c := floatcolor.NRGBAF32{R: 0.3, G: 0.4, B: 0.8, A: 1.0}
+			if y >= 4 {
+				c = floatcolor.NRGBAF32{R: 0.05, G: 0.04, B: 0.02, A: 1.0}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	img.Set(5, 2, floatcolor.NRGBAF32{R: 50, G: 45, B: 40, A: 1.0})
+	img.Set(12, 6, floatcolor.NRGBAF32{A: 1.0}) // Black pixel
+	return img
+}
+
+// pixelSolidAngle returns the solid angle covered by a pixel in row y of an equirectangular image.
+func pixelSolidAngle(y, width, height int) float64 {
+	theta0, theta1 := math.Pi*float64(y)/float64(height), math.Pi*float64(y+1)/float64(height)
+	return 2 * math.Pi / float64(width) * (math.Cos(theta0) - math.Cos(theta1))
+}
+
+func luminance(img *floatimage.NRGBAF32, x, y int) float64 {
+	c := img.At(x, y).(floatcolor.NRGBAF32)
+	return floatcolor.Luminance(float64(c.R), float64(c.G), float64(c.B))
+}
+
+func TestDistributionHistogram(t *testing.T) {
+	img := testEnvironment()
+	distribution := NewDistribution(img)
+	rnd := rand.New(rand.NewSource(1))
+
+	const samples = 200000
+	counts := make([]float64, 16*8)
+	for i := 0; i < samples; i++ {
+		d, pdf := distribution.Sample(rnd.Float64(), rnd.Float64())
+		if p := distribution.PDF(d); math.Abs(p-pdf) > 1e-6*pdf {
+			t.Fatalf("expected PDF %v of sampled direction %+v but got %v", pdf, d, p)
+		}
+
+		u, v := Equirectangular{}.UV(d)
+		counts[clampIndex(int(v*8), 8)*16+clampIndex(int(u*16), 16)]++
+	}
+
+	// Expected probability of each pixel: luminance weighted by sin(theta) at the pixel center.
+	expected := make([]float64, 16*8)
+	total := 0.0
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			expected[y*16+x] = luminance(img, x, y) * math.Sin(math.Pi*(float64(y)+0.5)/8)
+			total += expected[y*16+x]
+		}
+	}
+
+	chiSquare, cells := 0.0, 0
+	for i, e := range expected {
+		e *= samples / total
+		if e == 0 {
+			if counts[i] != 0 {
+				t.Errorf("expected no samples in black pixel %d but got %v", i, counts[i])
+			}
+			continue
+		}
+		chiSquare += (counts[i] - e) * (counts[i] - e) / e
+		cells++
+	}
+
+	// The chi-square statistic has mean (cells - 1) and standard deviation sqrt(2 * (cells - 1)) for a correct distribution.
+	dof := float64(cells - 1)
+	if chiSquare > dof+5*math.Sqrt(2*dof) {
+		t.Errorf("samples do not follow the distribution, chi-square %v for %v degrees of freedom", chiSquare, dof)
+	}
+}
+
+func TestDistributionIntegrals(t *testing.T) {
+	img := testEnvironment()
+	distribution := NewDistribution(img)
+
+	// The probability density integrates to one over the sphere.
+	integral := 0.0
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 128; x++ {
+			d, _ := Equirectangular{}.Direction((float64(x)+0.5)/128, (float64(y)+0.5)/64)
+			integral += distribution.PDF(d) * pixelSolidAngle(y, 128, 64)
+		}
+	}
+	if math.Abs(integral-1.0) > 0.01 {
+		t.Errorf("expected probability density to integrate to 1.0 but got %v", integral)
+	}
+
+	// Monte Carlo estimate of the total luminance of the environment with importance sampling.
+	exact := 0.0
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			exact += luminance(img, x, y) * pixelSolidAngle(y, 16, 8)
+		}
+	}
+
+	environment := NewEnvironment(img, Equirectangular{}, sampler.Nearest)
+	rnd := rand.New(rand.NewSource(2))
+	estimate := 0.0
+	const samples = 20000
+	for i := 0; i < samples; i++ {
+		d, pdf := distribution.Sample(rnd.Float64(), rnd.Float64())
+		c := environment.Lookup(d)
+		estimate += floatcolor.Luminance(c.R, c.G, c.B) / pdf / samples
+	}
+	if math.Abs(estimate-exact) > 0.01*exact {
+		t.Errorf("expected importance sampled estimate %v to be close to %v", estimate, exact)
+	}
+}
+
+func TestUniformDistributionWithoutLight(t *testing.T) {
+	distribution := NewDistribution(floatimage.NewRGBAF64(8, 4))
+
+	d, pdf := distribution.Sample(0.3, 0.6)
+	if math.Abs(d.Length()-1) > 1e-12 || pdf <= 0 {
+		t.Errorf("expected a valid direction and pdf but got %+v and %v", d, pdf)
+	}
+}
+
+func TestEmptyDistribution(t *testing.T) {
+	for _, img := range []image.Image{floatimage.NewRGBAF64(0, 0), floatimage.NewRGBAF64(0, 4), floatimage.NewRGBAF64(8, 0)} {
+		distribution := NewDistribution(img)
+
+		d, pdf := distribution.Sample(0.3, 0.6)
+		if math.Abs(d.Length()-1) > 1e-12 || pdf != 0 {
+			t.Errorf("%v: expected a valid direction and zero pdf but got %+v and %v", img.Bounds(), d, pdf)
+		}
+		if pdf := distribution.PDF(d); pdf != 0 {
+			t.Errorf("%v: expected zero pdf but got %v", img.Bounds(), pdf)
+		}
+	}
+}
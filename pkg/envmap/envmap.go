@@ -0,0 +1,168 @@
+package envmap
+
+import (
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"floatimage/pkg/sampler"
+	"fmt"
+	"image"
+)
+
+// Environment is an environment map (e.g. an HDR light probe), an image of the light coming from every direction.
+type Environment struct {
+	projection Projection
+	sampler    *sampler.Sampler
+	faces      *[6]*sampler.Sampler
+}
+
+// NewEnvironment creates a new environment map of the image in the given projection, sampled with the given filter.
+// Equirectangular images wrap around horizontally. Cross layouts are split into their faces,
+// so filtering does not reach into the unused parts of the image (see CubeFaces, which panics for invalid crosses).
+func NewEnvironment(img image.Image, projection Projection, filter sampler.Filter) *Environment {
+	if cross, ok := projection.(Cross); ok {
+		var faces [6]image.Image
+		for i, face := range CubeFaces(img, cross) {
+			faces[i] = face
+		}
+		e := NewCubeEnvironment(faces, filter)
+		e.projection = projection
+		return e
+	}
+
+	s := sampler.New(img, filter, sampler.WrapClamp)
+	if _, ok := projection.(Equirectangular); ok {
+		s.WrapU = sampler.WrapRepeat
+	}
+	return &Environment{projection: projection, sampler: s}
+}
+
+// NewCubeEnvironment creates a new environment map of the six (square) faces of a cube map, in CubeFace order,
+// sampled with the given filter.
+func NewCubeEnvironment(faces [6]image.Image, filter sampler.Filter) *Environment {
+	var samplers [6]*sampler.Sampler
+	for i, face := range faces {
+		samplers[i] = sampler.New(face, filter, sampler.WrapClamp)
+	}
+	return &Environment{projection: Cross{}, faces: &samplers}
+}
+
+// Projection returns the projection of the environment map.
+func (e *Environment) Projection() Projection {
+	return e.projection
+}
+
+// Lookup returns the premultiplied color of the environment map in the direction d.
+// The direction does not need to be of unit length.
+func (e *Environment) Lookup(d Vector) floatcolor.RGBAF64 {
+	if e.faces != nil {
+		face, s, t := cubeFace(d)
+		return e.faces[face].AtUV(s, t)
+	}
+
+	u, v := e.projection.UV(d)
+	return e.sampler.AtUV(u, v)
+}
+
+// Convert returns the environment map img, in projection from, reprojected to projection to, as a new
+// float image of the same type as img (see floatimage.NewLike) with the given size.
+// Each pixel is sampled once, at its center, with the given filter. Pixels that do not map to any direction are left transparent.
+func Convert(img image.Image, from, to Projection, width, height int, filter sampler.Filter) floatimage.FloatImage {
+	return NewEnvironment(img, from, filter).Render(img, to, width, height)
+}
+
+// Render returns the environment map rendered in the given projection, as a new float image
+// of the same type as like (see floatimage.NewLike) with the given size.
+func (e *Environment) Render(like image.Image, projection Projection, width, height int) floatimage.FloatImage {
+	result := floatimage.NewRGBAF64(width, height)
+
+	floatimage.ParallelFor(height, 0, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < width; x++ {
+				d, ok := projection.Direction((float64(x)+0.5)/float64(width), (float64(y)+0.5)/float64(height))
+				if !ok {
+					continue
+				}
+
+				c := e.Lookup(d)
+				i := result.PixOffset(x, y)
+				s := result.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+				s[0], s[1], s[2], s[3] = c.R, c.G, c.B, c.A
+			}
+		}
+	})
+
+	return floatimage.FromRGBAF64(result, like)
+}
+
+// CubeFaces returns the six faces of a cube map in a cross layout, in CubeFace order,
+// as new float images of the same type as img, with bounds starting at (0, 0).
+// It panics if img is not a cross of (non empty) square faces, i.e. its width and height are not
+// the same multiple of the columns and rows of the layout.
+func CubeFaces(img image.Image, layout Cross) [6]floatimage.FloatImage {
+	columns, rows := layout.Size()
+	bounds := img.Bounds()
+	size := bounds.Dx() / columns
+	if size <= 0 || bounds.Dx() != columns*size || bounds.Dy() != rows*size {
+		panic(fmt.Sprintf("envmap: CubeFaces image of size %dx%d is not a cross of %dx%d square faces", bounds.Dx(), bounds.Dy(), columns, rows))
+	}
+	src := floatimage.ToRGBAF64(img)
+
+	var faces [6]floatimage.FloatImage
+	for face := PositiveX; face <= NegativeZ; face++ {
+		column, row := layout.Cell(face)
+		rotate := layout.Vertical && face == NegativeZ
+
+		f := floatimage.NewRGBAF64(size, size)
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				sx, sy := x, y
+				if rotate {
+					sx, sy = size-1-x, size-1-y
+				}
+
+				i := src.PixOffset(src.Rect.Min.X+column*size+sx, src.Rect.Min.Y+row*size+sy)
+				j := f.PixOffset(x, y)
+				copy(f.Pix[j:j+4], src.Pix[i:i+4])
+			}
+		}
+
+		faces[face] = floatimage.FromRGBAF64(f, img)
+	}
+
+	return faces
+}
+
+// AssembleCross returns the six (square, same sized) faces of a cube map, in CubeFace order, laid out as a cross,
+// as a new float image of the same type as the first face. The parts of the image outside the faces are transparent.
+// It panics if the faces are not square, of the same size and non empty.
+func AssembleCross(faces [6]image.Image, layout Cross) floatimage.FloatImage {
+	size := faces[0].Bounds().Dx()
+	for face, f := range faces {
+		if b := f.Bounds(); size <= 0 || b.Dx() != size || b.Dy() != size {
+			panic(fmt.Sprintf("envmap: AssembleCross face %d of size %dx%d is not a non empty square of the size of face 0 (%d)", face, b.Dx(), b.Dy(), size))
+		}
+	}
+	columns, rows := layout.Size()
+	result := floatimage.NewRGBAF64(columns*size, rows*size)
+
+	for face := PositiveX; face <= NegativeZ; face++ {
+		src := floatimage.ToRGBAF64(faces[face])
+		column, row := layout.Cell(face)
+		rotate := layout.Vertical && face == NegativeZ
+
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				sx, sy := x, y
+				if rotate {
+					sx, sy = size-1-x, size-1-y
+				}
+
+				i := src.PixOffset(src.Rect.Min.X+sx, src.Rect.Min.Y+sy)
+				j := result.PixOffset(column*size+x, row*size+y)
+				copy(result.Pix[j:j+4], src.Pix[i:i+4])
+			}
+		}
+	}
+
+	return floatimage.FromRGBAF64(result, faces[0])
+}
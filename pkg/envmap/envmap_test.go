@@ -0,0 +1,162 @@
+package envmap
+
+import (
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"floatimage/pkg/sampler"
+	"image"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+var projections = []Projection{Equirectangular{}, MirrorBall{}, Octahedral{}, Cross{}, Cross{Vertical: true}}
+
+func randomDirection(rnd *rand.Rand) Vector {
+	for {
+		d := Vector{2*rnd.Float64() - 1, 2*rnd.Float64() - 1, 2*rnd.Float64() - 1}
+		if l := d.Length(); l > 0.1 && l <= 1 {
+			return d.Normalize()
+		}
+	}
+}
+
+func distance(d1, d2 Vector) float64 {
+	return Vector{d1.X - d2.X, d1.Y - d2.Y, d1.Z - d2.Z}.Length()
+}
+
+// directionColor is a smooth color encoding of a direction.
+func directionColor(d Vector) floatcolor.RGBAF64 {
+	return floatcolor.RGBAF64{R: (d.X + 1) / 2, G: (d.Y + 1) / 2, B: (d.Z + 1) / 2, A: 1.0}
+}
+
+func TestProjectionRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	for _, projection := range projections {
+		for i := 0; i < 1000; i++ {
+			d := randomDirection(rnd)
+			if _, isMirrorBall := projection.(MirrorBall); isMirrorBall && d.Z < -0.99 {
+				continue // The rim of the mirror ball is singular
+			}
+
+			u, v := projection.UV(d)
+			if u < 0 || u > 1 || v < 0 || v > 1 {
+				t.Fatalf("%T: direction %+v maps outside the image (%v, %v)", projection, d, u, v)
+			}
+
+			d2, ok := projection.Direction(u, v)
+			if !ok || distance(d, d2) > 1e-9 {
+				t.Fatalf("%T: expected direction %+v but got %+v (%v)", projection, d, d2, ok)
+			}
+		}
+	}
+
+	if _, ok := (MirrorBall{}).Direction(0.01, 0.01); ok {
+		t.Errorf("expected mirror ball corner to not map to any direction")
+	}
+	if _, ok := (Cross{}).Direction(0.1, 0.1); ok {
+		t.Errorf("expected cross corner to not map to any direction")
+	}
+}
+
+func TestConvert(t *testing.T) {
+	equirectangular := floatimage.NewNRGBAF32(256, 128)
+	for y := 0; y < 128; y++ {
+		for x := 0; x < 256; x++ {
+			d, _ := Equirectangular{}.Direction((float64(x)+0.5)/256, (float64(y)+0.5)/128)
+			equirectangular.Set(x, y, directionColor(d))
+		}
+	}
+
+	rnd := rand.New(rand.NewSource(2))
+	for _, projection := range projections {
+		width, height := 192, 192
+		switch p := projection.(type) {
+		case Equirectangular:
+			width, height = 256, 128
+		case Cross:
+			columns, rows := p.Size()
+			width, height = columns*48, rows*48
+		}
+
+		converted := Convert(equirectangular, Equirectangular{}, projection, width, height, sampler.Bilinear)
+		if _, ok := converted.(*floatimage.NRGBAF32); !ok {
+			t.Fatalf("expected converted image of the same type as the source but got %T", converted)
+		}
+
+		environment := NewEnvironment(converted, projection, sampler.Bilinear)
+		for i := 0; i < 1000; i++ {
+			d := randomDirection(rnd)
+			if _, isMirrorBall := projection.(MirrorBall); isMirrorBall && d.Z < -0.5 {
+				continue // The mirror ball is heavily compressed near its rim
+			}
+
+			expected, c := directionColor(d), environment.Lookup(d)
+			if math.Abs(c.R-expected.R) > 0.03 || math.Abs(c.G-expected.G) > 0.03 || math.Abs(c.B-expected.B) > 0.03 {
+				t.Fatalf("%T: expected %+v in direction %+v but got %+v", projection, expected, d, c)
+			}
+		}
+	}
+}
+
+func TestCubeFacesRoundTrip(t *testing.T) {
+	for _, layout := range []Cross{{}, {Vertical: true}} {
+		columns, rows := layout.Size()
+		cross := floatimage.NewRGBAF64(columns*8, rows*8)
+		for i := range cross.Pix {
+			cross.Pix[i] = float64(i%97) / 97
+		}
+
+		// Clear the unused parts of the layout.
+		for y := 0; y < rows*8; y++ {
+			for x := 0; x < columns*8; x++ {
+				if _, ok := layout.Direction((float64(x)+0.5)/float64(columns*8), (float64(y)+0.5)/float64(rows*8)); !ok {
+					cross.Set(x, y, floatcolor.RGBAF64{})
+				}
+			}
+		}
+
+		var faces [6]image.Image
+		for i, face := range CubeFaces(cross, layout) {
+			faces[i] = face
+		}
+
+		assembled := AssembleCross(faces, layout).(*floatimage.RGBAF64)
+		for i := range cross.Pix {
+			if assembled.Pix[i] != cross.Pix[i] {
+				t.Fatalf("expected assembled cross to be the same as the original cross")
+			}
+		}
+	}
+}
+
+func TestInvalidCross(t *testing.T) {
+	square := func(size int) image.Image { return floatimage.NewRGBAF64(size, size) }
+	for name, f := range map[string]func(){
+		"short":       func() { CubeFaces(floatimage.NewRGBAF64(8, 2), Cross{}) },
+		"empty":       func() { CubeFaces(floatimage.NewRGBAF64(3, 3), Cross{}) },
+		"uneven":      func() { CubeFaces(floatimage.NewRGBAF64(10, 6), Cross{}) },
+		"tall":        func() { CubeFaces(floatimage.NewRGBAF64(8, 8), Cross{}) },
+		"vertical":    func() { CubeFaces(floatimage.NewRGBAF64(8, 6), Cross{Vertical: true}) },
+		"environment": func() { NewEnvironment(floatimage.NewRGBAF64(8, 2), Cross{}, sampler.Bilinear) },
+		"not square": func() {
+			AssembleCross([6]image.Image{square(2), square(2), floatimage.NewRGBAF64(2, 3), square(2), square(2), square(2)}, Cross{})
+		},
+		"mixed sizes": func() {
+			AssembleCross([6]image.Image{square(2), square(2), square(2), square(2), square(2), square(4)}, Cross{})
+		},
+		"empty faces": func() {
+			AssembleCross([6]image.Image{square(0), square(0), square(0), square(0), square(0), square(0)}, Cross{})
+		},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
@@ -0,0 +1,248 @@
+package envmap
+
+import "math"
+
+// Vector is a direction in a right handed coordinate system with Y up. The forward direction is -Z.
+type Vector struct {
+	X, Y, Z float64
+}
+
+// Length returns the length of the vector.
+func (v Vector) Length() float64 {
+	return math.Sqrt(v.X*v.X + v.Y*v.Y + v.Z*v.Z)
+}
+
+// Normalize returns the vector scaled to unit length. The zero vector is returned as is.
+func (v Vector) Normalize() Vector {
+	l := v.Length()
+	if l == 0 {
+		return v
+	}
+	return Vector{v.X / l, v.Y / l, v.Z / l}
+}
+
+// Projection maps directions to normalized image coordinates, where (0, 0) is the top left corner
+// and (1, 1) the bottom right corner of the image, and back.
+type Projection interface {
+	// Direction returns the (unit length) direction at normalized image coordinates (u, v).
+	// It returns false for image positions that do not map to any direction.
+	Direction(u, v float64) (Vector, bool)
+	// UV returns the normalized image coordinates of the direction d.
+	UV(d Vector) (u, v float64)
+}
+
+// Equirectangular is the latitude-longitude projection (2:1 aspect ratio).
+// The horizontal center of the image is the forward direction (-Z) with +X to the right,
+// the top row is straight up (+Y) and the bottom row straight down.
+type Equirectangular struct{}
+
+func (Equirectangular) Direction(u, v float64) (Vector, bool) {
+	phi := (u - 0.5) * 2 * math.Pi
+	theta := v * math.Pi
+	sinTheta := math.Sin(theta)
+	return Vector{sinTheta * math.Sin(phi), math.Cos(theta), -sinTheta * math.Cos(phi)}, true
+}
+
+func (Equirectangular) UV(d Vector) (u, v float64) {
+	d = d.Normalize()
+	u = 0.5 + math.Atan2(d.X, -d.Z)/(2*math.Pi)
+	v = math.Acos(clamp(d.Y, -1, 1)) / math.Pi
+	return u, v
+}
+
+// MirrorBall is the projection of a photographed mirror ball (light probe), seen from +Z looking in the forward direction (-Z).
+// The ball fills the (square) image. Its center reflects the direction back towards the camera (+Z)
+// and its rim the forward direction (-Z). Positions outside the ball do not map to any direction.
+type MirrorBall struct{}
+
+func (MirrorBall) Direction(u, v float64) (Vector, bool) {
+	x, y := 2*u-1, 1-2*v
+	r2 := x*x + y*y
+	if r2 > 1 {
+		return Vector{}, false
+	}
+
+	// Reflect the view direction (0, 0, -1) at the ball normal.
+	nz := math.Sqrt(1 - r2)
+	return Vector{2 * nz * x, 2 * nz * y, 2*nz*nz - 1}, true
+}
+
+func (MirrorBall) UV(d Vector) (u, v float64) {
+	d = d.Normalize()
+
+	// The normal is halfway between the reflected direction and the direction towards the camera.
+	n := Vector{d.X, d.Y, d.Z + 1}.Normalize()
+	if n == (Vector{}) {
+		return 0.5, 0.0 // Straight forward is on the rim, at any angle
+	}
+	return (n.X + 1) / 2, (1 - n.Y) / 2
+}
+
+// Octahedral is the octahedral projection (square image). The upper hemisphere (+Y) maps to the central diamond
+// of the image and the lower hemisphere to the four corner triangles.
+// It has far less distortion than the equirectangular projection and no singularities.
+type Octahedral struct{}
+
+func (Octahedral) Direction(u, v float64) (Vector, bool) {
+	a, b := 2*u-1, 2*v-1
+	y := 1 - math.Abs(a) - math.Abs(b)
+	if y < 0 {
+		a, b = (1-math.Abs(b))*sign(a), (1-math.Abs(a))*sign(b)
+	}
+	return Vector{a, y, b}.Normalize(), true
+}
+
+func (Octahedral) UV(d Vector) (u, v float64) {
+	l := math.Abs(d.X) + math.Abs(d.Y) + math.Abs(d.Z)
+	if l == 0 {
+		return 0.5, 0.5
+	}
+
+	a, b := d.X/l, d.Z/l
+	if d.Y < 0 {
+		a, b = (1-math.Abs(b))*sign(a), (1-math.Abs(a))*sign(b)
+	}
+	return a*0.5 + 0.5, b*0.5 + 0.5
+}
+
+// CubeFace is a face of a cube map.
+type CubeFace int
+
+const (
+	PositiveX CubeFace = iota
+	NegativeX
+	PositiveY
+	NegativeY
+	PositiveZ
+	NegativeZ
+)
+
+// cubeFace returns the cube face the direction points at and the normalized coordinates (s, t) on that face.
+// The face coordinates follow the OpenGL cube map convention.
+func cubeFace(d Vector) (face CubeFace, s, t float64) {
+	ax, ay, az := math.Abs(d.X), math.Abs(d.Y), math.Abs(d.Z)
+
+	var sc, tc, ma float64
+	switch {
+	case ax >= ay && ax >= az:
+		ma = ax
+		if d.X > 0 {
+			face, sc, tc = PositiveX, -d.Z, -d.Y
+		} else {
+			face, sc, tc = NegativeX, d.Z, -d.Y
+		}
+	case ay >= az:
+		ma = ay
+		if d.Y > 0 {
+			face, sc, tc = PositiveY, d.X, d.Z
+		} else {
+			face, sc, tc = NegativeY, d.X, -d.Z
+		}
+	default:
+		ma = az
+		if d.Z > 0 {
+			face, sc, tc = PositiveZ, d.X, -d.Y
+		} else {
+			face, sc, tc = NegativeZ, -d.X, -d.Y
+		}
+	}
+
+	if ma == 0 {
+		return PositiveX, 0.5, 0.5
+	}
+	return face, (sc/ma + 1) / 2, (tc/ma + 1) / 2
+}
+
+// cubeDirection returns the direction at normalized coordinates (s, t) on the cube face.
+func cubeDirection(face CubeFace, s, t float64) Vector {
+	sc, tc := 2*s-1, 2*t-1
+
+	var d Vector
+	switch face {
+	case PositiveX:
+		d = Vector{1, -tc, -sc}
+	case NegativeX:
+		d = Vector{-1, -tc, sc}
+	case PositiveY:
+		d = Vector{sc, 1, tc}
+	case NegativeY:
+		d = Vector{sc, -1, -tc}
+	case PositiveZ:
+		d = Vector{sc, -tc, 1}
+	default:
+		d = Vector{-sc, -tc, -1}
+	}
+	return d.Normalize()
+}
+
+// Cross is a cube map with its six faces laid out as an unfolded cube in a single image.
+// The horizontal cross (4:3 aspect ratio) is laid out as
+//
+//	    +Y
+//	-X  +Z  +X  -Z
+//	    -Y
+//
+// and the vertical cross (3:4 aspect ratio) has the -Z face below -Y instead, rotated 180 degrees.
+// The parts of the image outside the faces do not map to any direction.
+type Cross struct {
+	Vertical bool
+}
+
+// crossCells are the cell (column, row) of each face in the horizontal cross.
+var crossCells = [6][2]int{PositiveX: {2, 1}, NegativeX: {0, 1}, PositiveY: {1, 0}, NegativeY: {1, 2}, PositiveZ: {1, 1}, NegativeZ: {3, 1}}
+
+// Size returns the size of the cross layout in cells (faces).
+func (c Cross) Size() (columns, rows int) {
+	if c.Vertical {
+		return 3, 4
+	}
+	return 4, 3
+}
+
+// Cell returns the cell (column, row) of the face in the layout.
+func (c Cross) Cell(face CubeFace) (column, row int) {
+	if c.Vertical && face == NegativeZ {
+		return 1, 3
+	}
+	return crossCells[face][0], crossCells[face][1]
+}
+
+func (c Cross) Direction(u, v float64) (Vector, bool) {
+	columns, rows := c.Size()
+	x, y := u*float64(columns), v*float64(rows)
+	column, row := int(math.Floor(x)), int(math.Floor(y))
+
+	for face := PositiveX; face <= NegativeZ; face++ {
+		if fc, fr := c.Cell(face); fc == column && fr == row {
+			s, t := x-float64(column), y-float64(row)
+			if c.Vertical && face == NegativeZ {
+				s, t = 1-s, 1-t
+			}
+			return cubeDirection(face, s, t), true
+		}
+	}
+
+	return Vector{}, false
+}
+
+func (c Cross) UV(d Vector) (u, v float64) {
+	face, s, t := cubeFace(d)
+	if c.Vertical && face == NegativeZ {
+		s, t = 1-s, 1-t
+	}
+
+	columns, rows := c.Size()
+	column, row := c.Cell(face)
+	return (float64(column) + s) / float64(columns), (float64(row) + t) / float64(rows)
+}
+
+func sign(v float64) float64 {
+	if v < 0 {
+		return -1.0
+	}
+	return 1.0
+}
+
+func clamp(v, min, max float64) float64 {
+	return math.Max(min, math.Min(max, v))
+}
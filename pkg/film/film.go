@@ -0,0 +1,148 @@
+package film
+
+import (
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"floatimage/pkg/resample"
+	"image"
+	"math"
+)
+
+// channels is the number of values per pixel of a film, the weighted sums of red, green, blue, and alpha and the sum of the weights.
+const channels = 5
+
+// Film is an accumulation buffer for the samples of a renderer. Each sample is spread over the pixels
+// around its position with a reconstruction filter, and every pixel keeps the weighted sum of the samples
+// and the sum of their weights. Resolve divides the two at any time, so a film can be displayed progressively
+// while samples are still added.
+//
+// Colors are accumulated premultiplied with alpha in float64 precision. A Film is not safe for concurrent use.
+type Film struct {
+	filter  resample.Filter
+	rect    image.Rectangle
+	stride  int
+	sums    []float64
+	samples int
+}
+
+// New returns a new, empty film with bounds r, reconstructing the image with the given filter (see DefaultFilter).
+func New(r image.Rectangle, filter resample.Filter) *Film {
+	r = r.Canon()
+	return &Film{
+		filter: filter,
+		rect:   r,
+		stride: channels * r.Dx(),
+		sums:   make([]float64, channels*r.Dx()*r.Dy()),
+	}
+}
+
+// Bounds returns the bounds of the film.
+func (f *Film) Bounds() image.Rectangle {
+	return f.rect
+}
+
+// Filter returns the reconstruction filter of the film.
+func (f *Film) Filter() resample.Filter {
+	return f.filter
+}
+
+// Samples returns the number of samples added since the film was created or cleared.
+func (f *Film) Samples() int {
+	return f.samples
+}
+
+// Clear removes all samples from the film.
+func (f *Film) Clear() {
+	for i := range f.sums {
+		f.sums[i] = 0
+	}
+	f.samples = 0
+}
+
+// AddSample adds a sample with the (premultiplied) color c at position (x, y). Pixel (px, py) covers
+// [px, px+1) x [py, py+1), so its center is at (px+0.5, py+0.5). The sample contributes to all pixels
+// whose center is within the filter support, including pixels of the film when the sample itself is outside.
+func (f *Film) AddSample(x, y float64, c floatcolor.RGBAF64) {
+	f.samples++
+
+	if f.filter.Support <= 0 {
+		px, py := int(math.Floor(x)), int(math.Floor(y))
+		if (image.Point{X: px, Y: py}).In(f.rect) {
+			f.add(px, py, 1.0, c)
+		}
+		return
+	}
+
+	support := f.filter.Support
+	x0, x1 := f.pixelRange(x, support, f.rect.Min.X, f.rect.Max.X)
+	y0, y1 := f.pixelRange(y, support, f.rect.Min.Y, f.rect.Max.Y)
+
+	for py := y0; py < y1; py++ {
+		wy := f.filter.Kernel(y - (float64(py) + 0.5))
+		if wy == 0 {
+			continue
+		}
+		for px := x0; px < x1; px++ {
+			if w := wy * f.filter.Kernel(x-(float64(px)+0.5)); w != 0 {
+				f.add(px, py, w, c)
+			}
+		}
+	}
+}
+
+// pixelRange returns the range [p0, p1) of pixels within [min, max) whose center is within support of v.
+func (f *Film) pixelRange(v, support float64, min, max int) (int, int) {
+	p0 := int(math.Ceil(v - 0.5 - support))
+	p1 := int(math.Floor(v-0.5+support)) + 1
+	if p0 < min {
+		p0 = min
+	}
+	if p1 > max {
+		p1 = max
+	}
+	return p0, p1
+}
+
+func (f *Film) add(x, y int, w float64, c floatcolor.RGBAF64) {
+	i := (y-f.rect.Min.Y)*f.stride + (x-f.rect.Min.X)*channels
+	s := f.sums[i : i+channels : i+channels] // Small cap improves performance, see https://golang.org/issue/27857
+	s[0] += w * c.R
+	s[1] += w * c.G
+	s[2] += w * c.B
+	s[3] += w * c.A
+	s[4] += w
+}
+
+// Weight returns the sum of the filter weights of the samples added to pixel (x, y),
+// a measure of how well the pixel is sampled. It returns zero outside the bounds of the film.
+func (f *Film) Weight(x, y int) float64 {
+	if !(image.Point{X: x, Y: y}.In(f.rect)) {
+		return 0
+	}
+	return f.sums[(y-f.rect.Min.Y)*f.stride+(x-f.rect.Min.X)*channels+4]
+}
+
+// Resolve returns the current image of the film, the weighted average of the samples of each pixel,
+// as a new float image of the same type as like (see floatimage.NewLike) with the bounds of the film.
+// Pixels without any samples are transparent. The film is not changed and more samples can be added afterwards.
+func (f *Film) Resolve(like image.Image) floatimage.FloatImage {
+	r := f.rect
+	result := floatimage.NewRGBAF64WithBounds(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+
+	floatimage.ForEachBand(r, 0, func(band image.Rectangle) {
+		for y := band.Min.Y; y < band.Max.Y; y++ {
+			row := result.Row(y)
+			i := (y - r.Min.Y) * f.stride
+			for x := 0; x < r.Dx(); x++ {
+				s := f.sums[i : i+channels : i+channels] // Small cap improves performance, see https://golang.org/issue/27857
+				if w := s[4]; w != 0 {
+					d := row[4*x : 4*x+4 : 4*x+4]
+					d[0], d[1], d[2], d[3] = s[0]/w, s[1]/w, s[2]/w, s[3]/w
+				}
+				i += channels
+			}
+		}
+	})
+
+	return floatimage.FromRGBAF64(result, like)
+}
@@ -0,0 +1,117 @@
+package film
+
+import (
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"floatimage/pkg/resample"
+	"image"
+	"math"
+	"testing"
+)
+
+var filters = []resample.Filter{
+	Box(0.5),
+	Box(1.0),
+	Tent(1.0),
+	Gaussian(1.5, 0.5),
+	MitchellNetravali(2.0, 1.0/3.0, 1.0/3.0),
+	BlackmanHarris(2.0),
+	{Name: "nearest"},
+}
+
+func TestFilters(t *testing.T) {
+	for _, filter := range filters[:len(filters)-1] {
+		if filter.Kernel(0) <= 0 {
+			t.Errorf("%s: expected positive weight at the center but got %v", filter.Name, filter.Kernel(0))
+		}
+		if w := filter.Kernel(filter.Support); math.Abs(w) > 1e-12 {
+			t.Errorf("%s: expected no weight at the radius but got %v", filter.Name, w)
+		}
+		if w1, w2 := filter.Kernel(-0.3), filter.Kernel(0.3); math.Abs(w1-w2) > 1e-12 {
+			t.Errorf("%s: expected symmetric filter but got %v and %v", filter.Name, w1, w2)
+		}
+	}
+
+	if mitchell := MitchellNetravali(2.0, 1.0/3.0, 1.0/3.0); mitchell.Kernel(1.5) >= 0 {
+		t.Errorf("expected negative lobe of the Mitchell-Netravali filter but got %v", mitchell.Kernel(1.5))
+	}
+}
+
+func TestConstantColor(t *testing.T) {
+	c := floatcolor.RGBAF64{R: 0.4, G: 0.2, B: 0.1, A: 0.5}
+
+	for _, filter := range filters {
+		film := New(image.Rect(-2, 3, 6, 8), filter)
+
+		// Stratified samples over the film and a margin around it, so every pixel gets its full filter footprint.
+		for y := -4.0; y < 10; y += 0.25 {
+			for x := -4.0; x < 8; x += 0.25 {
+				film.AddSample(x+0.125, y+0.125, c)
+			}
+		}
+
+		img := film.Resolve(floatimage.NewRGBAF64(0, 0)).(*floatimage.RGBAF64)
+		if img.Rect != film.Bounds() {
+			t.Fatalf("%s: expected bounds %v but got %v", filter.Name, film.Bounds(), img.Rect)
+		}
+		for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+			for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+				got := img.RGBAF64At(x, y)
+				if math.Abs(got.R-c.R) > 1e-9 || math.Abs(got.G-c.G) > 1e-9 || math.Abs(got.B-c.B) > 1e-9 || math.Abs(got.A-c.A) > 1e-9 {
+					t.Fatalf("%s: expected constant color %+v at (%d, %d) but got %+v", filter.Name, c, x, y, got)
+				}
+			}
+		}
+	}
+}
+
+func TestBoxAverage(t *testing.T) {
+	film := New(image.Rect(0, 0, 4, 2), Box(0.5))
+
+	film.AddSample(2.0, 0.5, floatcolor.RGBAF64{R: 1.0, A: 1.0}) // On the left edge of pixel 2
+	film.AddSample(2.9, 0.9, floatcolor.RGBAF64{G: 1.0, A: 1.0})
+	film.AddSample(0.5, 1.5, floatcolor.RGBAF64{B: 1.0, A: 1.0})
+	film.AddSample(-0.5, 1.5, floatcolor.RGBAF64{R: 1.0, A: 1.0}) // Outside, pixel centers not within the radius
+
+	if film.Samples() != 4 {
+		t.Errorf("expected 4 samples but got %d", film.Samples())
+	}
+	if film.Weight(2, 0) != 2 || film.Weight(0, 1) != 1 || film.Weight(1, 0) != 0 {
+		t.Errorf("expected samples to be added to the pixel they fall into")
+	}
+
+	img := film.Resolve(floatimage.NewNRGBAF32(0, 0)).(*floatimage.NRGBAF32)
+	if c := img.NRGBAF32At(2, 0); c.R != 0.5 || c.G != 0.5 || c.A != 1.0 {
+		t.Errorf("expected the average of the samples of pixel (2, 0) but got %+v", c)
+	}
+	if c := img.NRGBAF32At(0, 1); c.B != 1.0 || c.R != 0.0 {
+		t.Errorf("expected blue pixel (0, 1) but got %+v", c)
+	}
+	if c := img.NRGBAF32At(1, 0); c != (floatcolor.NRGBAF32{}) {
+		t.Errorf("expected transparent pixel without samples but got %+v", c)
+	}
+}
+
+func TestProgressiveResolve(t *testing.T) {
+	film := New(image.Rect(0, 0, 3, 3), Tent(1.0))
+	film.AddSample(1.5, 1.5, floatcolor.RGBAF64{R: 1.0, A: 1.0})
+
+	first := film.Resolve(floatimage.NewRGBAF64(0, 0)).(*floatimage.RGBAF64)
+	if c := first.RGBAF64At(1, 1); c.R != 1.0 {
+		t.Errorf("expected red center pixel but got %+v", c)
+	}
+
+	film.AddSample(1.5, 1.5, floatcolor.RGBAF64{B: 1.0, A: 1.0})
+	second := film.Resolve(floatimage.NewRGBAF64(0, 0)).(*floatimage.RGBAF64)
+	if c := second.RGBAF64At(1, 1); c.R != 0.5 || c.B != 0.5 {
+		t.Errorf("expected average of both samples but got %+v", c)
+	}
+	if c := first.RGBAF64At(1, 1); c.R != 1.0 {
+		t.Errorf("expected earlier resolved image to be unchanged but got %+v", c)
+	}
+
+	film.Clear()
+	if film.Samples() != 0 || film.Weight(1, 1) != 0 {
+		t.Errorf("expected empty film after clear")
+	}
+}
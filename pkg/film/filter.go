@@ -0,0 +1,72 @@
+package film
+
+import (
+	"floatimage/pkg/resample"
+	"math"
+)
+
+// The reconstruction filters of a film are resample.Filter values, applied separably in x and y.
+// Their Support is the radius in pixels around a sample within which pixels receive a weighted share of it.
+// Unlike for resizing, a filter with zero support adds each sample to the pixel it falls into.
+
+// DefaultFilter is the recommended filter to pass to New, a Gaussian with radius 1.5 and standard deviation 0.5 pixels.
+// New does not fall back to it, a film always uses the filter it is given.
+var DefaultFilter = Gaussian(1.5, 0.5)
+
+// Box returns the box filter with the given radius. All pixels whose center is within the radius get the same weight.
+// A radius of 0.5 adds each sample to the pixel it falls into, the plain average of the samples per pixel.
+func Box(radius float64) resample.Filter {
+	return resample.Filter{Name: "box", Support: radius, Kernel: func(x float64) float64 {
+		if x >= -radius && x < radius {
+			return 1.0
+		}
+		return 0.0
+	}}
+}
+
+// Tent returns the triangle filter with the given radius, falling off linearly from the sample position.
+func Tent(radius float64) resample.Filter {
+	return resample.Filter{Name: "tent", Support: radius, Kernel: func(x float64) float64 {
+		x = math.Abs(x)
+		if x < radius {
+			return 1.0 - x/radius
+		}
+		return 0.0
+	}}
+}
+
+// Gaussian returns the gaussian filter with standard deviation sigma, cut off at the given radius.
+// The value of the gaussian at the radius is subtracted so the filter goes smoothly to zero.
+func Gaussian(radius, sigma float64) resample.Filter {
+	edge := gaussian(radius, sigma)
+	return resample.Filter{Name: "gaussian", Support: radius, Kernel: func(x float64) float64 {
+		return math.Max(0.0, gaussian(x, sigma)-edge)
+	}}
+}
+
+// MitchellNetravali returns the bicubic filter of the Mitchell-Netravali family with parameters b and c
+// (see resample.Bicubic), scaled to the given radius. B = C = 1/3 are the recommended parameters.
+// The filter has negative lobes, so it sharpens but can ring at high contrast edges.
+func MitchellNetravali(radius, b, c float64) resample.Filter {
+	bicubic := resample.Bicubic("mitchell-netravali", b, c)
+	return resample.Filter{Name: bicubic.Name, Support: radius, Kernel: func(x float64) float64 {
+		return bicubic.Kernel(2 * x / radius)
+	}}
+}
+
+// BlackmanHarris returns the four term Blackman-Harris window with the given radius,
+// similar to a gaussian but with a very small response to frequencies above the pixel rate.
+func BlackmanHarris(radius float64) resample.Filter {
+	const a0, a1, a2, a3 = 0.35875, 0.48829, 0.14128, 0.01168
+	return resample.Filter{Name: "blackman-harris", Support: radius, Kernel: func(x float64) float64 {
+		if x <= -radius || x >= radius {
+			return 0.0
+		}
+		t := math.Pi * (x/radius + 1)
+		return a0 - a1*math.Cos(t) + a2*math.Cos(2*t) - a3*math.Cos(3*t)
+	}}
+}
+
+func gaussian(x, sigma float64) float64 {
+	return math.Exp(-x * x / (2 * sigma * sigma))
+}
@@ -0,0 +1,220 @@
+package filter
+
+import (
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"image"
+)
+
+// EdgeMode decides what pixel values are used where a kernel reaches outside the image.
+type EdgeMode int
+
+const (
+	// EdgeClamp repeats the outermost pixels of the image.
+	EdgeClamp EdgeMode = iota
+	// EdgeWrap wraps around to the opposite side of the image (tiling).
+	EdgeWrap
+	// EdgeMirror mirrors the image at its edges, repeating the edge pixels (...cba|abc...|cba...).
+	EdgeMirror
+	// EdgeConstant uses a constant color (Options.Border) outside the image.
+	EdgeConstant
+	// EdgeTransparent treats everything outside the image as fully transparent.
+	EdgeTransparent
+)
+
+// Options are the options of a convolution.
+type Options struct {
+	// Edge is the edge mode of the convolution.
+	Edge EdgeMode
+	// Border is the color outside the image for edge mode EdgeConstant.
+	Border floatcolor.NRGBAF64
+	// PreserveAlpha convolves only the (non premultiplied) red, green, and blue values and keeps the alpha of the source image.
+	// It is typically used with kernels that do not sum up to 1.0, such as edge detection kernels,
+	// where a convolved alpha channel makes no sense.
+	PreserveAlpha bool
+}
+
+// Convolve convolves the image with the kernel and returns the result as a new float image
+// of the same type as img (see floatimage.NewLike) with the same bounds.
+//
+// The convolution is done in float64 precision on premultiplied values, so transparent pixels
+// do not bleed their color into their neighbours. The kernel is applied as is, without being flipped
+// (i.e. as a correlation, as image processing applications usually do).
+func Convolve(img image.Image, k *Kernel, options Options) floatimage.FloatImage {
+	src, alpha := prepare(img, options)
+	return finish(convolve2D(src, k, options), alpha, img)
+}
+
+// ConvolveSeparable convolves the image with the separable kernel and returns the result
+// as a new float image of the same type as img. The result is the same as for Convolve with k.Kernel(),
+// but the cost grows linearly instead of quadratically with the kernel size.
+func ConvolveSeparable(img image.Image, k *SeparableKernel, options Options) floatimage.FloatImage {
+	src, alpha := prepare(img, options)
+	return finish(convolveSeparable(src, k, options), alpha, img)
+}
+
+// prepare returns the premultiplied working copy of the image. With PreserveAlpha the working copy holds
+// non premultiplied values and the alpha values of the image are returned separately.
+func prepare(img image.Image, options Options) (src *floatimage.RGBAF64, alpha []float64) {
+	src = floatimage.ToRGBAF64(img)
+	if !options.PreserveAlpha {
+		return src, nil
+	}
+
+	alpha = make([]float64, 0, src.Rect.Dx()*src.Rect.Dy())
+	for i := 0; i < len(src.Pix); i += 4 {
+		s := src.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+		alpha = append(alpha, s[3])
+		s[0], s[1], s[2] = floatcolor.Unpremultiply(s[0], s[1], s[2], s[3])
+		s[3] = 1.0
+	}
+	return src, alpha
+}
+
+// finish restores the preserved alpha values (if any) and converts the result to the image type of like.
+func finish(result *floatimage.RGBAF64, alpha []float64, like image.Image) floatimage.FloatImage {
+	if alpha != nil {
+		for i := 0; i < len(result.Pix); i += 4 {
+			s := result.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+			a := alpha[i/4]
+			s[0], s[1], s[2] = floatcolor.Premultiply(s[0], s[1], s[2], a)
+			s[3] = a
+		}
+	}
+	return floatimage.FromRGBAF64(result, like)
+}
+
+// border returns the premultiplied value used outside the image for the edge mode (constant and transparent modes only).
+func border(options Options) [4]float64 {
+	if options.Edge != EdgeConstant {
+		return [4]float64{}
+	}
+
+	c := options.Border
+	if options.PreserveAlpha {
+		return [4]float64{c.R, c.G, c.B, 1.0}
+	}
+	return [4]float64{c.R * c.A, c.G * c.A, c.B * c.A, c.A}
+}
+
+// edgeIndex maps the index i to an index within [0, n) according to the edge mode.
+// It returns false if the index is outside and the border value is to be used instead.
+func edgeIndex(i, n int, mode EdgeMode) (int, bool) {
+	if i >= 0 && i < n {
+		return i, true
+	}
+
+	switch mode {
+	case EdgeClamp:
+		if i < 0 {
+			return 0, true
+		}
+		return n - 1, true
+	case EdgeWrap:
+		return ((i % n) + n) % n, true
+	case EdgeMirror:
+		period := 2 * n
+		i = ((i % period) + period) % period
+		if i >= n {
+			i = period - 1 - i
+		}
+		return i, true
+	default:
+		return 0, false
+	}
+}
+
+func convolve2D(src *floatimage.RGBAF64, k *Kernel, options Options) *floatimage.RGBAF64 {
+	r := src.Rect
+	width, height := r.Dx(), r.Dy()
+	dst := floatimage.NewRGBAF64WithBounds(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+	outside := border(options)
+
+	floatimage.ParallelFor(height, 0, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < width; x++ {
+				var acc [4]float64
+
+				for ky := 0; ky < k.Height; ky++ {
+					sy, insideY := edgeIndex(y+ky-k.CenterY, height, options.Edge)
+
+					for kx := 0; kx < k.Width; kx++ {
+						w := k.Values[ky*k.Width+kx]
+						if w == 0 {
+							continue
+						}
+
+						sx, insideX := edgeIndex(x+kx-k.CenterX, width, options.Edge)
+						if !insideX || !insideY {
+							acc[0], acc[1], acc[2], acc[3] = acc[0]+w*outside[0], acc[1]+w*outside[1], acc[2]+w*outside[2], acc[3]+w*outside[3]
+							continue
+						}
+
+						i := sy*src.Stride + sx*4
+						s := src.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+						acc[0], acc[1], acc[2], acc[3] = acc[0]+w*s[0], acc[1]+w*s[1], acc[2]+w*s[2], acc[3]+w*s[3]
+					}
+				}
+
+				i := y*dst.Stride + x*4
+				d := dst.Pix[i : i+4 : i+4]
+				d[0], d[1], d[2], d[3] = acc[0], acc[1], acc[2], acc[3]
+			}
+		}
+	})
+
+	return dst
+}
+
+func convolveSeparable(src *floatimage.RGBAF64, k *SeparableKernel, options Options) *floatimage.RGBAF64 {
+	outside := border(options)
+	r := src.Rect
+	temp := convolve1D(src, k.Horizontal, 4, src.Stride, r.Dx(), r.Dy(), options.Edge, outside)
+
+	// Rows outside the image are entirely border values, i.e. the border value scaled by the horizontal kernel sum.
+	hs := sum(k.Horizontal)
+	outside = [4]float64{outside[0] * hs, outside[1] * hs, outside[2] * hs, outside[3] * hs}
+
+	return convolve1D(temp, k.Vertical, src.Stride, 4, r.Dy(), r.Dx(), options.Edge, outside)
+}
+
+// convolve1D convolves src with a one dimensional kernel along the axis where consecutive pixels are step Pix elements apart.
+// The n pixels long lines are lineStep Pix elements apart.
+func convolve1D(src *floatimage.RGBAF64, kernel []float64, step, lineStep int, n, lines int, edge EdgeMode, outside [4]float64) *floatimage.RGBAF64 {
+	r := src.Rect
+	dst := floatimage.NewRGBAF64WithBounds(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+	// The strides of src and dst are the same as both have the same bounds and are not sub images.
+
+	center := len(kernel) / 2
+
+	floatimage.ParallelFor(lines, 0, func(start, end int) {
+		for line := start; line < end; line++ {
+			base := line * lineStep
+			for p := 0; p < n; p++ {
+				var acc [4]float64
+
+				for ki, w := range kernel {
+					if w == 0 {
+						continue
+					}
+
+					sp, inside := edgeIndex(p+ki-center, n, edge)
+					if !inside {
+						acc[0], acc[1], acc[2], acc[3] = acc[0]+w*outside[0], acc[1]+w*outside[1], acc[2]+w*outside[2], acc[3]+w*outside[3]
+						continue
+					}
+
+					i := base + sp*step
+					s := src.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+					acc[0], acc[1], acc[2], acc[3] = acc[0]+w*s[0], acc[1]+w*s[1], acc[2]+w*s[2], acc[3]+w*s[3]
+				}
+
+				i := base + p*step
+				d := dst.Pix[i : i+4 : i+4]
+				d[0], d[1], d[2], d[3] = acc[0], acc[1], acc[2], acc[3]
+			}
+		}
+	})
+
+	return dst
+}
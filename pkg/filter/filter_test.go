@@ -0,0 +1,169 @@
+package filter
+
+import (
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func randomImage(width, height int, seed int64) *floatimage.NRGBAF64 {
+	rnd := rand.New(rand.NewSource(seed))
+	img := floatimage.NewNRGBAF64WithBounds(-3, 2, width-3, height+2)
+	for i := range img.Pix {
+		img.Pix[i] = rnd.Float64() * 2.0
+		if i%4 == 3 {
+			img.Pix[i] = rnd.Float64()
+		}
+	}
+	return img
+}
+
+func maxDifference(img1, img2 *floatimage.RGBAF64) float64 {
+	diff := 0.0
+	for i := range img1.Pix {
+		diff = math.Max(diff, math.Abs(img1.Pix[i]-img2.Pix[i]))
+	}
+	return diff
+}
+
+func TestSeparableMatchesKernel(t *testing.T) {
+	k := NewSeparableKernel([]float64{0.1, 0.5, 0.2, 0.2, 0.3}, []float64{0.3, -0.4, 1.1})
+
+	for _, img := range []*floatimage.NRGBAF64{randomImage(13, 9, 1), randomImage(1, 7, 2), randomImage(6, 1, 3)} {
+		for _, edge := range []EdgeMode{EdgeClamp, EdgeWrap, EdgeMirror, EdgeConstant, EdgeTransparent} {
+			options := Options{Edge: edge, Border: floatcolor.NRGBAF64{R: 0.2, G: 0.4, B: 0.6, A: 0.5}}
+
+			separable := ConvolveSeparable(img, k, options)
+			direct := Convolve(img, k.Kernel(), options)
+			if _, ok := separable.(*floatimage.NRGBAF64); !ok {
+				t.Fatalf("expected result of the same type as the source but got %T", separable)
+			}
+			if separable.Bounds() != img.Bounds() {
+				t.Errorf("expected bounds %v but got %v", img.Bounds(), separable.Bounds())
+			}
+
+			if diff := maxDifference(floatimage.ToRGBAF64(separable), floatimage.ToRGBAF64(direct)); diff > 1e-9 {
+				t.Errorf("edge mode %d: separable and direct convolution differ by %v", edge, diff)
+			}
+		}
+	}
+}
+
+func TestEdgeIndex(t *testing.T) {
+	tests := []struct {
+		mode     EdgeMode
+		expected []int
+	}{
+		{EdgeClamp, []int{0, 0, 0, 1, 2, 2, 2}},
+		{EdgeWrap, []int{1, 2, 0, 1, 2, 0, 1}},
+		{EdgeMirror, []int{1, 0, 0, 1, 2, 2, 1}},
+	}
+
+	for _, test := range tests {
+		for i, expected := range test.expected {
+			if index, inside := edgeIndex(i-2, 3, test.mode); !inside || index != expected {
+				t.Errorf("edge mode %d: expected %d for %d but got %d", test.mode, expected, i-2, index)
+			}
+		}
+	}
+
+	if _, inside := edgeIndex(-1, 3, EdgeTransparent); inside {
+		t.Errorf("expected index outside the image for transparent edges")
+	}
+}
+
+func TestTransparentPixelsDoNotBleed(t *testing.T) {
+	img := floatimage.NewNRGBAF32(3, 1)
+	img.Set(0, 0, floatcolor.NRGBAF32{R: 1.0, A: 1.0})
+	img.Set(1, 0, floatcolor.NRGBAF32{G: 1.0, A: 0.0}) // Invisible green
+	img.Set(2, 0, floatcolor.NRGBAF32{R: 1.0, A: 1.0})
+
+	result := ConvolveSeparable(img, Box(1), Options{Edge: EdgeClamp}).(*floatimage.NRGBAF32)
+
+	c := result.At(1, 0).(floatcolor.NRGBAF32)
+	if c.G != 0 || math.Abs(float64(c.R)-1.0) > 1e-6 || math.Abs(float64(c.A)-2.0/3.0) > 1e-6 {
+		t.Errorf("expected transparent pixel to be ignored in the color but got %+v", c)
+	}
+}
+
+func TestEdgeModesOnConstantImage(t *testing.T) {
+	img := floatimage.NewRGBAF64(4, 4)
+	for i := range img.Pix {
+		img.Pix[i] = 0.5
+	}
+
+	clamped := floatimage.ToRGBAF64(ConvolveSeparable(img, Gaussian(1.5), Options{Edge: EdgeClamp}))
+	if diff := maxDifference(clamped, img); diff > 1e-12 {
+		t.Errorf("expected blurred constant image to stay constant but it differs by %v", diff)
+	}
+
+	transparent := ConvolveSeparable(img, Box(1), Options{Edge: EdgeTransparent}).(*floatimage.RGBAF64)
+	if a := transparent.At(0, 0).(floatcolor.RGBAF64).A; math.Abs(a-0.5*4.0/9.0) > 1e-12 {
+		t.Errorf("expected corner alpha %v but got %v", 0.5*4.0/9.0, a)
+	}
+
+	constant := ConvolveSeparable(img, Box(1), Options{Edge: EdgeConstant, Border: floatcolor.NRGBAF64{R: 1, G: 1, B: 1, A: 1}}).(*floatimage.RGBAF64)
+	if a := constant.At(0, 0).(floatcolor.RGBAF64).A; math.Abs(a-(0.5*4.0+5.0)/9.0) > 1e-12 {
+		t.Errorf("expected corner alpha %v but got %v", (0.5*4.0+5.0)/9.0, a)
+	}
+}
+
+func TestSobelAndLaplacian(t *testing.T) {
+	img := floatimage.NewGrayF64(5, 5)
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 5; x++ {
+			img.Set(x, y, floatcolor.GrayF64{Y: float64(x) * 0.25})
+		}
+	}
+
+	sobelX := Convolve(img, SobelX(), Options{Edge: EdgeClamp, PreserveAlpha: true}).(*floatimage.GrayF64)
+	sobelY := Convolve(img, SobelY(), Options{Edge: EdgeClamp, PreserveAlpha: true}).(*floatimage.GrayF64)
+	laplacian := Convolve(img, Laplacian(), Options{Edge: EdgeClamp, PreserveAlpha: true}).(*floatimage.GrayF64)
+
+	if v := sobelX.At(2, 2).(floatcolor.GrayF64).Y; math.Abs(v-2.0) > 1e-12 {
+		t.Errorf("expected horizontal gradient 2.0 but got %v", v)
+	}
+	if v := sobelY.At(2, 2).(floatcolor.GrayF64).Y; math.Abs(v) > 1e-12 {
+		t.Errorf("expected no vertical gradient but got %v", v)
+	}
+	if v := laplacian.At(2, 2).(floatcolor.GrayF64).Y; math.Abs(v) > 1e-12 {
+		t.Errorf("expected no second derivative on a ramp but got %v", v)
+	}
+
+	sharpened := Convolve(img, Sharpen(0.0), Options{}).(*floatimage.GrayF64)
+	for i := range img.Pix {
+		if math.Abs(sharpened.Pix[i]-img.Pix[i]) > 1e-12 {
+			t.Fatalf("expected sharpen amount 0.0 to keep the image")
+		}
+	}
+}
+
+func TestGaussianBlurMatchesKernel(t *testing.T) {
+	img := floatimage.NewRGBAF32(96, 80)
+	for y := 0; y < 80; y++ {
+		for x := 0; x < 96; x++ {
+			v := float32(0.0)
+			if x > 40 || (x-20)*(x-20)+(y-20)*(y-20) < 9 {
+				v = 1.0
+			}
+			img.Set(x, y, floatcolor.RGBAF32{R: v, G: v * 0.5, B: 1 - v, A: 1.0})
+		}
+	}
+
+	for _, sigma := range []float64{1.0, 3.0, 7.5, 12.0} {
+		for _, edge := range []EdgeMode{EdgeClamp, EdgeMirror, EdgeTransparent} {
+			kernel := Gaussian1D(sigma, int(math.Ceil(5*sigma)))
+			direct := floatimage.ToRGBAF64(ConvolveSeparable(img, NewSeparableKernel(kernel, kernel), Options{Edge: edge}))
+			blurred := GaussianBlur(img, sigma, Options{Edge: edge})
+
+			if _, ok := blurred.(*floatimage.RGBAF32); !ok {
+				t.Fatalf("expected result of the same type as the source but got %T", blurred)
+			}
+			if diff := maxDifference(floatimage.ToRGBAF64(blurred), direct); diff > 0.02 {
+				t.Errorf("sigma %v, edge mode %d: fast blur differs from direct convolution by %v", sigma, edge, diff)
+			}
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package filter
+
+import (
+	"fmt"
+	"math"
+)
+
+// Kernel is a two dimensional convolution kernel.
+// Values are stored row by row and the kernel is anchored at (CenterX, CenterY).
+type Kernel struct {
+	Width, Height    int
+	CenterX, CenterY int
+	Values           []float64
+}
+
+// NewKernel creates a new kernel with the given size and values (row by row), anchored at its center.
+// It panics if the number of values does not match the kernel size.
+func NewKernel(width, height int, values ...float64) *Kernel {
+	if width <= 0 || height <= 0 || len(values) != width*height {
+		panic(fmt.Sprintf("filter: kernel of size %dx%d can not have %d values", width, height, len(values)))
+	}
+
+	return &Kernel{Width: width, Height: height, CenterX: width / 2, CenterY: height / 2, Values: append([]float64(nil), values...)}
+}
+
+// At returns the kernel value at (x, y), relative to the top left corner of the kernel.
+func (k *Kernel) At(x, y int) float64 {
+	return k.Values[y*k.Width+x]
+}
+
+// Sum returns the sum of all kernel values.
+func (k *Kernel) Sum() float64 {
+	return sum(k.Values)
+}
+
+// Normalize scales the kernel values so that they sum up to 1.0. Kernels summing up to zero are left as is.
+func (k *Kernel) Normalize() *Kernel {
+	normalize(k.Values)
+	return k
+}
+
+// SeparableKernel is a two dimensional kernel that is the outer product of a horizontal and a vertical one dimensional kernel.
+// Convolving with a separable kernel costs len(Horizontal) + len(Vertical) operations per pixel
+// instead of len(Horizontal) * len(Vertical).
+// Both one dimensional kernels are anchored at their center element (index len/2).
+type SeparableKernel struct {
+	Horizontal []float64
+	Vertical   []float64
+}
+
+// NewSeparableKernel creates a new separable kernel from a horizontal and a vertical one dimensional kernel.
+func NewSeparableKernel(horizontal, vertical []float64) *SeparableKernel {
+	return &SeparableKernel{Horizontal: append([]float64(nil), horizontal...), Vertical: append([]float64(nil), vertical...)}
+}
+
+// Kernel returns the separable kernel as an ordinary two dimensional kernel.
+func (k *SeparableKernel) Kernel() *Kernel {
+	values := make([]float64, 0, len(k.Horizontal)*len(k.Vertical))
+	for _, v := range k.Vertical {
+		for _, h := range k.Horizontal {
+			values = append(values, v*h)
+		}
+	}
+	return NewKernel(len(k.Horizontal), len(k.Vertical), values...)
+}
+
+// Box returns a normalized box (mean) kernel of size 2*radius+1.
+func Box(radius int) *SeparableKernel {
+	values := make([]float64, 2*radius+1)
+	for i := range values {
+		values[i] = 1.0
+	}
+	normalize(values)
+	return &SeparableKernel{Horizontal: values, Vertical: append([]float64(nil), values...)}
+}
+
+// Gaussian returns a normalized gaussian kernel with standard deviation sigma.
+// The kernel is truncated at 3*sigma.
+func Gaussian(sigma float64) *SeparableKernel {
+	values := Gaussian1D(sigma, int(math.Ceil(3*sigma)))
+	return &SeparableKernel{Horizontal: values, Vertical: append([]float64(nil), values...)}
+}
+
+// Gaussian1D returns a normalized one dimensional gaussian kernel of size 2*radius+1 with standard deviation sigma.
+func Gaussian1D(sigma float64, radius int) []float64 {
+	if radius < 0 {
+		radius = 0
+	}
+
+	values := make([]float64, 2*radius+1)
+	for i := range values {
+		x := float64(i - radius)
+		if sigma > 0 {
+			values[i] = math.Exp(-x * x / (2 * sigma * sigma))
+		} else if x == 0 {
+			values[i] = 1.0
+		}
+	}
+	normalize(values)
+	return values
+}
+
+// Sharpen returns a 3x3 sharpening kernel. An amount of 0.0 gives the identity kernel,
+// higher values sharpen more. The kernel sums up to 1.0 and keeps the overall brightness.
+func Sharpen(amount float64) *Kernel {
+	return NewKernel(3, 3,
+		0, -amount, 0,
+		-amount, 1+4*amount, -amount,
+		0, -amount, 0)
+}
+
+// SobelX returns the 3x3 Sobel kernel for horizontal gradients (positive where values increase to the right).
+func SobelX() *Kernel {
+	return NewKernel(3, 3,
+		-1, 0, 1,
+		-2, 0, 2,
+		-1, 0, 1)
+}
+
+// SobelY returns the 3x3 Sobel kernel for vertical gradients (positive where values increase downwards).
+func SobelY() *Kernel {
+	return NewKernel(3, 3,
+		-1, -2, -1,
+		0, 0, 0,
+		1, 2, 1)
+}
+
+// Laplacian returns the 3x3 Laplacian kernel (4-neighbourhood).
+func Laplacian() *Kernel {
+	return NewKernel(3, 3,
+		0, 1, 0,
+		1, -4, 1,
+		0, 1, 0)
+}
+
+func sum(values []float64) float64 {
+	s := 0.0
+	for _, v := range values {
+		s += v
+	}
+	return s
+}
+
+func normalize(values []float64) {
+	s := sum(values)
+	if s == 0 {
+		return
+	}
+	for i := range values {
+		values[i] /= s
+	}
+}
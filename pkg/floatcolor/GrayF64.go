@@ -0,0 +1,87 @@
+package floatcolor
+
+import "image/color"
+
+// GrayF64 is a fully opaque single channel (gray scale) color.
+type GrayF64 struct {
+	Y float64
+	// Precise rounds channel values to the nearest integer when converting to 8 and 16 bit colors.
+	//
+	// Deprecated: Precise is the same as the RoundHalfAwayFromZero quantization, set Quantization instead.
+	Precise bool
+	// Quantization is the strategy to convert channel values to 8 and 16 bit colors.
+	Quantization Quantization
+}
+
+var (
+	GrayF64Model = color.ModelFunc(grayf64Model)
+)
+
+// NewGrayF64 creates a new GrayF64 color.
+// It is not set to "precise".
+func NewGrayF64(y float64) GrayF64 {
+	return GrayF64{Y: y, Precise: false}
+}
+
+func (grayf64 GrayF64) RGBA() (r, g, b, a uint32) {
+	y := uint32(clampF64(grayf64.Y*0xffff, 0x0000, 0xffff, grayf64.quantization()))
+	return y, y, y, 0xffff
+}
+
+func (grayf64 GrayF64) AsNRGBA() color.NRGBA {
+	y := uint8(clampF64(grayf64.Y*0xff, 0x00, 0xff, grayf64.quantization()))
+	return color.NRGBA{R: y, G: y, B: y, A: 0xff}
+}
+
+func (grayf64 GrayF64) AsRGBA() color.RGBA {
+	y := uint8(clampF64(grayf64.Y*0xff, 0x00, 0xff, grayf64.quantization()))
+	return color.RGBA{R: y, G: y, B: y, A: 0xff}
+}
+
+func (grayf64 *GrayF64) SetPrecise(usePreciseCalculation bool) {
+	grayf64.Precise = usePreciseCalculation
+}
+
+// SetQuantization sets the strategy to convert channel values to 8 and 16 bit colors.
+func (grayf64 *GrayF64) SetQuantization(q Quantization) {
+	grayf64.Quantization = q
+}
+
+// quantization returns the quantization of the color, taking the deprecated Precise flag into account.
+func (grayf64 GrayF64) quantization() Quantization {
+	return grayf64.Quantization.WithPrecise(grayf64.Precise)
+}
+
+// Luminance returns the relative luminance of linear red, green, and blue values
+// using the Rec. 709 (sRGB) primaries.
+func Luminance(r, g, b float64) float64 {
+	return 0.2126*r + 0.7152*g + 0.0722*b
+}
+
+// grayf64Model converts colors to gray scale using the relative luminance of the
+// alpha premultiplied red, green, and blue values (as if composited over black).
+func grayf64Model(c color.Color) color.Color {
+	if _, ok := c.(GrayF64); ok {
+		return c
+	}
+
+	if rgbaf, ok := c.(RGBAF64); ok {
+		return GrayF64{Y: Luminance(rgbaf.R, rgbaf.G, rgbaf.B)}
+	}
+
+	if rgbaf, ok := c.(RGBAF32); ok {
+		return GrayF64{Y: Luminance(float64(rgbaf.R), float64(rgbaf.G), float64(rgbaf.B))}
+	}
+
+	if nrgbaf, ok := c.(NRGBAF64); ok {
+		return GrayF64{Y: Luminance(nrgbaf.R, nrgbaf.G, nrgbaf.B) * nrgbaf.A}
+	}
+
+	if nrgbaf, ok := c.(NRGBAF32); ok {
+		return GrayF64{Y: Luminance(float64(nrgbaf.R), float64(nrgbaf.G), float64(nrgbaf.B)) * float64(nrgbaf.A)}
+	}
+
+	r, g, b, _ := c.RGBA()
+	conv := 1.0 / 0xffff
+	return GrayF64{Y: Luminance(float64(r)*conv, float64(g)*conv, float64(b)*conv)}
+}
@@ -4,7 +4,12 @@ import "image/color"
 
 type NRGBAF32 struct {
 	R, G, B, A float32
-	Precise    bool
+	// Precise rounds channel values to the nearest integer when converting to 8 and 16 bit colors.
+	//
+	// Deprecated: Precise is the same as the RoundHalfAwayFromZero quantization, set Quantization instead.
+	Precise bool
+	// Quantization is the strategy to convert channel values to 8 and 16 bit colors.
+	Quantization Quantization
 }
 
 var (
@@ -28,29 +33,29 @@ func NewNRGBAF32PremultipliedAlpha(red, green, blue, alpha float32) NRGBAF32 {
 
 func (nrgbaf32 NRGBAF32) RGBA() (r, g, b, a uint32) {
 	conv := nrgbaf32.A * 0xffff
-	return uint32(clampF32(nrgbaf32.R*conv, 0.0, 0xffff, nrgbaf32.Precise)),
-		uint32(clampF32(nrgbaf32.G*conv, 0.0, 0xffff, nrgbaf32.Precise)),
-		uint32(clampF32(nrgbaf32.B*conv, 0.0, 0xffff, nrgbaf32.Precise)),
-		uint32(clampF32(nrgbaf32.A*0xffff, 0.0, 0xffff, nrgbaf32.Precise))
+	return uint32(clampF32(nrgbaf32.R*conv, 0.0, 0xffff, nrgbaf32.quantization())),
+		uint32(clampF32(nrgbaf32.G*conv, 0.0, 0xffff, nrgbaf32.quantization())),
+		uint32(clampF32(nrgbaf32.B*conv, 0.0, 0xffff, nrgbaf32.quantization())),
+		uint32(clampF32(nrgbaf32.A*0xffff, 0.0, 0xffff, nrgbaf32.quantization()))
 }
 
 func (nrgbaf32 NRGBAF32) AsNRGBA() color.NRGBA {
 	const conv = float32(0xff)
 	return color.NRGBA{
-		R: uint8(clampF32(nrgbaf32.R*conv, 0x00, 0xff, nrgbaf32.Precise)),
-		G: uint8(clampF32(nrgbaf32.G*conv, 0x00, 0xff, nrgbaf32.Precise)),
-		B: uint8(clampF32(nrgbaf32.B*conv, 0x00, 0xff, nrgbaf32.Precise)),
-		A: uint8(clampF32(nrgbaf32.A*conv, 0x00, 0xff, nrgbaf32.Precise)),
+		R: uint8(clampF32(nrgbaf32.R*conv, 0x00, 0xff, nrgbaf32.quantization())),
+		G: uint8(clampF32(nrgbaf32.G*conv, 0x00, 0xff, nrgbaf32.quantization())),
+		B: uint8(clampF32(nrgbaf32.B*conv, 0x00, 0xff, nrgbaf32.quantization())),
+		A: uint8(clampF32(nrgbaf32.A*conv, 0x00, 0xff, nrgbaf32.quantization())),
 	}
 }
 
 func (nrgbaf32 NRGBAF32) AsRGBA() color.RGBA {
 	conv := nrgbaf32.A * 0xff
 	return color.RGBA{
-		R: uint8(clampF32(nrgbaf32.R*conv, 0x00, 0xff, nrgbaf32.Precise)),
-		G: uint8(clampF32(nrgbaf32.G*conv, 0x00, 0xff, nrgbaf32.Precise)),
-		B: uint8(clampF32(nrgbaf32.B*conv, 0x00, 0xff, nrgbaf32.Precise)),
-		A: uint8(clampF32(nrgbaf32.A*0xff, 0x00, 0xff, nrgbaf32.Precise)),
+		R: uint8(clampF32(nrgbaf32.R*conv, 0x00, 0xff, nrgbaf32.quantization())),
+		G: uint8(clampF32(nrgbaf32.G*conv, 0x00, 0xff, nrgbaf32.quantization())),
+		B: uint8(clampF32(nrgbaf32.B*conv, 0x00, 0xff, nrgbaf32.quantization())),
+		A: uint8(clampF32(nrgbaf32.A*0xff, 0x00, 0xff, nrgbaf32.quantization())),
 	}
 }
 
@@ -58,6 +63,16 @@ func (nrgbaf32 *NRGBAF32) SetPrecise(usePreciseCalculation bool) {
 	nrgbaf32.Precise = usePreciseCalculation
 }
 
+// SetQuantization sets the strategy to convert channel values to 8 and 16 bit colors.
+func (nrgbaf32 *NRGBAF32) SetQuantization(q Quantization) {
+	nrgbaf32.Quantization = q
+}
+
+// quantization returns the quantization of the color, taking the deprecated Precise flag into account.
+func (nrgbaf32 NRGBAF32) quantization() Quantization {
+	return nrgbaf32.Quantization.WithPrecise(nrgbaf32.Precise)
+}
+
 // Mix smoothly mixes the RGB values of two color into one resulting color.
 // Parameter mix determine how much percent of color c2 is in the resulting mixed color.
 // Mix value range is [0.0, 1.0] where the resulting mix of 0.0 gives same color as c1
@@ -100,13 +115,13 @@ func nrgbaf32Model(c color.Color) color.Color {
 	}
 
 	if rgbaf, ok := c.(RGBAF64); ok {
-		conv := 1.0 / rgbaf.A
-		return NRGBAF32{R: float32(rgbaf.R * conv), G: float32(rgbaf.G * conv), B: float32(rgbaf.B * conv), A: float32(rgbaf.A)}
+		r, g, b := UnpremultiplyHidden(rgbaf.R, rgbaf.G, rgbaf.B, rgbaf.A, rgbaf.Hidden)
+		return NRGBAF32{R: float32(r), G: float32(g), B: float32(b), A: float32(rgbaf.A)}
 	}
 
 	if rgbaf, ok := c.(RGBAF32); ok {
-		conv := 1.0 / rgbaf.A
-		return NRGBAF32{R: rgbaf.R * conv, G: rgbaf.G * conv, B: rgbaf.B * conv, A: rgbaf.A}
+		r, g, b := UnpremultiplyHidden(float64(rgbaf.R), float64(rgbaf.G), float64(rgbaf.B), float64(rgbaf.A), hidden64(rgbaf.Hidden))
+		return NRGBAF32{R: float32(r), G: float32(g), B: float32(b), A: rgbaf.A}
 	}
 
 	if nrgba, ok := c.(color.NRGBA); ok {
@@ -114,16 +129,19 @@ func nrgbaf32Model(c color.Color) color.Color {
 		return NRGBAF32{R: float32(nrgba.R) * conv, G: float32(nrgba.G) * conv, B: float32(nrgba.B) * conv, A: float32(nrgba.A) * conv}
 	}
 
+	if grayf, ok := c.(GrayF64); ok {
+		return NRGBAF32{R: float32(grayf.Y), G: float32(grayf.Y), B: float32(grayf.Y), A: 1.0}
+	}
+
 	r, g, b, a := c.RGBA()
 	if a == 0xffff {
 		conv := float32(1.0 / 0xffff)
 		return NRGBAF32{R: float32(r) * conv, G: float32(g) * conv, B: float32(b) * conv, A: 1.0}
 	}
-	if a == 0 {
-		return NRGBAF32{R: 0.0, G: 0.0, B: 0.0, A: 0.0}
-	}
 
 	// Since Color.RGBA returns an alpha-premultiplied color, we should have r <= a && g <= a && b <= a.
-	conv := float32(a) / (0xffff * 0xffff) // (1.0 / 0xffff) * (float64(a) / 0xffff)
-	return NRGBAF32{R: float32(r) * conv, G: float32(g) * conv, B: float32(b) * conv, A: float32(a) / 0xffff}
+	const conv = 1.0 / 0xffff
+	alpha := float64(a) * conv
+	rf, gf, bf := Unpremultiply(float64(r)*conv, float64(g)*conv, float64(b)*conv, alpha)
+	return NRGBAF32{R: float32(rf), G: float32(gf), B: float32(bf), A: float32(alpha)}
 }
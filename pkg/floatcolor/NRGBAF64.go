@@ -4,7 +4,12 @@ import "image/color"
 
 type NRGBAF64 struct {
 	R, G, B, A float64
-	Precise    bool
+	// Precise rounds channel values to the nearest integer when converting to 8 and 16 bit colors.
+	//
+	// Deprecated: Precise is the same as the RoundHalfAwayFromZero quantization, set Quantization instead.
+	Precise bool
+	// Quantization is the strategy to convert channel values to 8 and 16 bit colors.
+	Quantization Quantization
 }
 
 var (
@@ -28,29 +33,29 @@ func NewNRGBAF64PremultipliedAlpha(red, green, blue, alpha float64) NRGBAF64 {
 
 func (nrgbaf64 NRGBAF64) RGBA() (r, g, b, a uint32) {
 	conv := nrgbaf64.A * 0xffff
-	return uint32(clampF64(nrgbaf64.R*conv, 0x0000, 0xffff, nrgbaf64.Precise)),
-		uint32(clampF64(nrgbaf64.G*conv, 0x0000, 0xffff, nrgbaf64.Precise)),
-		uint32(clampF64(nrgbaf64.B*conv, 0x0000, 0xffff, nrgbaf64.Precise)),
-		uint32(clampF64(nrgbaf64.A*0xffff, 0x0000, 0xffff, nrgbaf64.Precise))
+	return uint32(clampF64(nrgbaf64.R*conv, 0x0000, 0xffff, nrgbaf64.quantization())),
+		uint32(clampF64(nrgbaf64.G*conv, 0x0000, 0xffff, nrgbaf64.quantization())),
+		uint32(clampF64(nrgbaf64.B*conv, 0x0000, 0xffff, nrgbaf64.quantization())),
+		uint32(clampF64(nrgbaf64.A*0xffff, 0x0000, 0xffff, nrgbaf64.quantization()))
 }
 
 func (nrgbaf64 NRGBAF64) AsNRGBA() color.NRGBA {
 	const conv = float64(0xff)
 	return color.NRGBA{
-		R: uint8(clampF64(nrgbaf64.R*conv, 0x00, 0xff, nrgbaf64.Precise)),
-		G: uint8(clampF64(nrgbaf64.G*conv, 0x00, 0xff, nrgbaf64.Precise)),
-		B: uint8(clampF64(nrgbaf64.B*conv, 0x00, 0xff, nrgbaf64.Precise)),
-		A: uint8(clampF64(nrgbaf64.A*conv, 0x00, 0xff, nrgbaf64.Precise)),
+		R: uint8(clampF64(nrgbaf64.R*conv, 0x00, 0xff, nrgbaf64.quantization())),
+		G: uint8(clampF64(nrgbaf64.G*conv, 0x00, 0xff, nrgbaf64.quantization())),
+		B: uint8(clampF64(nrgbaf64.B*conv, 0x00, 0xff, nrgbaf64.quantization())),
+		A: uint8(clampF64(nrgbaf64.A*conv, 0x00, 0xff, nrgbaf64.quantization())),
 	}
 }
 
 func (nrgbaf64 NRGBAF64) AsRGBA() color.RGBA {
 	conv := nrgbaf64.A * 0xff
 	return color.RGBA{
-		R: uint8(clampF64(nrgbaf64.R*conv, 0x00, 0xff, nrgbaf64.Precise)),
-		G: uint8(clampF64(nrgbaf64.G*conv, 0x00, 0xff, nrgbaf64.Precise)),
-		B: uint8(clampF64(nrgbaf64.B*conv, 0x00, 0xff, nrgbaf64.Precise)),
-		A: uint8(clampF64(nrgbaf64.A*0xff, 0x00, 0xff, nrgbaf64.Precise)),
+		R: uint8(clampF64(nrgbaf64.R*conv, 0x00, 0xff, nrgbaf64.quantization())),
+		G: uint8(clampF64(nrgbaf64.G*conv, 0x00, 0xff, nrgbaf64.quantization())),
+		B: uint8(clampF64(nrgbaf64.B*conv, 0x00, 0xff, nrgbaf64.quantization())),
+		A: uint8(clampF64(nrgbaf64.A*0xff, 0x00, 0xff, nrgbaf64.quantization())),
 	}
 }
 
@@ -58,6 +63,16 @@ func (nrgbaf64 *NRGBAF64) SetPrecise(usePreciseCalculation bool) {
 	nrgbaf64.Precise = usePreciseCalculation
 }
 
+// SetQuantization sets the strategy to convert channel values to 8 and 16 bit colors.
+func (nrgbaf64 *NRGBAF64) SetQuantization(q Quantization) {
+	nrgbaf64.Quantization = q
+}
+
+// quantization returns the quantization of the color, taking the deprecated Precise flag into account.
+func (nrgbaf64 NRGBAF64) quantization() Quantization {
+	return nrgbaf64.Quantization.WithPrecise(nrgbaf64.Precise)
+}
+
 // Mix smoothly mixes the RGB values of two color into one resulting color.
 // Parameter mix determine how much percent of color c2 is in the resulting mixed color.
 // Mix value range is [0.0, 1.0] where the resulting mix of 0.0 gives same color as c1
@@ -100,13 +115,13 @@ func nrgbaf64Model(c color.Color) color.Color {
 	}
 
 	if rgbaf, ok := c.(RGBAF64); ok {
-		conv := 1.0 / rgbaf.A
-		return NRGBAF64{R: rgbaf.R * conv, G: rgbaf.G * conv, B: rgbaf.B * conv, A: rgbaf.A}
+		r, g, b := UnpremultiplyHidden(rgbaf.R, rgbaf.G, rgbaf.B, rgbaf.A, rgbaf.Hidden)
+		return NRGBAF64{R: r, G: g, B: b, A: rgbaf.A}
 	}
 
 	if rgbaf, ok := c.(RGBAF32); ok {
-		conv := 1.0 / rgbaf.A
-		return NRGBAF64{R: float64(rgbaf.R * conv), G: float64(rgbaf.G * conv), B: float64(rgbaf.B * conv), A: float64(rgbaf.A)}
+		r, g, b := UnpremultiplyHidden(float64(rgbaf.R), float64(rgbaf.G), float64(rgbaf.B), float64(rgbaf.A), hidden64(rgbaf.Hidden))
+		return NRGBAF64{R: r, G: g, B: b, A: float64(rgbaf.A)}
 	}
 
 	if nrgba, ok := c.(color.NRGBA); ok {
@@ -115,9 +130,14 @@ func nrgbaf64Model(c color.Color) color.Color {
 	}
 
 	if rgba64, ok := c.(color.RGBA64); ok {
-		alpha := float64(rgba64.A) / 0xffff
-		conv := (1.0 / 0xffff) / alpha // Premultiply alpha channel
-		return NRGBAF64{R: float64(rgba64.R) * conv, G: float64(rgba64.G) * conv, B: float64(rgba64.B) * conv, A: alpha}
+		const conv = 1.0 / 0xffff
+		alpha := float64(rgba64.A) * conv
+		r, g, b := Unpremultiply(float64(rgba64.R)*conv, float64(rgba64.G)*conv, float64(rgba64.B)*conv, alpha)
+		return NRGBAF64{R: r, G: g, B: b, A: alpha}
+	}
+
+	if grayf, ok := c.(GrayF64); ok {
+		return NRGBAF64{R: grayf.Y, G: grayf.Y, B: grayf.Y, A: 1.0}
 	}
 
 	r, g, b, a := c.RGBA()
@@ -125,11 +145,10 @@ func nrgbaf64Model(c color.Color) color.Color {
 		conv := 1.0 / 0xffff
 		return NRGBAF64{R: float64(r) * conv, G: float64(g) * conv, B: float64(b) * conv, A: 1.0}
 	}
-	if a == 0 {
-		return NRGBAF64{R: 0.0, G: 0.0, B: 0.0, A: 0.0}
-	}
 
 	// Since Color.RGBA returns an alpha-premultiplied color, we should have r <= a && g <= a && b <= a.
-	conv := float64(a) / (0xffff * 0xffff) // (1.0 / 0xffff) * (float64(a) / 0xffff)
-	return NRGBAF64{R: float64(r) * conv, G: float64(g) * conv, B: float64(b) * conv, A: float64(a) / 0xffff}
+	const conv = 1.0 / 0xffff
+	alpha := float64(a) * conv
+	rf, gf, bf := Unpremultiply(float64(r)*conv, float64(g)*conv, float64(b)*conv, alpha)
+	return NRGBAF64{R: rf, G: gf, B: bf, A: alpha}
 }
@@ -4,7 +4,15 @@ import "image/color"
 
 type RGBAF32 struct {
 	R, G, B, A float32
-	Precise    bool
+	// Precise rounds channel values to the nearest integer when converting to 8 and 16 bit colors.
+	//
+	// Deprecated: Precise is the same as the RoundHalfAwayFromZero quantization, set Quantization instead.
+	Precise bool
+	// Quantization is the strategy to convert channel values to 8 and 16 bit colors.
+	Quantization Quantization
+	// Hidden is the side channel of the PreserveHiddenColor policy: the red, green, and blue values (with ordinary alpha)
+	// of a color with zero alpha, whose premultiplied values are zero. It is zero for all other colors and policies.
+	Hidden [3]float32
 }
 
 var (
@@ -27,29 +35,30 @@ func NewRGBAF32NonPremultipliedAlpha(red, green, blue, alpha float32) RGBAF32 {
 }
 
 func (rgbaf32 RGBAF32) RGBA() (r, g, b, a uint32) {
-	return uint32(clampF32(rgbaf32.R*0xffff, 0.0, 0xffff, rgbaf32.Precise)),
-		uint32(clampF32(rgbaf32.G*0xffff, 0.0, 0xffff, rgbaf32.Precise)),
-		uint32(clampF32(rgbaf32.B*0xffff, 0.0, 0xffff, rgbaf32.Precise)),
-		uint32(clampF32(rgbaf32.A*0xffff, 0.0, 0xffff, rgbaf32.Precise))
+	return uint32(clampF32(rgbaf32.R*0xffff, 0.0, 0xffff, rgbaf32.quantization())),
+		uint32(clampF32(rgbaf32.G*0xffff, 0.0, 0xffff, rgbaf32.quantization())),
+		uint32(clampF32(rgbaf32.B*0xffff, 0.0, 0xffff, rgbaf32.quantization())),
+		uint32(clampF32(rgbaf32.A*0xffff, 0.0, 0xffff, rgbaf32.quantization()))
 }
 
 func (rgbaf32 RGBAF32) AsNRGBA() color.NRGBA {
-	conv := 0xff / rgbaf32.A
+	const conv = float32(0xff)
+	r, g, b := UnpremultiplyHidden(float64(rgbaf32.R), float64(rgbaf32.G), float64(rgbaf32.B), float64(rgbaf32.A), hidden64(rgbaf32.Hidden))
 	return color.NRGBA{
-		R: uint8(clampF32(rgbaf32.R*conv, 0x00, 0xff, rgbaf32.Precise)),
-		G: uint8(clampF32(rgbaf32.G*conv, 0x00, 0xff, rgbaf32.Precise)),
-		B: uint8(clampF32(rgbaf32.B*conv, 0x00, 0xff, rgbaf32.Precise)),
-		A: uint8(clampF32(rgbaf32.A*0xff, 0x00, 0xff, rgbaf32.Precise)),
+		R: uint8(clampF32(float32(r)*conv, 0x00, 0xff, rgbaf32.quantization())),
+		G: uint8(clampF32(float32(g)*conv, 0x00, 0xff, rgbaf32.quantization())),
+		B: uint8(clampF32(float32(b)*conv, 0x00, 0xff, rgbaf32.quantization())),
+		A: uint8(clampF32(rgbaf32.A*0xff, 0x00, 0xff, rgbaf32.quantization())),
 	}
 }
 
 func (rgbaf32 RGBAF32) AsRGBA() color.RGBA {
 	const conv = float32(0xff)
 	return color.RGBA{
-		R: uint8(clampF32(rgbaf32.R*conv, 0x00, 0xff, rgbaf32.Precise)),
-		G: uint8(clampF32(rgbaf32.G*conv, 0x00, 0xff, rgbaf32.Precise)),
-		B: uint8(clampF32(rgbaf32.B*conv, 0x00, 0xff, rgbaf32.Precise)),
-		A: uint8(clampF32(rgbaf32.A*conv, 0x00, 0xff, rgbaf32.Precise)),
+		R: uint8(clampF32(rgbaf32.R*conv, 0x00, 0xff, rgbaf32.quantization())),
+		G: uint8(clampF32(rgbaf32.G*conv, 0x00, 0xff, rgbaf32.quantization())),
+		B: uint8(clampF32(rgbaf32.B*conv, 0x00, 0xff, rgbaf32.quantization())),
+		A: uint8(clampF32(rgbaf32.A*conv, 0x00, 0xff, rgbaf32.quantization())),
 	}
 }
 
@@ -57,6 +66,16 @@ func (rgbaf32 *RGBAF32) SetPrecise(usePreciseCalculation bool) {
 	rgbaf32.Precise = usePreciseCalculation
 }
 
+// SetQuantization sets the strategy to convert channel values to 8 and 16 bit colors.
+func (rgbaf32 *RGBAF32) SetQuantization(q Quantization) {
+	rgbaf32.Quantization = q
+}
+
+// quantization returns the quantization of the color, taking the deprecated Precise flag into account.
+func (rgbaf32 RGBAF32) quantization() Quantization {
+	return rgbaf32.Quantization.WithPrecise(rgbaf32.Precise)
+}
+
 // Mix smoothly mixes the RGB values of two color into one resulting color.
 // Parameter mix determine how much percent of color c2 is in the resulting mixed color.
 // Mix value range is [0.0, 1.0] where the resulting mix of 0.0 gives same color as c1
@@ -68,10 +87,10 @@ func (rgbaf32 *RGBAF32) Mix(c1 color.Color, mixAmount float64) color.Color {
 }
 
 func (rgbaf32 *RGBAF32) SetAlpha(alpha float32) {
-	nrgbaf := nrgbaf32Model(rgbaf32).(NRGBAF32)
+	nrgbaf := nrgbaf32Model(*rgbaf32).(NRGBAF32)
 	nrgbaf.A = alpha
 	rgbaf := rgbaf32Model(nrgbaf).(RGBAF32)
-	rgbaf32.R, rgbaf32.G, rgbaf32.B, rgbaf32.A = rgbaf.R, rgbaf.G, rgbaf.B, rgbaf.A
+	rgbaf32.R, rgbaf32.G, rgbaf32.B, rgbaf32.A, rgbaf32.Hidden = rgbaf.R, rgbaf.G, rgbaf.B, rgbaf.A, rgbaf.Hidden
 }
 
 func (rgbaf32 *RGBAF32) SetRGB(red float32, green float32, blue float32) {
@@ -82,14 +101,17 @@ func (rgbaf32 *RGBAF32) SetRGB(red float32, green float32, blue float32) {
 
 func (rgbaf32 *RGBAF32) SetR(red float32) {
 	rgbaf32.R = red * rgbaf32.A
+	rgbaf32.Hidden[0] = float32(hiddenColor(float64(red), 0, 0, float64(rgbaf32.A))[0])
 }
 
 func (rgbaf32 *RGBAF32) SetG(green float32) {
 	rgbaf32.G = green * rgbaf32.A
+	rgbaf32.Hidden[1] = float32(hiddenColor(float64(green), 0, 0, float64(rgbaf32.A))[0])
 }
 
 func (rgbaf32 *RGBAF32) SetB(blue float32) {
 	rgbaf32.B = blue * rgbaf32.A
+	rgbaf32.Hidden[2] = float32(hiddenColor(float64(blue), 0, 0, float64(rgbaf32.A))[0])
 }
 
 func rgbaf32Model(c color.Color) color.Color {
@@ -98,17 +120,21 @@ func rgbaf32Model(c color.Color) color.Color {
 	}
 
 	if rgbaf, ok := c.(RGBAF64); ok {
-		return RGBAF32{R: float32(rgbaf.R), G: float32(rgbaf.G), B: float32(rgbaf.B), A: float32(rgbaf.A)}
+		return RGBAF32{R: float32(rgbaf.R), G: float32(rgbaf.G), B: float32(rgbaf.B), A: float32(rgbaf.A), Hidden: hidden32(rgbaf.Hidden)}
 	}
 
 	if nrgbaf, ok := c.(NRGBAF64); ok {
-		conv := nrgbaf.A
-		return RGBAF32{R: float32(nrgbaf.R * conv), G: float32(nrgbaf.G * conv), B: float32(nrgbaf.B * conv), A: float32(nrgbaf.A)}
+		r, g, b, hidden := PremultiplyHidden(nrgbaf.R, nrgbaf.G, nrgbaf.B, nrgbaf.A)
+		return RGBAF32{R: float32(r), G: float32(g), B: float32(b), A: float32(nrgbaf.A), Hidden: hidden32(hidden)}
 	}
 
 	if nrgbaf, ok := c.(NRGBAF32); ok {
-		conv := nrgbaf.A
-		return RGBAF32{R: nrgbaf.R * conv, G: nrgbaf.G * conv, B: nrgbaf.B * conv, A: nrgbaf.A}
+		r, g, b, hidden := PremultiplyHidden(float64(nrgbaf.R), float64(nrgbaf.G), float64(nrgbaf.B), float64(nrgbaf.A))
+		return RGBAF32{R: float32(r), G: float32(g), B: float32(b), A: nrgbaf.A, Hidden: hidden32(hidden)}
+	}
+
+	if grayf, ok := c.(GrayF64); ok {
+		return RGBAF32{R: float32(grayf.Y), G: float32(grayf.Y), B: float32(grayf.Y), A: 1.0}
 	}
 
 	r, g, b, a := c.RGBA()
@@ -4,7 +4,15 @@ import "image/color"
 
 type RGBAF64 struct {
 	R, G, B, A float64
-	Precise    bool
+	// Precise rounds channel values to the nearest integer when converting to 8 and 16 bit colors.
+	//
+	// Deprecated: Precise is the same as the RoundHalfAwayFromZero quantization, set Quantization instead.
+	Precise bool
+	// Quantization is the strategy to convert channel values to 8 and 16 bit colors.
+	Quantization Quantization
+	// Hidden is the side channel of the PreserveHiddenColor policy: the red, green, and blue values (with ordinary alpha)
+	// of a color with zero alpha, whose premultiplied values are zero. It is zero for all other colors and policies.
+	Hidden [3]float64
 }
 
 var (
@@ -27,29 +35,30 @@ func NewRGBAF64NonPremultipliedAlpha(red, green, blue, alpha float64) RGBAF64 {
 }
 
 func (rgbaf64 RGBAF64) RGBA() (r, g, b, a uint32) {
-	return uint32(clampF64(rgbaf64.R*0xffff, 0.0, 0xffff, rgbaf64.Precise)),
-		uint32(clampF64(rgbaf64.G*0xffff, 0.0, 0xffff, rgbaf64.Precise)),
-		uint32(clampF64(rgbaf64.B*0xffff, 0.0, 0xffff, rgbaf64.Precise)),
-		uint32(clampF64(rgbaf64.A*0xffff, 0.0, 0xffff, rgbaf64.Precise))
+	return uint32(clampF64(rgbaf64.R*0xffff, 0.0, 0xffff, rgbaf64.quantization())),
+		uint32(clampF64(rgbaf64.G*0xffff, 0.0, 0xffff, rgbaf64.quantization())),
+		uint32(clampF64(rgbaf64.B*0xffff, 0.0, 0xffff, rgbaf64.quantization())),
+		uint32(clampF64(rgbaf64.A*0xffff, 0.0, 0xffff, rgbaf64.quantization()))
 }
 
 func (rgbaf64 RGBAF64) AsNRGBA() color.NRGBA {
-	conv := 0xff / rgbaf64.A
+	const conv = float64(0xff)
+	r, g, b := UnpremultiplyHidden(rgbaf64.R, rgbaf64.G, rgbaf64.B, rgbaf64.A, rgbaf64.Hidden)
 	return color.NRGBA{
-		R: uint8(clampF64(rgbaf64.R*conv, 0x00, 0xff, rgbaf64.Precise)),
-		G: uint8(clampF64(rgbaf64.G*conv, 0x00, 0xff, rgbaf64.Precise)),
-		B: uint8(clampF64(rgbaf64.B*conv, 0x00, 0xff, rgbaf64.Precise)),
-		A: uint8(clampF64(rgbaf64.A*0xff, 0x00, 0xff, rgbaf64.Precise)),
+		R: uint8(clampF64(r*conv, 0x00, 0xff, rgbaf64.quantization())),
+		G: uint8(clampF64(g*conv, 0x00, 0xff, rgbaf64.quantization())),
+		B: uint8(clampF64(b*conv, 0x00, 0xff, rgbaf64.quantization())),
+		A: uint8(clampF64(rgbaf64.A*0xff, 0x00, 0xff, rgbaf64.quantization())),
 	}
 }
 
 func (rgbaf64 RGBAF64) AsRGBA() color.RGBA {
 	const conv = float64(0xff)
 	return color.RGBA{
-		R: uint8(clampF64(rgbaf64.R*conv, 0x00, 0xff, rgbaf64.Precise)),
-		G: uint8(clampF64(rgbaf64.G*conv, 0x00, 0xff, rgbaf64.Precise)),
-		B: uint8(clampF64(rgbaf64.B*conv, 0x00, 0xff, rgbaf64.Precise)),
-		A: uint8(clampF64(rgbaf64.A*conv, 0x00, 0xff, rgbaf64.Precise)),
+		R: uint8(clampF64(rgbaf64.R*conv, 0x00, 0xff, rgbaf64.quantization())),
+		G: uint8(clampF64(rgbaf64.G*conv, 0x00, 0xff, rgbaf64.quantization())),
+		B: uint8(clampF64(rgbaf64.B*conv, 0x00, 0xff, rgbaf64.quantization())),
+		A: uint8(clampF64(rgbaf64.A*conv, 0x00, 0xff, rgbaf64.quantization())),
 	}
 }
 
@@ -57,6 +66,16 @@ func (rgbaf64 *RGBAF64) SetPrecise(usePreciseCalculation bool) {
 	rgbaf64.Precise = usePreciseCalculation
 }
 
+// SetQuantization sets the strategy to convert channel values to 8 and 16 bit colors.
+func (rgbaf64 *RGBAF64) SetQuantization(q Quantization) {
+	rgbaf64.Quantization = q
+}
+
+// quantization returns the quantization of the color, taking the deprecated Precise flag into account.
+func (rgbaf64 RGBAF64) quantization() Quantization {
+	return rgbaf64.Quantization.WithPrecise(rgbaf64.Precise)
+}
+
 // Mix smoothly mixes the RGB values of two color into one resulting color.
 // Parameter mix determine how much percent of color c2 is in the resulting mixed color.
 // Mix value range is [0.0, 1.0] where the resulting mix of 0.0 gives same color as c1
@@ -68,10 +87,10 @@ func (rgbaf64 *RGBAF64) Mix(c1 color.Color, mixAmount float64) color.Color {
 }
 
 func (rgbaf64 *RGBAF64) SetAlpha(alpha float64) {
-	nrgbaf := nrgbaf64Model(rgbaf64).(NRGBAF64)
+	nrgbaf := nrgbaf64Model(*rgbaf64).(NRGBAF64)
 	nrgbaf.A = alpha
 	rgbaf := rgbaf64Model(nrgbaf).(RGBAF64)
-	rgbaf64.R, rgbaf64.G, rgbaf64.B, rgbaf64.A = rgbaf.R, rgbaf.G, rgbaf.B, rgbaf.A
+	rgbaf64.R, rgbaf64.G, rgbaf64.B, rgbaf64.A, rgbaf64.Hidden = rgbaf.R, rgbaf.G, rgbaf.B, rgbaf.A, rgbaf.Hidden
 }
 
 func (rgbaf64 *RGBAF64) SetRGB(red float64, green float64, blue float64) {
@@ -82,14 +101,17 @@ func (rgbaf64 *RGBAF64) SetRGB(red float64, green float64, blue float64) {
 
 func (rgbaf64 *RGBAF64) SetR(red float64) {
 	rgbaf64.R = red * rgbaf64.A
+	rgbaf64.Hidden[0] = hiddenColor(red, 0, 0, rgbaf64.A)[0]
 }
 
 func (rgbaf64 *RGBAF64) SetG(green float64) {
 	rgbaf64.G = green * rgbaf64.A
+	rgbaf64.Hidden[1] = hiddenColor(green, 0, 0, rgbaf64.A)[0]
 }
 
 func (rgbaf64 *RGBAF64) SetB(blue float64) {
 	rgbaf64.B = blue * rgbaf64.A
+	rgbaf64.Hidden[2] = hiddenColor(blue, 0, 0, rgbaf64.A)[0]
 }
 
 func rgbaf64Model(c color.Color) color.Color {
@@ -98,17 +120,21 @@ func rgbaf64Model(c color.Color) color.Color {
 	}
 
 	if rgbaf, ok := c.(RGBAF32); ok {
-		return RGBAF64{R: float64(rgbaf.R), G: float64(rgbaf.G), B: float64(rgbaf.B), A: float64(rgbaf.A)}
+		return RGBAF64{R: float64(rgbaf.R), G: float64(rgbaf.G), B: float64(rgbaf.B), A: float64(rgbaf.A), Hidden: hidden64(rgbaf.Hidden)}
 	}
 
 	if nrgbaf, ok := c.(NRGBAF64); ok {
-		conv := nrgbaf.A
-		return RGBAF64{R: nrgbaf.R * conv, G: nrgbaf.G * conv, B: nrgbaf.B * conv, A: nrgbaf.A}
+		r, g, b, hidden := PremultiplyHidden(nrgbaf.R, nrgbaf.G, nrgbaf.B, nrgbaf.A)
+		return RGBAF64{R: r, G: g, B: b, A: nrgbaf.A, Hidden: hidden}
 	}
 
 	if nrgbaf, ok := c.(NRGBAF32); ok {
-		conv := nrgbaf.A
-		return RGBAF64{R: float64(nrgbaf.R * conv), G: float64(nrgbaf.G * conv), B: float64(nrgbaf.B * conv), A: float64(nrgbaf.A)}
+		r, g, b, hidden := PremultiplyHidden(float64(nrgbaf.R), float64(nrgbaf.G), float64(nrgbaf.B), float64(nrgbaf.A))
+		return RGBAF64{R: r, G: g, B: b, A: float64(nrgbaf.A), Hidden: hidden}
+	}
+
+	if grayf, ok := c.(GrayF64); ok {
+		return RGBAF64{R: grayf.Y, G: grayf.Y, B: grayf.Y, A: 1.0}
 	}
 
 	r, g, b, a := c.RGBA()
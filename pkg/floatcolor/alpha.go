@@ -0,0 +1,125 @@
+package floatcolor
+
+import "sync/atomic"
+
+// ZeroAlphaPolicy defines how premultiplied colors with zero alpha (or with a tiny alpha) are converted to colors
+// with ordinary alpha, where the color values have to be divided by alpha. It is applied by all color models and
+// conversions of this package and by the float image conversions, see SetZeroAlphaPolicy.
+type ZeroAlphaPolicy int32
+
+const (
+	// ZeroColor gives zero color values for colors whose alpha is zero (or negative), so a fully transparent color
+	// becomes transparent black. Any positive alpha is divided by, however small. This is the default policy.
+	ZeroColor ZeroAlphaPolicy = iota
+	// PreserveHiddenColor keeps the color of fully transparent colors in a side channel: premultiplied colors and images
+	// with zero (or negative) alpha hold zero color values as with ZeroColor, but keep the color they had with ordinary alpha
+	// in their Hidden field (or plane), so it survives a round trip through premultiplied colors and images. As the
+	// premultiplied values never hold it, filtering, compositing and the RGBA method see transparent black.
+	PreserveHiddenColor
+	// EpsilonClamp clamps positive alpha values to the alpha epsilon before dividing by them, so tiny alpha values do not
+	// amplify the rounding errors of the color values beyond any precision. Valid premultiplied colors (color values not
+	// above alpha) stay within [0.0, 1.0]. Colors with zero (or negative) alpha become transparent black.
+	EpsilonClamp
+)
+
+// DefaultAlphaEpsilon is the alpha epsilon of the EpsilonClamp policy if none is given.
+// Dividing premultiplied color values by an alpha below it amplifies their rounding errors (of float32 values in particular)
+// beyond any useful precision.
+const DefaultAlphaEpsilon = 1e-6
+
+type zeroAlpha struct {
+	policy  ZeroAlphaPolicy
+	epsilon float64
+}
+
+var currentZeroAlpha atomic.Pointer[zeroAlpha]
+
+func init() {
+	currentZeroAlpha.Store(&zeroAlpha{policy: ZeroColor, epsilon: DefaultAlphaEpsilon})
+}
+
+// SetZeroAlphaPolicy sets the zero alpha policy and the alpha epsilon of the EpsilonClamp policy
+// (DefaultAlphaEpsilon if epsilon <= 0) used by all conversions from then on. It is safe for concurrent use,
+// but conversions running at the same time may use either policy. Use UnpremultiplyWithPolicy (or the WithPolicy
+// conversions of package floatimage) to convert with a policy without changing it for the whole program.
+//
+// With the PreserveHiddenColor policy, storing transparent colors into images that have no hidden color plane yet
+// allocates it, which must not happen concurrently for the same image.
+func SetZeroAlphaPolicy(policy ZeroAlphaPolicy, epsilon float64) {
+	if epsilon <= 0 {
+		epsilon = DefaultAlphaEpsilon
+	}
+	currentZeroAlpha.Store(&zeroAlpha{policy: policy, epsilon: epsilon})
+}
+
+// CurrentZeroAlphaPolicy returns the zero alpha policy and the alpha epsilon set by SetZeroAlphaPolicy.
+func CurrentZeroAlphaPolicy() (policy ZeroAlphaPolicy, epsilon float64) {
+	z := currentZeroAlpha.Load()
+	return z.policy, z.epsilon
+}
+
+// Unpremultiply returns the ordinary (non premultiplied) color values of the premultiplied values r, g, b with alpha a,
+// following the zero alpha policy (see SetZeroAlphaPolicy). Values without a side channel have no hidden color,
+// so colors with zero alpha become transparent black with every policy, see UnpremultiplyHidden.
+func Unpremultiply(r, g, b, a float64) (float64, float64, float64) {
+	z := currentZeroAlpha.Load()
+	return UnpremultiplyWithPolicy(r, g, b, a, [3]float64{}, z.policy, z.epsilon)
+}
+
+// UnpremultiplyHidden is like Unpremultiply, but returns the hidden color (the side channel of the values) for colors with
+// zero alpha if the policy is PreserveHiddenColor.
+func UnpremultiplyHidden(r, g, b, a float64, hidden [3]float64) (float64, float64, float64) {
+	z := currentZeroAlpha.Load()
+	return UnpremultiplyWithPolicy(r, g, b, a, hidden, z.policy, z.epsilon)
+}
+
+// UnpremultiplyWithPolicy is like UnpremultiplyHidden, but follows the given zero alpha policy and alpha epsilon
+// (DefaultAlphaEpsilon if epsilon <= 0) instead of the ones set by SetZeroAlphaPolicy.
+func UnpremultiplyWithPolicy(r, g, b, a float64, hidden [3]float64, policy ZeroAlphaPolicy, epsilon float64) (float64, float64, float64) {
+	if a <= 0 {
+		if policy == PreserveHiddenColor {
+			return hidden[0], hidden[1], hidden[2]
+		}
+		return 0, 0, 0
+	}
+	if policy == EpsilonClamp {
+		if epsilon <= 0 {
+			epsilon = DefaultAlphaEpsilon
+		}
+		if a < epsilon {
+			a = epsilon
+		}
+	}
+
+	conv := 1.0 / a
+	return r * conv, g * conv, b * conv
+}
+
+// Premultiply returns the color values r, g, b with ordinary alpha multiplied with alpha a.
+func Premultiply(r, g, b, a float64) (float64, float64, float64) {
+	return r * a, g * a, b * a
+}
+
+// PremultiplyHidden is like Premultiply, but also returns the hidden color to keep in the side channel of the premultiplied
+// values: the color values r, g, b for zero (or negative) alpha with the PreserveHiddenColor policy, and zero otherwise.
+func PremultiplyHidden(r, g, b, a float64) (float64, float64, float64, [3]float64) {
+	return r * a, g * a, b * a, hiddenColor(r, g, b, a)
+}
+
+// hiddenColor returns the hidden color of the color values r, g, b with ordinary alpha a, see PremultiplyHidden.
+func hiddenColor(r, g, b, a float64) [3]float64 {
+	if a <= 0 && currentZeroAlpha.Load().policy == PreserveHiddenColor {
+		return [3]float64{r, g, b}
+	}
+	return [3]float64{}
+}
+
+// hidden64 returns the hidden color of an RGBAF32 color with float64 values.
+func hidden64(h [3]float32) [3]float64 {
+	return [3]float64{float64(h[0]), float64(h[1]), float64(h[2])}
+}
+
+// hidden32 returns the hidden color of an RGBAF64 color with float32 values.
+func hidden32(h [3]float64) [3]float32 {
+	return [3]float32{float32(h[0]), float32(h[1]), float32(h[2])}
+}
@@ -0,0 +1,233 @@
+package floatcolor
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+var policies = []struct {
+	name   string
+	policy ZeroAlphaPolicy
+}{
+	{"ZeroColor", ZeroColor},
+	{"PreserveHiddenColor", PreserveHiddenColor},
+	{"EpsilonClamp", EpsilonClamp},
+}
+
+// withPolicy runs f with the zero alpha policy set, restoring the previous policy afterwards.
+func withPolicy(t *testing.T, policy ZeroAlphaPolicy, epsilon float64, f func()) {
+	t.Helper()
+	previous, previousEpsilon := CurrentZeroAlphaPolicy()
+	defer SetZeroAlphaPolicy(previous, previousEpsilon)
+	SetZeroAlphaPolicy(policy, epsilon)
+	f()
+}
+
+func finite(values ...float64) bool {
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// alphas are zero, tiny (below and above the default epsilon, and below the float32 resolution near 1.0),
+// small and ordinary alpha values.
+var alphas = []float64{0, 1e-30, 1e-9, 1e-7, 1e-6, 2e-6, 1.0 / 0xffff, 0.5, 1.0}
+
+// premultipliedColors returns colors with the premultiplied color values r, g, b, alpha a and the hidden color
+// of all color types whose values are premultiplied.
+func premultipliedColors(r, g, b, a float64, hidden [3]float64) []color.Color {
+	return []color.Color{
+		RGBAF64{R: r, G: g, B: b, A: a, Hidden: hidden},
+		RGBAF32{R: float32(r), G: float32(g), B: float32(b), A: float32(a), Hidden: hidden32(hidden)},
+	}
+}
+
+func TestUnpremultiplyZeroAlpha(t *testing.T) {
+	for _, p := range policies {
+		withPolicy(t, p.policy, 0, func() {
+			for _, a := range alphas {
+				// Valid premultiplied values (not above alpha), and the hidden color of fully transparent colors
+				r, g, b := 0.25*a, 0.5*a, a
+				var hidden [3]float64
+				if a == 0 {
+					hidden = [3]float64{0.25, 0.5, 1.0}
+				}
+
+				for _, c := range premultipliedColors(r, g, b, a, hidden) {
+					for _, model := range []color.Model{NRGBAF64Model, NRGBAF32Model} {
+						n := NRGBAF64Model.Convert(model.Convert(c)).(NRGBAF64)
+						if !finite(n.R, n.G, n.B, n.A) {
+							t.Fatalf("%s: expected finite values for %#v but got %+v", p.name, c, n)
+						}
+
+						var expected NRGBAF64
+						switch {
+						case a == 0 && p.policy == PreserveHiddenColor:
+							expected = NRGBAF64{R: 0.25, G: 0.5, B: 1.0}
+						case a == 0:
+							expected = NRGBAF64{}
+						case p.policy == EpsilonClamp && a < DefaultAlphaEpsilon:
+							scale := a / DefaultAlphaEpsilon
+							expected = NRGBAF64{R: 0.25 * scale, G: 0.5 * scale, B: scale, A: a}
+						default:
+							expected = NRGBAF64{R: 0.25, G: 0.5, B: 1.0, A: a}
+						}
+						if math.Abs(n.R-expected.R) > 1e-6 || math.Abs(n.G-expected.G) > 1e-6 || math.Abs(n.B-expected.B) > 1e-6 ||
+							math.Abs(n.A-expected.A) > 1e-6*a {
+							t.Errorf("%s: expected %+v for %#v but got %+v", p.name, expected, c, n)
+						}
+					}
+
+					nrgba := c.(ConvertableColor).AsNRGBA()
+					if a == 0 && p.policy != PreserveHiddenColor && nrgba != (color.NRGBA{}) {
+						t.Errorf("%s: expected transparent black for %#v but got %+v", p.name, c, nrgba)
+					}
+					if a == 0 && p.policy == PreserveHiddenColor && nrgba != (color.NRGBA{R: 0x3f, G: 0x7f, B: 0xff}) {
+						t.Errorf("%s: expected hidden color for %#v but got %+v", p.name, c, nrgba)
+					}
+				}
+			}
+
+			// Invalid premultiplied values of a fully transparent color without a hidden color
+			for _, c := range premultipliedColors(0.25, 0.5, 1.0, 0, [3]float64{}) {
+				if n := NRGBAF64Model.Convert(c).(NRGBAF64); n != (NRGBAF64{}) {
+					t.Errorf("%s: expected transparent black for %#v but got %+v", p.name, c, n)
+				}
+			}
+		})
+	}
+}
+
+func TestPremultipliedZeroAlpha(t *testing.T) {
+	for _, p := range policies {
+		withPolicy(t, p.policy, 0, func() {
+			hidden := NRGBAF64{R: 0.25, G: 0.5, B: 1.0}
+			for _, model := range []color.Model{RGBAF64Model, RGBAF32Model} {
+				c := model.Convert(hidden)
+				back := NRGBAF64Model.Convert(c).(NRGBAF64)
+				if p.policy == PreserveHiddenColor && back != hidden {
+					t.Errorf("%s: expected hidden color to survive a round trip but got %+v", p.name, back)
+				}
+				if p.policy != PreserveHiddenColor && back != (NRGBAF64{}) {
+					t.Errorf("%s: expected transparent black but got %+v", p.name, back)
+				}
+				if back := NRGBAF64Model.Convert(RGBAF32Model.Convert(RGBAF64Model.Convert(c))).(NRGBAF64); back != NRGBAF64Model.Convert(c) {
+					t.Errorf("%s: expected hidden color to survive conversions between premultiplied colors but got %+v", p.name, back)
+				}
+
+				// The premultiplied values of colors with zero alpha are zero, hidden colors are transparent black
+				if r, g, b, a := c.RGBA(); r != 0 || g != 0 || b != 0 || a != 0 {
+					t.Errorf("%s: expected transparent RGBA but got %d, %d, %d, %d", p.name, r, g, b, a)
+				}
+				if rgba := c.(ConvertableColor).AsRGBA(); rgba != (color.RGBA{}) {
+					t.Errorf("%s: expected transparent RGBA but got %+v", p.name, rgba)
+				}
+				if gray := GrayF64Model.Convert(c).(GrayF64); gray.Y != 0 {
+					t.Errorf("%s: expected black but got %+v", p.name, gray)
+				}
+			}
+
+			rgbaf := RGBAF64{A: 0}
+			rgbaf.SetRGB(0.25, 0.5, 1.0)
+			if rgbaf.R != 0 || rgbaf.B != 0 {
+				t.Errorf("%s: expected SetRGB to give zero color values but got %+v", p.name, rgbaf)
+			}
+			if p.policy == PreserveHiddenColor && rgbaf.Hidden != [3]float64{0.25, 0.5, 1.0} {
+				t.Errorf("%s: expected SetRGB to keep the hidden color but got %+v", p.name, rgbaf)
+			}
+			if p.policy != PreserveHiddenColor && rgbaf.Hidden != [3]float64{} {
+				t.Errorf("%s: expected SetRGB to keep no hidden color but got %+v", p.name, rgbaf)
+			}
+
+			// Making a hidden color visible again
+			rgbaf.SetAlpha(0.5)
+			if p.policy == PreserveHiddenColor && (rgbaf != RGBAF64{R: 0.125, G: 0.25, B: 0.5, A: 0.5}) {
+				t.Errorf("%s: expected the hidden color with new alpha but got %+v", p.name, rgbaf)
+			}
+			if p.policy != PreserveHiddenColor && (rgbaf != RGBAF64{A: 0.5}) {
+				t.Errorf("%s: expected black with new alpha but got %+v", p.name, rgbaf)
+			}
+
+			rgbaf32 := RGBAF32{R: 0.1, G: 0.1, B: 0.1, A: 0}
+			rgbaf32.SetAlpha(0.5)
+			if !finite(float64(rgbaf32.R), float64(rgbaf32.G), float64(rgbaf32.B), float64(rgbaf32.A)) || rgbaf32.A != 0.5 {
+				t.Errorf("%s: expected finite color with new alpha but got %+v", p.name, rgbaf32)
+			}
+		})
+	}
+}
+
+func TestUnpremultiplyStandardColors(t *testing.T) {
+	for _, p := range policies {
+		withPolicy(t, p.policy, 0, func() {
+			for _, c := range []color.Color{
+				color.RGBA{R: 0x80, G: 0x40, B: 0x00, A: 0x80},
+				color.RGBA64{R: 0x8000, G: 0x4000, B: 0x0000, A: 0x8000},
+			} {
+				n := NRGBAF64Model.Convert(c).(NRGBAF64)
+				if math.Abs(n.R-1.0) > 1e-9 || math.Abs(n.G-0.5) > 1e-9 || n.B != 0 || math.Abs(n.A-0x8000/float64(0xffff)) > 1e-2 {
+					t.Errorf("%s: expected half transparent orange for %#v but got %+v", p.name, c, n)
+				}
+				n32 := NRGBAF32Model.Convert(c).(NRGBAF32)
+				if math.Abs(float64(n32.R)-1.0) > 1e-6 || math.Abs(float64(n32.G)-0.5) > 1e-6 {
+					t.Errorf("%s: expected half transparent orange for %#v but got %+v", p.name, c, n32)
+				}
+			}
+
+			for _, c := range []color.Color{color.RGBA{}, color.RGBA64{}, color.Transparent} {
+				for _, model := range []color.Model{NRGBAF64Model, NRGBAF32Model} {
+					if n := NRGBAF64Model.Convert(model.Convert(c)).(NRGBAF64); n != (NRGBAF64{}) {
+						t.Errorf("%s: expected transparent black for %#v but got %+v", p.name, c, n)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestZeroAlphaPolicy(t *testing.T) {
+	withPolicy(t, EpsilonClamp, 0.01, func() {
+		if policy, epsilon := CurrentZeroAlphaPolicy(); policy != EpsilonClamp || epsilon != 0.01 {
+			t.Errorf("expected EpsilonClamp with epsilon 0.01 but got %v and %v", policy, epsilon)
+		}
+		if r, _, _ := Unpremultiply(0.001, 0, 0, 0.001); math.Abs(r-0.1) > 1e-12 {
+			t.Errorf("expected alpha to be clamped to epsilon but got %v", r)
+		}
+	})
+
+	withPolicy(t, EpsilonClamp, -1, func() {
+		if _, epsilon := CurrentZeroAlphaPolicy(); epsilon != DefaultAlphaEpsilon {
+			t.Errorf("expected default epsilon but got %v", epsilon)
+		}
+	})
+
+	// The policy given per call is used instead of the current one
+	withPolicy(t, ZeroColor, 0, func() {
+		if r, _, _ := UnpremultiplyWithPolicy(1e-9, 0, 0, 1e-9, [3]float64{}, EpsilonClamp, 0); math.Abs(r-1e-3) > 1e-12 {
+			t.Errorf("expected alpha to be clamped to the default epsilon but got %v", r)
+		}
+		if r, _, _ := UnpremultiplyWithPolicy(0.001, 0, 0, 0.001, [3]float64{}, EpsilonClamp, 0.01); math.Abs(r-0.1) > 1e-12 {
+			t.Errorf("expected alpha to be clamped to epsilon but got %v", r)
+		}
+		if r, _, _ := UnpremultiplyWithPolicy(0.001, 0, 0, 0.001, [3]float64{}, ZeroColor, 0.01); math.Abs(r-1.0) > 1e-12 {
+			t.Errorf("expected alpha not to be clamped but got %v", r)
+		}
+		if r, g, b := UnpremultiplyWithPolicy(0.25, 0.5, 1.0, 0, [3]float64{1, 1, 1}, EpsilonClamp, 0.01); r != 0 || g != 0 || b != 0 {
+			t.Errorf("expected zero color but got %v, %v, %v", r, g, b)
+		}
+		if r, g, b := UnpremultiplyWithPolicy(0, 0, 0, 0, [3]float64{0.25, 0.5, 1.0}, PreserveHiddenColor, 0); r != 0.25 || g != 0.5 || b != 1.0 {
+			t.Errorf("expected hidden color but got %v, %v, %v", r, g, b)
+		}
+		if r, _, _ := UnpremultiplyHidden(0, 0, 0, 0, [3]float64{0.25, 0.5, 1.0}); r != 0 {
+			t.Errorf("expected the current policy to ignore the hidden color but got %v", r)
+		}
+	})
+
+	if policy, _ := CurrentZeroAlphaPolicy(); policy != ZeroColor {
+		t.Errorf("expected policy to be restored to ZeroColor but got %v", policy)
+	}
+}
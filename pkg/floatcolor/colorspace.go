@@ -0,0 +1,70 @@
+package floatcolor
+
+import "math"
+
+// SRGBToLinear decodes a gamma encoded sRGB channel value into a linear channel value.
+// Negative values are mirrored so that the transfer function is defined for all values.
+func SRGBToLinear(v float64) float64 {
+	if v < 0 {
+		return -SRGBToLinear(-v)
+	}
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// LinearToSRGB encodes a linear channel value into a gamma encoded sRGB channel value.
+// Negative values are mirrored so that the transfer function is defined for all values.
+func LinearToSRGB(v float64) float64 {
+	if v < 0 {
+		return -LinearToSRGB(-v)
+	}
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1.0/2.4) - 0.055
+}
+
+// LinearRGBToOKLab converts linear sRGB red, green, and blue values to the perceptual OKLab color space.
+func LinearRGBToOKLab(r, g, b float64) (L, A, B float64) {
+	l := 0.4122214708*r + 0.5363325363*g + 0.0514459929*b
+	m := 0.2119034982*r + 0.6806995451*g + 0.1073969566*b
+	s := 0.0883024619*r + 0.2817188376*g + 0.6299787005*b
+
+	l, m, s = math.Cbrt(l), math.Cbrt(m), math.Cbrt(s)
+
+	L = 0.2104542553*l + 0.7936177850*m - 0.0040720468*s
+	A = 1.9779984951*l - 2.4285922050*m + 0.4505937099*s
+	B = 0.0259040371*l + 0.7827717662*m - 0.8086757660*s
+	return L, A, B
+}
+
+// OKLabToLinearRGB converts OKLab values to linear sRGB red, green, and blue values.
+func OKLabToLinearRGB(L, A, B float64) (r, g, b float64) {
+	l := L + 0.3963377774*A + 0.2158037573*B
+	m := L - 0.1055613458*A - 0.0638541728*B
+	s := L - 0.0894841775*A - 1.2914855480*B
+
+	l, m, s = l*l*l, m*m*m, s*s*s
+
+	r = 4.0767416621*l - 3.3077115913*m + 0.2309699292*s
+	g = -1.2684380046*l + 2.6097574011*m - 0.3413193965*s
+	b = -0.0041960863*l - 0.7034186147*m + 1.7076147010*s
+	return r, g, b
+}
+
+// OKLabToOKLCh converts OKLab values to the cylindrical OKLCh representation.
+// The hue is given in radians in the range [0, 2π).
+func OKLabToOKLCh(L, A, B float64) (l, c, h float64) {
+	h = math.Atan2(B, A)
+	if h < 0 {
+		h += 2 * math.Pi
+	}
+	return L, math.Hypot(A, B), h
+}
+
+// OKLChToOKLab converts OKLCh values (hue in radians) to OKLab values.
+func OKLChToOKLab(l, c, h float64) (L, A, B float64) {
+	return l, c * math.Cos(h), c * math.Sin(h)
+}
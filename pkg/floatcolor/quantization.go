@@ -0,0 +1,59 @@
+package floatcolor
+
+import "math"
+
+// Quantization is the strategy used to turn float channel values into the integer values of 8 and 16 bit colors and images.
+// The dithering strategies need the position of a pixel (or its neighbours) and only apply when whole float images
+// are converted, single colors quantize with RoundHalfEven instead.
+type Quantization uint8
+
+const (
+	// Truncate cuts off the fraction of the scaled value. This is the default and the behaviour of the integer conversion.
+	Truncate Quantization = iota
+	// RoundHalfEven rounds the scaled value to the nearest integer, and to the even one if the fraction is exactly 0.5.
+	RoundHalfEven
+	// RoundHalfAwayFromZero rounds the scaled value to the nearest integer, and up if the fraction is exactly 0.5
+	// (like math.Round). It is the rounding of the deprecated Precise flag.
+	RoundHalfAwayFromZero
+	// Bayer adds an 8x8 ordered dither (Bayer) matrix to the scaled values before truncating them.
+	Bayer
+	// BlueNoise adds a 64x64 blue noise threshold map to the scaled values before truncating them. Unlike the Bayer
+	// matrix it leaves no regular cross hatch pattern, only fine grained noise.
+	BlueNoise
+	// FloydSteinberg rounds the scaled values and distributes the rounding errors to the neighbouring pixels not
+	// converted yet. Pixels are converted one after another, so this is the slowest strategy.
+	FloydSteinberg
+)
+
+// WithPrecise returns q, or RoundHalfAwayFromZero if q is Truncate and precise is set. It maps the deprecated Precise flag
+// of colors and images to a quantization.
+func (q Quantization) WithPrecise(precise bool) Quantization {
+	if q == Truncate && precise {
+		return RoundHalfAwayFromZero
+	}
+	return q
+}
+
+// Quantize returns v clamped to [0, max] and quantized to an integer without a pixel position,
+// i.e. truncated with Truncate, rounded half away from zero with RoundHalfAwayFromZero and rounded to the nearest
+// integer (half to even) with all other strategies.
+func (q Quantization) Quantize(v, max float64) float64 {
+	if v > max {
+		v = max
+	} else if !(v > 0) {
+		return 0 // Includes NaN
+	}
+
+	if q == Truncate {
+		return math.Trunc(v)
+	}
+	return q.round(v)
+}
+
+// round rounds v to an integer half away from zero with RoundHalfAwayFromZero and half to even otherwise.
+func (q Quantization) round(v float64) float64 {
+	if q == RoundHalfAwayFromZero {
+		return math.Round(v)
+	}
+	return math.RoundToEven(v)
+}
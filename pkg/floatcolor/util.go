@@ -1,9 +1,6 @@
 package floatcolor
 
-import (
-	"image/color"
-	"math"
-)
+import "image/color"
 
 // Mix smoothly mixes the RGB values of two color into one resulting color.
 // Parameter mix determine how much percent of color c2 is in the resulting mixed color.
@@ -20,33 +17,37 @@ func mix(c1 color.Color, c2 color.Color, mix float64) color.Color {
 	A := cc1.A*(1.0-mix) + cc2.A*mix
 
 	precise := cc1.Precise || cc2.Precise
+	quantization := cc1.Quantization
+	if quantization == Truncate {
+		quantization = cc2.Quantization
+	}
 
-	return NRGBAF64{R: R, G: G, B: B, A: A, Precise: precise}
+	return NRGBAF64{R: R, G: G, B: B, A: A, Precise: precise, Quantization: quantization}
 }
 
-func clampF32(v float32, min float32, max float32, roundToInteger bool) float32 {
+func clampF32(v float32, min float32, max float32, q Quantization) float32 {
 	if v > max {
 		v = max
 	} else if v < min {
 		v = min
 	}
 
-	if roundToInteger {
-		return float32(math.Round(float64(v)))
+	if q != Truncate {
+		return float32(q.round(float64(v)))
 	} else {
 		return v
 	}
 }
 
-func clampF64(v float64, min float64, max float64, roundToInteger bool) float64 {
+func clampF64(v float64, min float64, max float64, q Quantization) float64 {
 	if v > max {
 		v = max
 	} else if v < min {
 		v = min
 	}
 
-	if roundToInteger {
-		return math.Round(v)
+	if q != Truncate {
+		return q.round(v)
 	} else {
 		return v
 	}
@@ -0,0 +1,254 @@
+package floatimage
+
+import (
+	"floatimage/pkg/floatcolor"
+	"image"
+	"image/color"
+)
+
+// GrayF64 is an in-memory image whose At method returns floatcolor.GrayF64 values.
+// It is typically used for scalar data such as depth, error or density fields.
+type GrayF64 struct {
+	// Pix holds the image's pixels, as gray values.
+	// The pixel at (x, y) is at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*1].
+	Pix []float64
+	// Stride is the Pix stride (in elements) between vertically adjacent pixels.
+	Stride int
+	// Rect is the image's bounds.
+	Rect image.Rectangle
+	// Precise rounds channel values to the nearest integer when converting to 8 and 16 bit images and colors.
+	//
+	// Deprecated: Precise is the same as the RoundHalfAwayFromZero quantization, set Quantization instead.
+	Precise bool
+	// Quantization is the strategy to convert channel values to 8 and 16 bit images and colors,
+	// including the dithering strategies for whole images.
+	Quantization floatcolor.Quantization
+}
+
+// NewGrayF64 returns a new GrayF64 image with the given dimensions.
+// A GrayF64 image is a single channel, fully opaque image
+// where the gray values are float64 values in the typical range [0.0, 1.0].
+func NewGrayF64(width, height int) *GrayF64 {
+	return NewGrayF64WithBounds(0, 0, width, height)
+}
+
+// NewGrayF64WithBounds returns a new GrayF64 image with the given bounds.
+// A GrayF64 image is a single channel, fully opaque image
+// where the gray values are float64 values in the typical range [0.0, 1.0].
+func NewGrayF64WithBounds(x0, y0, x1, y1 int) *GrayF64 {
+	r := image.Rect(x0, y0, x1, y1)
+	const channels = 1
+
+	return &GrayF64{
+		Pix:     make([]float64, pixelBufferLength(channels, r, "GrayF64")),
+		Stride:  channels * r.Dx(),
+		Rect:    r,
+		Precise: false,
+	}
+}
+
+// NewGrayF64Checked returns a new GrayF64 image with the bounds r, like NewGrayF64WithBounds, but returns a *DimensionError
+// instead of panicking if r has negative dimensions (r is not canonicalized), is too large to allocate, or has more pixels
+// than maxPixels. A maxPixels of zero applies the global budget of SetMaxPixels, a negative one no budget at all.
+func NewGrayF64Checked(r image.Rectangle, maxPixels int) (*GrayF64, error) {
+	const channels = 1
+	length, err := checkedBufferLength(channels, 8, r, maxPixels, "GrayF64")
+	if err != nil {
+		return nil, err
+	}
+
+	return &GrayF64{
+		Pix:    make([]float64, length),
+		Stride: channels * r.Dx(),
+		Rect:   r,
+	}, nil
+}
+
+func (p *GrayF64) ColorModel() color.Model { return floatcolor.GrayF64Model }
+
+func (p *GrayF64) Bounds() image.Rectangle { return p.Rect }
+
+func (p *GrayF64) At(x, y int) color.Color {
+	if !(image.Point{X: x, Y: y}.In(p.Rect)) {
+		return color.RGBA64{}
+	}
+	i := p.PixOffset(x, y)
+
+	return floatcolor.GrayF64{Y: p.Pix[i], Precise: p.Precise, Quantization: p.Quantization}
+}
+
+func (p *GrayF64) RGBA64At(x, y int) color.RGBA64 {
+	if !(image.Point{X: x, Y: y}.In(p.Rect)) {
+		return color.RGBA64{}
+	}
+	i := p.PixOffset(x, y)
+
+	v := uint16(quantizeAt(p.Pix[i]*0xffff, 0xffff, p.quantization(), x, y))
+
+	return color.RGBA64{R: v, G: v, B: v, A: 0xffff}
+}
+
+func (p *GrayF64) AsRGBA() *image.RGBA {
+	return exportRGBA(p, 0, 0, false)
+}
+
+func (p *GrayF64) AsNRGBA() *image.NRGBA {
+	return exportNRGBA(p, 0, 0, false)
+}
+
+func (p *GrayF64) AsRGBAForRange(min, max float64) *image.RGBA {
+	return exportRGBA(p, min, max, true)
+}
+
+func (p *GrayF64) AsNRGBAForRange(min, max float64) *image.NRGBA {
+	return exportNRGBA(p, min, max, true)
+}
+
+func (p *GrayF64) AsRGBA64() *image.RGBA64 {
+	return exportRGBA64(p, 0, 0, false)
+}
+
+func (p *GrayF64) AsNRGBA64() *image.NRGBA64 {
+	return exportNRGBA64(p, 0, 0, false)
+}
+
+func (p *GrayF64) AsRGBA64ForRange(min, max float64) *image.RGBA64 {
+	return exportRGBA64(p, min, max, true)
+}
+
+func (p *GrayF64) AsNRGBA64ForRange(min, max float64) *image.NRGBA64 {
+	return exportNRGBA64(p, min, max, true)
+}
+
+func (p *GrayF64) AsGray() *image.Gray {
+	return exportGray(p, 0, 0, false)
+}
+
+func (p *GrayF64) AsGray16() *image.Gray16 {
+	return exportGray16(p, 0, 0, false)
+}
+
+func (p *GrayF64) AsGrayForRange(min, max float64) *image.Gray {
+	return exportGray(p, min, max, true)
+}
+
+func (p *GrayF64) AsGray16ForRange(min, max float64) *image.Gray16 {
+	return exportGray16(p, min, max, true)
+}
+
+// exportValues returns the values function of quantizeImage for the conversion to images with premultiplied
+// or ordinary alpha, with the color values mapped from [min, max] to [0.0, 1.0] if useRange is set.
+func (p *GrayF64) exportValues(min, max float64, useRange bool, premultiplied bool) func(x, y int, v []float64) {
+	return func(x, y int, v []float64) {
+		c := p.GrayF64At(x, y)
+		if useRange {
+			c.Y = (c.Y - min) / (max - min)
+		}
+		v[0], v[1], v[2], v[3] = c.Y, c.Y, c.Y, 1.0
+	}
+}
+
+// quantization returns the quantization of the image, taking the deprecated Precise flag into account.
+func (p *GrayF64) quantization() floatcolor.Quantization {
+	return p.Quantization.WithPrecise(p.Precise)
+}
+
+// PixOffset returns the index of the element of Pix that corresponds to the pixel at (x, y).
+func (p *GrayF64) PixOffset(x, y int) int {
+	const channels = 1
+	return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*channels
+}
+
+// GrayF64At returns the color of the pixel at (x, y). Unlike At, it returns the concrete color type,
+// which avoids allocating a color.Color interface value and the type assertion on the caller side.
+func (p *GrayF64) GrayF64At(x, y int) floatcolor.GrayF64 {
+	if !(image.Point{X: x, Y: y}.In(p.Rect)) {
+		return floatcolor.GrayF64{}
+	}
+
+	return floatcolor.GrayF64{Y: p.Pix[p.PixOffset(x, y)], Precise: p.Precise, Quantization: p.Quantization}
+}
+
+// SetGrayF64 sets the pixel at (x, y) to c. Unlike Set, it stores the value as it is, without going through the color model.
+func (p *GrayF64) SetGrayF64(x, y int, c floatcolor.GrayF64) {
+	if !(image.Point{X: x, Y: y}.In(p.Rect)) {
+		return
+	}
+
+	p.Pix[p.PixOffset(x, y)] = c.Y
+}
+
+// NRGBAF64At returns the color of the pixel at (x, y) as a floatcolor.NRGBAF64, an opaque gray.
+// It gives all float image types a common accessor that does not allocate.
+func (p *GrayF64) NRGBAF64At(x, y int) floatcolor.NRGBAF64 {
+	if !(image.Point{X: x, Y: y}.In(p.Rect)) {
+		return floatcolor.NRGBAF64{}
+	}
+	v := p.Pix[p.PixOffset(x, y)]
+
+	return floatcolor.NRGBAF64{R: v, G: v, B: v, A: 1.0, Precise: p.Precise, Quantization: p.Quantization}
+}
+
+// SetNRGBAF64 sets the pixel at (x, y) to the luminance of c composited over black, like Set does for float colors.
+func (p *GrayF64) SetNRGBAF64(x, y int, c floatcolor.NRGBAF64) {
+	if !(image.Point{X: x, Y: y}.In(p.Rect)) {
+		return
+	}
+
+	p.Pix[p.PixOffset(x, y)] = floatcolor.Luminance(c.R, c.G, c.B) * c.A
+}
+
+// Row returns the part of Pix holding row y of the image, one value per pixel from Rect.Min.X to Rect.Max.X.
+// The returned slice shares its values with the image. It returns nil if y is outside the image bounds.
+func (p *GrayF64) Row(y int) []float64 {
+	if y < p.Rect.Min.Y || y >= p.Rect.Max.Y {
+		return nil
+	}
+	i := (y - p.Rect.Min.Y) * p.Stride
+	n := p.Rect.Dx() * 1
+
+	return p.Pix[i : i+n : i+n]
+}
+
+func (p *GrayF64) Set(x, y int, c color.Color) {
+	if !(image.Point{X: x, Y: y}.In(p.Rect)) {
+		return
+	}
+	c1 := floatcolor.GrayF64Model.Convert(c).(floatcolor.GrayF64)
+
+	p.Pix[p.PixOffset(x, y)] = c1.Y
+}
+
+func (p *GrayF64) SetRGBA64(x, y int, c color.RGBA64) {
+	if !(image.Point{X: x, Y: y}.In(p.Rect)) {
+		return
+	}
+	c1 := floatcolor.GrayF64Model.Convert(c).(floatcolor.GrayF64)
+
+	p.Pix[p.PixOffset(x, y)] = c1.Y
+}
+
+// SubImage returns an image representing the portion of the image p visible through r.
+// The returned value shares pixels with the original image.
+func (p *GrayF64) SubImage(r image.Rectangle) image.Image {
+	r = r.Intersect(p.Rect)
+	// If r1 and r2 are Rectangles, r1.Intersect(r2) is not guaranteed to be inside
+	// either r1 or r2 if the intersection is empty.
+	// Without explicitly checking for this, the Pix[i:] expression below can panic.
+	if r.Empty() {
+		return &GrayF64{}
+	}
+	i := p.PixOffset(r.Min.X, r.Min.Y)
+	return &GrayF64{
+		Pix:          p.Pix[i:],
+		Stride:       p.Stride,
+		Rect:         r,
+		Precise:      p.Precise,
+		Quantization: p.Quantization,
+	}
+}
+
+// Opaque reports whether the image is fully opaque, which a gray scale image always is.
+func (p *GrayF64) Opaque() bool {
+	return true
+}
@@ -0,0 +1,34 @@
+package floatimage
+
+import (
+	"floatimage/pkg/floatcolor"
+	"math"
+	"testing"
+)
+
+func TestGrayF64(t *testing.T) {
+	grayf64 := NewGrayF64(100, 100)
+
+	width := grayf64.Bounds().Dx()
+	height := grayf64.Bounds().Dy()
+	diagonalMax := math.Sqrt(float64(width*width + height*height))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			diagonal := math.Sqrt(float64(x*x + y*y))
+
+			// Values in the range [-1.0, 1.0] to have something to test the ranged conversion with
+			c := floatcolor.GrayF64{Y: 2.0*diagonal/diagonalMax - 1.0}
+
+			grayf64.Set(x+grayf64.Bounds().Min.X, y+grayf64.Bounds().Min.Y, c)
+		}
+	}
+
+	writeImage("../testresult/GrayF64.png", grayf64)
+	writeImage("../testresult/GrayF64_as_NRGBA.png", grayf64.AsNRGBA())
+	writeImage("../testresult/GrayF64_as_RGBA_for_range.png", grayf64.AsRGBAForRange(-1.0, 1.0))
+
+	if v := grayf64.AsNRGBAForRange(-1.0, 1.0).NRGBAAt(99, 99).R; v < 0xfa {
+		t.Errorf("expected ranged conversion to map the maximum value to white but got %d", v)
+	}
+}
@@ -0,0 +1,391 @@
+package floatimage
+
+import (
+	"errors"
+	"floatimage/pkg/floatcolor"
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// MultiChannelF32 is an in-memory image with any number of named channels per pixel, all encoded as float32 values.
+// It holds the arbitrary output variables (AOVs) of a renderer next to each other, e.g. the beauty pass,
+// normals, albedo, depth, motion vectors and object IDs. The channels have no color meaning by themselves,
+// any three or four of them can be extracted as a color image or viewed as one.
+type MultiChannelF32 struct {
+	// Pix holds the image's pixels, the values of all channels of a pixel next to each other in channel order.
+	// The pixel at (x, y) starts at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*Channels()].
+	Pix []float32
+	// Stride is the Pix stride (in values) between vertically adjacent pixels.
+	Stride int
+	// Rect is the image's bounds.
+	Rect image.Rectangle
+	// Precise is the Precise setting of the colors of extracted images and views.
+	//
+	// Deprecated: Precise is the same as the RoundHalfAwayFromZero quantization, set Quantization instead.
+	Precise bool
+	// Quantization is the quantization of the colors of extracted images and views.
+	Quantization floatcolor.Quantization
+
+	names []string
+}
+
+// NewMultiChannelF32 returns a new MultiChannelF32 image with the given dimensions and one channel per name.
+// It panics if there are no names or a name is used twice.
+func NewMultiChannelF32(width, height int, names ...string) *MultiChannelF32 {
+	return NewMultiChannelF32WithBounds(0, 0, width, height, names...)
+}
+
+// NewMultiChannelF32WithBounds returns a new MultiChannelF32 image with the given bounds and one channel per name.
+// It panics if there are no names or a name is used twice.
+func NewMultiChannelF32WithBounds(x0, y0, x1, y1 int, names ...string) *MultiChannelF32 {
+	if err := checkChannelNames(names); err != nil {
+		panic(err.Error())
+	}
+
+	r := image.Rect(x0, y0, x1, y1)
+	channels := len(names)
+
+	return &MultiChannelF32{
+		Pix:    make([]float32, pixelBufferLength(channels, r, "MultiChannelF32")),
+		Stride: channels * r.Dx(),
+		Rect:   r,
+		names:  append([]string(nil), names...),
+	}
+}
+
+// NewMultiChannelF32Checked returns a new MultiChannelF32 image with the bounds r and one channel per name,
+// like NewMultiChannelF32WithBounds, but returns an error instead of panicking: a *DimensionError if r has negative
+// dimensions (r is not canonicalized), is too large to allocate, or has more pixels than maxPixels, and an error
+// if there are no names or a name is used twice. A maxPixels of zero applies the global budget of SetMaxPixels,
+// a negative one no budget at all.
+func NewMultiChannelF32Checked(r image.Rectangle, maxPixels int, names ...string) (*MultiChannelF32, error) {
+	if err := checkChannelNames(names); err != nil {
+		return nil, err
+	}
+	channels := len(names)
+	length, err := checkedBufferLength(channels, 4, r, maxPixels, "MultiChannelF32")
+	if err != nil {
+		return nil, err
+	}
+
+	return &MultiChannelF32{
+		Pix:    make([]float32, length),
+		Stride: channels * r.Dx(),
+		Rect:   r,
+		names:  append([]string(nil), names...),
+	}, nil
+}
+
+// checkChannelNames returns an error if there are no names or a name is used twice.
+func checkChannelNames(names []string) error {
+	if len(names) == 0 {
+		return errors.New("image: NewMultiChannelF32 needs at least one channel")
+	}
+	for i, name := range names {
+		for _, other := range names[:i] {
+			if name == other {
+				return errors.New("image: NewMultiChannelF32 channel name " + name + " used twice")
+			}
+		}
+	}
+	return nil
+}
+
+func (p *MultiChannelF32) Bounds() image.Rectangle { return p.Rect }
+
+// Channels returns the number of channels per pixel.
+func (p *MultiChannelF32) Channels() int {
+	return len(p.names)
+}
+
+// ChannelNames returns the names of the channels in channel order.
+func (p *MultiChannelF32) ChannelNames() []string {
+	return append([]string(nil), p.names...)
+}
+
+// ChannelIndex returns the index of the channel with the given name, or -1 if there is no such channel.
+func (p *MultiChannelF32) ChannelIndex(name string) int {
+	for i, n := range p.names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// PixOffset returns the index of the first element of Pix that corresponds to the pixel at (x, y).
+func (p *MultiChannelF32) PixOffset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*len(p.names)
+}
+
+// Pixel returns the values of all channels of the pixel at (x, y), sharing them with the image.
+// It returns nil if (x, y) is outside the image bounds.
+func (p *MultiChannelF32) Pixel(x, y int) []float32 {
+	if !(image.Point{X: x, Y: y}.In(p.Rect)) {
+		return nil
+	}
+	i := p.PixOffset(x, y)
+	return p.Pix[i : i+len(p.names) : i+len(p.names)]
+}
+
+// Value returns the value of the channel with the given index at (x, y), or zero outside the image bounds
+// and for channel indices outside [0, Channels()).
+func (p *MultiChannelF32) Value(x, y, channel int) float32 {
+	if !(image.Point{X: x, Y: y}.In(p.Rect)) || channel < 0 || channel >= len(p.names) {
+		return 0
+	}
+	return p.Pix[p.PixOffset(x, y)+channel]
+}
+
+// SetValue sets the value of the channel with the given index at (x, y). Pixels outside the image bounds
+// and channel indices outside [0, Channels()) are ignored.
+func (p *MultiChannelF32) SetValue(x, y, channel int, v float32) {
+	if !(image.Point{X: x, Y: y}.In(p.Rect)) || channel < 0 || channel >= len(p.names) {
+		return
+	}
+	p.Pix[p.PixOffset(x, y)+channel] = v
+}
+
+// SubImage returns an image representing the portion of the image p visible through r.
+// The returned value shares pixels with the original image.
+func (p *MultiChannelF32) SubImage(r image.Rectangle) *MultiChannelF32 {
+	r = r.Intersect(p.Rect)
+	// If r1 and r2 are Rectangles, r1.Intersect(r2) is not guaranteed to be inside
+	// either r1 or r2 if the intersection is empty.
+	// Without explicitly checking for this, the Pix[i:] expression below can panic.
+	if r.Empty() {
+		return &MultiChannelF32{names: p.names, Precise: p.Precise, Quantization: p.Quantization}
+	}
+	i := p.PixOffset(r.Min.X, r.Min.Y)
+	return &MultiChannelF32{
+		Pix:          p.Pix[i:],
+		Stride:       p.Stride,
+		Rect:         r,
+		Precise:      p.Precise,
+		Quantization: p.Quantization,
+		names:        p.names,
+	}
+}
+
+// channelIndices returns the indices of the named channels, three or four of them.
+func (p *MultiChannelF32) channelIndices(names []string) ([]int, error) {
+	if len(names) != 3 && len(names) != 4 {
+		return nil, fmt.Errorf("floatimage: expected 3 or 4 channel names but got %d", len(names))
+	}
+
+	indices := make([]int, len(names))
+	for i, name := range names {
+		if indices[i] = p.ChannelIndex(name); indices[i] < 0 {
+			return nil, fmt.Errorf("floatimage: unknown channel %q", name)
+		}
+	}
+	return indices, nil
+}
+
+// ExtractNRGBAF32 returns a copy of the named channels as the red, green, blue and (if four names are given) alpha
+// channels of a new NRGBAF32 image with the same bounds. Without an alpha channel the image is opaque.
+// It returns an error if not three or four names are given or a channel does not exist.
+func (p *MultiChannelF32) ExtractNRGBAF32(names ...string) (*NRGBAF32, error) {
+	v, err := p.ViewNRGBAF32(names...)
+	if err != nil {
+		return nil, err
+	}
+	return v.Copy().(*NRGBAF32), nil
+}
+
+// ExtractRGBAF32 is like ExtractNRGBAF32, but returns an RGBAF32 image.
+// The channel values are copied as they are, so the color channels have to hold values premultiplied with alpha.
+func (p *MultiChannelF32) ExtractRGBAF32(names ...string) (*RGBAF32, error) {
+	v, err := p.ViewRGBAF32(names...)
+	if err != nil {
+		return nil, err
+	}
+	return v.Copy().(*RGBAF32), nil
+}
+
+// ExtractGrayF64 returns a copy of the named channel as a new GrayF64 image with the same bounds,
+// e.g. for a depth channel. It returns an error if the channel does not exist.
+func (p *MultiChannelF32) ExtractGrayF64(name string) (*GrayF64, error) {
+	c := p.ChannelIndex(name)
+	if c < 0 {
+		return nil, fmt.Errorf("floatimage: unknown channel %q", name)
+	}
+
+	result := NewGrayF64WithBounds(p.Rect.Min.X, p.Rect.Min.Y, p.Rect.Max.X, p.Rect.Max.Y)
+	result.Precise = p.Precise
+	result.Quantization = p.Quantization
+	forEachPixelPair(p.Rect, result.Stride, p.Stride, 1, len(p.names), func(i, j int) {
+		result.Pix[i] = float64(p.Pix[j+c])
+	})
+	return result, nil
+}
+
+// extract copies the channels with the given indices into the 4 channel pixels pix with the given stride,
+// with alpha 1.0 if there are only three channels.
+func (p *MultiChannelF32) extract(pix []float32, stride int, indices []int) {
+	forEachPixelPair(p.Rect, stride, p.Stride, 4, len(p.names), func(i, j int) {
+		d := pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+		d[0], d[1], d[2], d[3] = p.Pix[j+indices[0]], p.Pix[j+indices[1]], p.Pix[j+indices[2]], 1.0
+		if len(indices) == 4 {
+			d[3] = p.Pix[j+indices[3]]
+		}
+	})
+}
+
+// ViewNRGBAF32 returns a view of the named channels as the red, green, blue and (if four names are given) alpha
+// channels of an NRGBAF32 image. The view shares its values with the image: it reads the current channel values
+// and Set writes to them. It returns an error if not three or four names are given or a channel does not exist.
+func (p *MultiChannelF32) ViewNRGBAF32(names ...string) (*ChannelView, error) {
+	indices, err := p.channelIndices(names)
+	if err != nil {
+		return nil, err
+	}
+	return &ChannelView{image: p, indices: indices}, nil
+}
+
+// ViewRGBAF32 is like ViewNRGBAF32, but the view is an RGBAF32 image whose color channels hold values premultiplied with alpha.
+func (p *MultiChannelF32) ViewRGBAF32(names ...string) (*ChannelView, error) {
+	indices, err := p.channelIndices(names)
+	if err != nil {
+		return nil, err
+	}
+	return &ChannelView{image: p, indices: indices, premultiplied: true}, nil
+}
+
+// ChannelView is a float image of three or four channels of a MultiChannelF32 image,
+// with the color model of NRGBAF32 or RGBAF32 images. Views without an alpha channel are opaque
+// and ignore the alpha of the colors they are set to. Views are created by the View methods of MultiChannelF32.
+type ChannelView struct {
+	image         *MultiChannelF32
+	indices       []int
+	premultiplied bool
+}
+
+func (v *ChannelView) ColorModel() color.Model {
+	if v.premultiplied {
+		return floatcolor.RGBAF32Model
+	}
+	return floatcolor.NRGBAF32Model
+}
+
+func (v *ChannelView) Bounds() image.Rectangle { return v.image.Rect }
+
+// values returns the red, green, blue and alpha values of the pixel at (x, y), which has to be within the bounds.
+func (v *ChannelView) values(x, y int) (r, g, b, a float32) {
+	s := v.image.Pix[v.image.PixOffset(x, y):]
+	a = 1.0
+	if len(v.indices) == 4 {
+		a = s[v.indices[3]]
+	}
+	return s[v.indices[0]], s[v.indices[1]], s[v.indices[2]], a
+}
+
+func (v *ChannelView) At(x, y int) color.Color {
+	if !(image.Point{X: x, Y: y}.In(v.image.Rect)) {
+		return color.RGBA64{}
+	}
+
+	r, g, b, a := v.values(x, y)
+	if v.premultiplied {
+		return floatcolor.RGBAF32{R: r, G: g, B: b, A: a, Precise: v.image.Precise, Quantization: v.image.Quantization}
+	}
+	return floatcolor.NRGBAF32{R: r, G: g, B: b, A: a, Precise: v.image.Precise, Quantization: v.image.Quantization}
+}
+
+func (v *ChannelView) RGBA64At(x, y int) color.RGBA64 {
+	r, g, b, a := v.At(x, y).RGBA()
+	return color.RGBA64{R: uint16(r), G: uint16(g), B: uint16(b), A: uint16(a)}
+}
+
+func (v *ChannelView) Set(x, y int, c color.Color) {
+	if !(image.Point{X: x, Y: y}.In(v.image.Rect)) {
+		return
+	}
+
+	s := v.image.Pix[v.image.PixOffset(x, y):]
+	if v.premultiplied {
+		c1 := floatcolor.RGBAF32Model.Convert(c).(floatcolor.RGBAF32)
+		s[v.indices[0]], s[v.indices[1]], s[v.indices[2]] = c1.R, c1.G, c1.B
+		if len(v.indices) == 4 {
+			s[v.indices[3]] = c1.A
+		}
+		return
+	}
+
+	c1 := floatcolor.NRGBAF32Model.Convert(c).(floatcolor.NRGBAF32)
+	s[v.indices[0]], s[v.indices[1]], s[v.indices[2]] = c1.R, c1.G, c1.B
+	if len(v.indices) == 4 {
+		s[v.indices[3]] = c1.A
+	}
+}
+
+func (v *ChannelView) SetRGBA64(x, y int, c color.RGBA64) {
+	v.Set(x, y, c)
+}
+
+// Copy returns a copy of the viewed channels as a new NRGBAF32 or RGBAF32 image, see ExtractNRGBAF32 and ExtractRGBAF32.
+func (v *ChannelView) Copy() FloatImage {
+	p := v.image
+	if v.premultiplied {
+		result := NewRGBAF32WithBounds(p.Rect.Min.X, p.Rect.Min.Y, p.Rect.Max.X, p.Rect.Max.Y)
+		result.Precise = p.Precise
+		result.Quantization = p.Quantization
+		p.extract(result.Pix, result.Stride, v.indices)
+		return result
+	}
+
+	result := NewNRGBAF32WithBounds(p.Rect.Min.X, p.Rect.Min.Y, p.Rect.Max.X, p.Rect.Max.Y)
+	result.Precise = p.Precise
+	result.Quantization = p.Quantization
+	p.extract(result.Pix, result.Stride, v.indices)
+	return result
+}
+
+func (v *ChannelView) AsRGBA() *image.RGBA { return v.Copy().AsRGBA() }
+
+func (v *ChannelView) AsNRGBA() *image.NRGBA { return v.Copy().AsNRGBA() }
+
+func (v *ChannelView) AsRGBAForRange(min, max float64) *image.RGBA {
+	return v.Copy().AsRGBAForRange(min, max)
+}
+
+func (v *ChannelView) AsNRGBAForRange(min, max float64) *image.NRGBA {
+	return v.Copy().AsNRGBAForRange(min, max)
+}
+
+func (v *ChannelView) AsRGBA64() *image.RGBA64 { return v.Copy().AsRGBA64() }
+
+func (v *ChannelView) AsNRGBA64() *image.NRGBA64 { return v.Copy().AsNRGBA64() }
+
+func (v *ChannelView) AsRGBA64ForRange(min, max float64) *image.RGBA64 {
+	return v.Copy().AsRGBA64ForRange(min, max)
+}
+
+func (v *ChannelView) AsNRGBA64ForRange(min, max float64) *image.NRGBA64 {
+	return v.Copy().AsNRGBA64ForRange(min, max)
+}
+
+func (v *ChannelView) AsGray() *image.Gray { return v.Copy().AsGray() }
+
+func (v *ChannelView) AsGray16() *image.Gray16 { return v.Copy().AsGray16() }
+
+func (v *ChannelView) AsGrayForRange(min, max float64) *image.Gray {
+	return v.Copy().AsGrayForRange(min, max)
+}
+
+func (v *ChannelView) AsGray16ForRange(min, max float64) *image.Gray16 {
+	return v.Copy().AsGray16ForRange(min, max)
+}
+
+// Opaque scans the entire image and reports whether it is fully opaque.
+func (v *ChannelView) Opaque() bool {
+	if len(v.indices) == 3 || v.image.Rect.Empty() {
+		return true
+	}
+
+	alpha := v.indices[3]
+	return allPixels(v.image.Rect, v.image.Stride, len(v.image.names), func(i int) bool {
+		return v.image.Pix[i+alpha] == 1.0
+	})
+}
@@ -0,0 +1,154 @@
+package floatimage
+
+import (
+	"floatimage/pkg/floatcolor"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+var aovChannels = []string{"R", "G", "B", "A", "N.x", "N.y", "N.z", "depth", "id"}
+
+func testMultiChannelImage() *MultiChannelF32 {
+	img := NewMultiChannelF32WithBounds(-2, 1, 6, 5, aovChannels...)
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			s := img.Pixel(x, y)
+			for c := range s {
+				s[c] = float32(c*100 + (x+2)*10 + (y - 1))
+			}
+		}
+	}
+	return img
+}
+
+func TestMultiChannelF32(t *testing.T) {
+	img := testMultiChannelImage()
+
+	if img.Channels() != len(aovChannels) || img.ChannelIndex("depth") != 7 || img.ChannelIndex("missing") != -1 {
+		t.Fatalf("expected %d named channels with depth at index 7", len(aovChannels))
+	}
+	if img.Value(3, 2, 7) != 751 || img.Pixel(3, 2)[8] != 851 {
+		t.Errorf("expected channel values of pixel (3, 2) but got %v", img.Pixel(3, 2))
+	}
+	img.SetValue(3, 2, 7, 100)
+	if img.Value(3, 2, 7) != 100 || img.Value(10, 2, 7) != 0 {
+		t.Errorf("expected value to be set within bounds only")
+	}
+
+	n := img.Channels()
+	before := append([]float32(nil), img.Pix...)
+	img.SetValue(3, 2, n, 42)
+	img.SetValue(3, 2, -1, 42)
+	for i := range before {
+		if img.Pix[i] != before[i] {
+			t.Fatalf("expected invalid channels to be ignored but value %d changed", i)
+		}
+	}
+	if img.Value(3, 2, n) != 0 || img.Value(3, 2, -1) != 0 {
+		t.Errorf("expected zero for invalid channels but got %v and %v", img.Value(3, 2, n), img.Value(3, 2, -1))
+	}
+
+	sub := img.SubImage(image.Rect(0, 2, 4, 4))
+	if sub.Value(3, 2, 7) != 100 || sub.Value(-1, 2, 0) != 0 {
+		t.Errorf("expected sub image to share values within its bounds")
+	}
+
+	names := img.ChannelNames()
+	names[0] = "changed"
+	if img.ChannelIndex("R") != 0 {
+		t.Errorf("expected channel names to be copied")
+	}
+}
+
+func TestMultiChannelF32Extract(t *testing.T) {
+	img := testMultiChannelImage()
+
+	normals, err := img.ExtractNRGBAF32("N.x", "N.y", "N.z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := normals.NRGBAF32At(2, 3); c != (floatcolor.NRGBAF32{R: 442, G: 542, B: 642, A: 1.0}) || normals.Rect != img.Rect {
+		t.Errorf("expected opaque normals at (2, 3) but got %+v", c)
+	}
+
+	beauty, err := img.ExtractRGBAF32("R", "G", "B", "A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := beauty.RGBAF32At(-2, 4); c != (floatcolor.RGBAF32{R: 3, G: 103, B: 203, A: 303}) {
+		t.Errorf("expected beauty channels at (-2, 4) but got %+v", c)
+	}
+
+	depth, err := img.ExtractGrayF64("depth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := depth.GrayF64At(5, 1).Y; v != 770 {
+		t.Errorf("expected depth at (5, 1) but got %v", v)
+	}
+
+	beauty.Pix[0] = 1000
+	if img.Pix[0] == 1000 {
+		t.Errorf("expected extracted image to be a copy")
+	}
+
+	if _, err := img.ExtractNRGBAF32("R", "G"); err == nil {
+		t.Errorf("expected error for two channels")
+	}
+	if _, err := img.ExtractRGBAF32("R", "G", "missing"); err == nil {
+		t.Errorf("expected error for unknown channel")
+	}
+	if _, err := img.ExtractGrayF64("missing"); err == nil {
+		t.Errorf("expected error for unknown channel")
+	}
+}
+
+func TestMultiChannelF32View(t *testing.T) {
+	img := testMultiChannelImage()
+
+	view, err := img.ViewNRGBAF32("depth", "id", "R")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view.ColorModel() != floatcolor.NRGBAF32Model || !view.Opaque() {
+		t.Errorf("expected opaque NRGBAF32 view")
+	}
+	if c := view.At(1, 1); c != (floatcolor.NRGBAF32{R: 730, G: 830, B: 30, A: 1.0}) {
+		t.Errorf("expected viewed channels at (1, 1) but got %+v", c)
+	}
+
+	img.SetValue(1, 1, 7, 0.5)
+	view.Set(2, 2, floatcolor.NRGBAF32{R: 0.25, G: 0.75, B: 1.0, A: 0.5})
+	if c := view.At(1, 1).(floatcolor.NRGBAF32); c.R != 0.5 {
+		t.Errorf("expected view to read the current values but got %+v", c)
+	}
+	if img.Value(2, 2, 7) != 0.25 || img.Value(2, 2, 8) != 0.75 || img.Value(2, 2, 0) != 1.0 || img.Value(2, 2, 3) != 341 {
+		t.Errorf("expected Set to write the viewed channels only but got %v", img.Pixel(2, 2))
+	}
+
+	// Beauty pass drawn into the image through a premultiplied view.
+	beauty := NewRGBAF32WithBounds(-2, 1, 6, 5)
+	for i := range beauty.Pix {
+		beauty.Pix[i] = 0.5
+	}
+	premultiplied, err := img.ViewRGBAF32("R", "G", "B", "A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	draw.Draw(premultiplied, premultiplied.Bounds(), beauty, beauty.Rect.Min, draw.Src)
+	if p := img.Pixel(0, 3); math.Abs(float64(p[0])-0.5) > 1e-4 || math.Abs(float64(p[3])-0.5) > 1e-4 || p[4] != 422 {
+		t.Errorf("expected beauty pass in the first four channels but got %v", p)
+	}
+	if premultiplied.Opaque() {
+		t.Errorf("expected translucent view")
+	}
+	if c := premultiplied.AsNRGBA().NRGBAAt(0, 3); c != (color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x7f}) {
+		t.Errorf("expected white with half alpha but got %+v", c)
+	}
+	if c := premultiplied.RGBA64At(0, 3); c.A != 0x7fff {
+		t.Errorf("expected half alpha but got %+v", c)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"floatimage/pkg/floatcolor"
 	"image"
 	"image/color"
-	"image/draw"
 )
 
 // NRGBAF32 is an in-memory image whose At method returns floatcolor.NRGBAF32 values.
@@ -15,8 +14,14 @@ type NRGBAF32 struct {
 	// Stride is the Pix stride (in bytes) between vertically adjacent pixels.
 	Stride int
 	// Rect is the image's bounds.
-	Rect    image.Rectangle
+	Rect image.Rectangle
+	// Precise rounds channel values to the nearest integer when converting to 8 and 16 bit images and colors.
+	//
+	// Deprecated: Precise is the same as the RoundHalfAwayFromZero quantization, set Quantization instead.
 	Precise bool
+	// Quantization is the strategy to convert channel values to 8 and 16 bit images and colors,
+	// including the dithering strategies for whole images.
+	Quantization floatcolor.Quantization
 }
 
 // NewNRGBAF32 returns a new NRGBAF32 image with the given dimensions.
@@ -41,6 +46,23 @@ func NewNRGBAF32WithBounds(x0, y0, x1, y1 int) *NRGBAF32 {
 	}
 }
 
+// NewNRGBAF32Checked returns a new NRGBAF32 image with the bounds r, like NewNRGBAF32WithBounds, but returns a *DimensionError
+// instead of panicking if r has negative dimensions (r is not canonicalized), is too large to allocate, or has more pixels
+// than maxPixels. A maxPixels of zero applies the global budget of SetMaxPixels, a negative one no budget at all.
+func NewNRGBAF32Checked(r image.Rectangle, maxPixels int) (*NRGBAF32, error) {
+	const channels = 4
+	length, err := checkedBufferLength(channels, 4, r, maxPixels, "NRGBAF32")
+	if err != nil {
+		return nil, err
+	}
+
+	return &NRGBAF32{
+		Pix:    make([]float32, length),
+		Stride: channels * r.Dx(),
+		Rect:   r,
+	}, nil
+}
+
 func (p *NRGBAF32) ColorModel() color.Model { return floatcolor.NRGBAF32Model }
 
 func (p *NRGBAF32) Bounds() image.Rectangle { return p.Rect }
@@ -52,7 +74,7 @@ func (p *NRGBAF32) At(x, y int) color.Color {
 	i := p.PixOffset(x, y)
 	s := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
 
-	return floatcolor.NRGBAF32{R: s[0], G: s[1], B: s[2], A: s[3], Precise: p.Precise}
+	return floatcolor.NRGBAF32{R: s[0], G: s[1], B: s[2], A: s[3], Precise: p.Precise, Quantization: p.Quantization}
 }
 
 func (p *NRGBAF32) RGBA64At(x, y int) color.RGBA64 {
@@ -62,72 +84,144 @@ func (p *NRGBAF32) RGBA64At(x, y int) color.RGBA64 {
 	i := p.PixOffset(x, y)
 	s := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
 
-	r := uint16(clampF32(s[0]*0xffff*s[3], 0x0000, 0xffff, p.Precise))
-	g := uint16(clampF32(s[1]*0xffff*s[3], 0x0000, 0xffff, p.Precise))
-	b := uint16(clampF32(s[2]*0xffff*s[3], 0x0000, 0xffff, p.Precise))
-	a := uint16(clampF32(s[3]*0xffff, 0x0000, 0xffff, p.Precise))
-
-	return color.RGBA64{R: r, G: g, B: b, A: a}
+	a := float64(s[3])
+	return quantizeRGBA64(float64(s[0])*a, float64(s[1])*a, float64(s[2])*a, a, p.quantization(), x, y)
 }
 
 func (p *NRGBAF32) AsRGBA() *image.RGBA {
-	rgbaImage := image.NewRGBA(p.Rect)
-	convertNRGBAF32ToImage(p, rgbaImage, 0, 0, false, convColorNRGBAF32toRGBA)
-	return rgbaImage
+	return exportRGBA(p, 0, 0, false)
 }
 
 func (p *NRGBAF32) AsNRGBA() *image.NRGBA {
-	nrgbaImage := image.NewNRGBA(p.Rect)
-	convertNRGBAF32ToImage(p, nrgbaImage, 0, 0, false, convColorNRGBAF32toNRGBA)
-	return nrgbaImage
+	return exportNRGBA(p, 0, 0, false)
 }
 
 func (p *NRGBAF32) AsRGBAForRange(min, max float64) *image.RGBA {
-	rgbaImage := image.NewRGBA(p.Rect)
-	convertNRGBAF32ToImage(p, rgbaImage, min, max, true, convColorNRGBAF32toRGBA)
-	return rgbaImage
+	return exportRGBA(p, min, max, true)
 }
 
 func (p *NRGBAF32) AsNRGBAForRange(min, max float64) *image.NRGBA {
-	nrgbaImage := image.NewNRGBA(p.Rect)
-	convertNRGBAF32ToImage(p, nrgbaImage, min, max, true, convColorNRGBAF32toNRGBA)
-	return nrgbaImage
+	return exportNRGBA(p, min, max, true)
 }
 
-func convColorNRGBAF32toNRGBA(convertableColor floatcolor.ConvertableColor) color.Color {
-	return convertableColor.AsNRGBA()
+func (p *NRGBAF32) AsRGBA64() *image.RGBA64 {
+	return exportRGBA64(p, 0, 0, false)
 }
 
-func convColorNRGBAF32toRGBA(convertableColor floatcolor.ConvertableColor) color.Color {
-	return convertableColor.AsRGBA()
+func (p *NRGBAF32) AsNRGBA64() *image.NRGBA64 {
+	return exportNRGBA64(p, 0, 0, false)
 }
 
-func convertNRGBAF32ToImage(source *NRGBAF32, destination draw.Image, min, max float64, useRange bool, convColorFunc func(convertableColor floatcolor.ConvertableColor) color.Color) {
-	if useRange && (min > max) {
-		min, max = max, min
-	}
+func (p *NRGBAF32) AsRGBA64ForRange(min, max float64) *image.RGBA64 {
+	return exportRGBA64(p, min, max, true)
+}
+
+func (p *NRGBAF32) AsNRGBA64ForRange(min, max float64) *image.NRGBA64 {
+	return exportNRGBA64(p, min, max, true)
+}
+
+func (p *NRGBAF32) AsGray() *image.Gray {
+	return exportGray(p, 0, 0, false)
+}
+
+func (p *NRGBAF32) AsGray16() *image.Gray16 {
+	return exportGray16(p, 0, 0, false)
+}
 
-	for y := source.Rect.Min.Y; y < source.Rect.Max.Y; y++ {
-		for x := source.Rect.Min.X; x < source.Rect.Max.X; x++ {
-			c := source.At(x, y)
-			nrgbaf32c := c.(floatcolor.NRGBAF32)
-			f32c := c.(floatcolor.ConvertableColor)
-			if useRange {
-				nrgbaf32c.R = (nrgbaf32c.R - float32(min)) / float32(max-min)
-				nrgbaf32c.G = (nrgbaf32c.G - float32(min)) / float32(max-min)
-				nrgbaf32c.B = (nrgbaf32c.B - float32(min)) / float32(max-min)
-			}
-			destination.Set(x, y, convColorFunc(f32c))
+func (p *NRGBAF32) AsGrayForRange(min, max float64) *image.Gray {
+	return exportGray(p, min, max, true)
+}
+
+func (p *NRGBAF32) AsGray16ForRange(min, max float64) *image.Gray16 {
+	return exportGray16(p, min, max, true)
+}
+
+// exportValues returns the values function of quantizeImage for the conversion to images with premultiplied
+// or ordinary alpha, with the color values mapped from [min, max] to [0.0, 1.0] if useRange is set.
+func (p *NRGBAF32) exportValues(min, max float64, useRange bool, premultiplied bool) func(x, y int, v []float64) {
+	return func(x, y int, v []float64) {
+		c := p.NRGBAF64At(x, y)
+		if useRange {
+			c.R = (c.R - min) / (max - min)
+			c.G = (c.G - min) / (max - min)
+			c.B = (c.B - min) / (max - min)
 		}
+		if premultiplied {
+			c.R, c.G, c.B = c.R*c.A, c.G*c.A, c.B*c.A
+		}
+		v[0], v[1], v[2], v[3] = c.R, c.G, c.B, c.A
 	}
 }
 
+// quantization returns the quantization of the image, taking the deprecated Precise flag into account.
+func (p *NRGBAF32) quantization() floatcolor.Quantization {
+	return p.Quantization.WithPrecise(p.Precise)
+}
+
 // PixOffset returns the index of the first element of Pix that corresponds to the pixel at (x, y).
 func (p *NRGBAF32) PixOffset(x, y int) int {
 	const channels = 4
 	return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*channels
 }
 
+// NRGBAF32At returns the color of the pixel at (x, y). Unlike At, it returns the concrete color type,
+// which avoids allocating a color.Color interface value and the type assertion on the caller side.
+func (p *NRGBAF32) NRGBAF32At(x, y int) floatcolor.NRGBAF32 {
+	if !(image.Point{X: x, Y: y}.In(p.Rect)) {
+		return floatcolor.NRGBAF32{}
+	}
+	i := p.PixOffset(x, y)
+	s := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+
+	return floatcolor.NRGBAF32{R: s[0], G: s[1], B: s[2], A: s[3], Precise: p.Precise, Quantization: p.Quantization}
+}
+
+// SetNRGBAF32 sets the pixel at (x, y) to c. Unlike Set, it stores the values as they are, without going through the color model.
+func (p *NRGBAF32) SetNRGBAF32(x, y int, c floatcolor.NRGBAF32) {
+	if !(image.Point{X: x, Y: y}.In(p.Rect)) {
+		return
+	}
+	i := p.PixOffset(x, y)
+	s := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+
+	s[0], s[1], s[2], s[3] = c.R, c.G, c.B, c.A
+}
+
+// NRGBAF64At returns the color of the pixel at (x, y) as a floatcolor.NRGBAF64.
+// It gives all float image types a common accessor that does not allocate.
+func (p *NRGBAF32) NRGBAF64At(x, y int) floatcolor.NRGBAF64 {
+	if !(image.Point{X: x, Y: y}.In(p.Rect)) {
+		return floatcolor.NRGBAF64{}
+	}
+	i := p.PixOffset(x, y)
+	s := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+
+	return floatcolor.NRGBAF64{R: float64(s[0]), G: float64(s[1]), B: float64(s[2]), A: float64(s[3]), Precise: p.Precise, Quantization: p.Quantization}
+}
+
+// SetNRGBAF64 sets the pixel at (x, y) to c.
+func (p *NRGBAF32) SetNRGBAF64(x, y int, c floatcolor.NRGBAF64) {
+	if !(image.Point{X: x, Y: y}.In(p.Rect)) {
+		return
+	}
+	i := p.PixOffset(x, y)
+	s := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+
+	s[0], s[1], s[2], s[3] = float32(c.R), float32(c.G), float32(c.B), float32(c.A)
+}
+
+// Row returns the part of Pix holding row y of the image, four values (red, green, blue, alpha) per pixel from Rect.Min.X to Rect.Max.X.
+// The returned slice shares its values with the image. It returns nil if y is outside the image bounds.
+func (p *NRGBAF32) Row(y int) []float32 {
+	if y < p.Rect.Min.Y || y >= p.Rect.Max.Y {
+		return nil
+	}
+	i := (y - p.Rect.Min.Y) * p.Stride
+	n := p.Rect.Dx() * 4
+
+	return p.Pix[i : i+n : i+n]
+}
+
 func (p *NRGBAF32) Set(x, y int, c color.Color) {
 	if !(image.Point{X: x, Y: y}.In(p.Rect)) {
 		return
@@ -170,10 +264,11 @@ func (p *NRGBAF32) SubImage(r image.Rectangle) image.Image {
 	}
 	i := p.PixOffset(r.Min.X, r.Min.Y)
 	return &NRGBAF32{
-		Pix:     p.Pix[i:],
-		Stride:  p.Stride,
-		Rect:    r,
-		Precise: p.Precise,
+		Pix:          p.Pix[i:],
+		Stride:       p.Stride,
+		Rect:         r,
+		Precise:      p.Precise,
+		Quantization: p.Quantization,
 	}
 }
 
@@ -185,13 +280,7 @@ func (p *NRGBAF32) Opaque() bool {
 
 	const channels = 4
 
-	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
-		for x := p.Rect.Min.X; x < p.Rect.Max.X; x++ {
-			if p.Pix[y*p.Stride+x*channels+(channels-1)] != 1.0 {
-				return false
-			}
-		}
-	}
-
-	return true
+	return allPixels(p.Rect, p.Stride, channels, func(i int) bool {
+		return p.Pix[i+(channels-1)] == 1.0
+	})
 }
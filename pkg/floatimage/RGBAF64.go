@@ -4,7 +4,6 @@ import (
 	"floatimage/pkg/floatcolor"
 	"image"
 	"image/color"
-	"image/draw"
 )
 
 // RGBAF64 is an in-memory image whose At method returns floatcolor.RGBAF64 values.
@@ -15,8 +14,19 @@ type RGBAF64 struct {
 	// Stride is the Pix stride (in bytes) between vertically adjacent pixels.
 	Stride int
 	// Rect is the image's bounds.
-	Rect    image.Rectangle
+	Rect image.Rectangle
+	// Precise rounds channel values to the nearest integer when converting to 8 and 16 bit images and colors.
+	//
+	// Deprecated: Precise is the same as the RoundHalfAwayFromZero quantization, set Quantization instead.
 	Precise bool
+	// Quantization is the strategy to convert channel values to 8 and 16 bit images and colors,
+	// including the dithering strategies for whole images.
+	Quantization floatcolor.Quantization
+	// Hidden is the side channel of the floatcolor.PreserveHiddenColor policy: the red, green, and blue values
+	// (with ordinary alpha) of the pixels with zero alpha, whose premultiplied values in Pix are zero.
+	// The hidden color of the pixel at (x, y) starts at Hidden[PixOffset(x, y)/4*3]. Hidden is nil as long as
+	// no hidden color was stored.
+	Hidden []float64
 }
 
 // NewRGBAF64 returns a new RGBAF64 image with the given dimensions.
@@ -40,6 +50,23 @@ func NewRGBAF64WithBounds(x0, y0, x1, y1 int) *RGBAF64 {
 	}
 }
 
+// NewRGBAF64Checked returns a new RGBAF64 image with the bounds r, like NewRGBAF64WithBounds, but returns a *DimensionError
+// instead of panicking if r has negative dimensions (r is not canonicalized), is too large to allocate, or has more pixels
+// than maxPixels. A maxPixels of zero applies the global budget of SetMaxPixels, a negative one no budget at all.
+func NewRGBAF64Checked(r image.Rectangle, maxPixels int) (*RGBAF64, error) {
+	const channels = 4
+	length, err := checkedBufferLength(channels, 8, r, maxPixels, "RGBAF64")
+	if err != nil {
+		return nil, err
+	}
+
+	return &RGBAF64{
+		Pix:    make([]float64, length),
+		Stride: channels * r.Dx(),
+		Rect:   r,
+	}, nil
+}
+
 func (p *RGBAF64) ColorModel() color.Model { return floatcolor.RGBAF64Model }
 
 func (p *RGBAF64) Bounds() image.Rectangle { return p.Rect }
@@ -51,7 +78,7 @@ func (p *RGBAF64) At(x, y int) color.Color {
 	i := p.PixOffset(x, y)
 	s := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
 
-	return floatcolor.RGBAF64{R: s[0], G: s[1], B: s[2], A: s[3], Precise: p.Precise}
+	return floatcolor.RGBAF64{R: s[0], G: s[1], B: s[2], A: s[3], Precise: p.Precise, Quantization: p.Quantization, Hidden: hiddenAt(p.Hidden, i)}
 }
 
 func (p *RGBAF64) RGBA64At(x, y int) color.RGBA64 {
@@ -61,74 +88,158 @@ func (p *RGBAF64) RGBA64At(x, y int) color.RGBA64 {
 	i := p.PixOffset(x, y)
 	s := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
 
-	r := uint16(clampF64(s[0]*0xffff, 0x0000, 0xffff, p.Precise))
-	g := uint16(clampF64(s[1]*0xffff, 0x0000, 0xffff, p.Precise))
-	b := uint16(clampF64(s[2]*0xffff, 0x0000, 0xffff, p.Precise))
-	a := uint16(clampF64(s[3]*0xffff, 0x0000, 0xffff, p.Precise))
-
-	return color.RGBA64{R: r, G: g, B: b, A: a}
+	return quantizeRGBA64(s[0], s[1], s[2], s[3], p.quantization(), x, y)
 }
 
 func (p *RGBAF64) AsRGBA() *image.RGBA {
-	rgbaImage := image.NewRGBA(p.Rect)
-	convertRGBAF64ToImage(p, rgbaImage, 0, 0, false, convColorRGBAF64toRGBA)
-	return rgbaImage
+	return exportRGBA(p, 0, 0, false)
 }
 
 func (p *RGBAF64) AsNRGBA() *image.NRGBA {
-	nrgbaImage := image.NewNRGBA(p.Rect)
-	convertRGBAF64ToImage(p, nrgbaImage, 0, 0, false, convColorRGBAF64toNRGBA)
-	return nrgbaImage
+	return exportNRGBA(p, 0, 0, false)
 }
 
 func (p *RGBAF64) AsRGBAForRange(min, max float64) *image.RGBA {
-	rgbaImage := image.NewRGBA(p.Rect)
-	convertRGBAF64ToImage(p, rgbaImage, min, max, true, convColorRGBAF64toRGBA)
-	return rgbaImage
+	return exportRGBA(p, min, max, true)
 }
 
 func (p *RGBAF64) AsNRGBAForRange(min, max float64) *image.NRGBA {
-	nrgbaImage := image.NewNRGBA(p.Rect)
-	convertRGBAF64ToImage(p, nrgbaImage, min, max, true, convColorRGBAF64toNRGBA)
-	return nrgbaImage
+	return exportNRGBA(p, min, max, true)
 }
 
-func convColorRGBAF64toNRGBA(convertableColor floatcolor.ConvertableColor) color.Color {
-	return convertableColor.AsNRGBA()
+func (p *RGBAF64) AsRGBA64() *image.RGBA64 {
+	return exportRGBA64(p, 0, 0, false)
 }
 
-func convColorRGBAF64toRGBA(convertableColor floatcolor.ConvertableColor) color.Color {
-	return convertableColor.AsRGBA()
+func (p *RGBAF64) AsNRGBA64() *image.NRGBA64 {
+	return exportNRGBA64(p, 0, 0, false)
 }
 
-func convertRGBAF64ToImage(source *RGBAF64, destination draw.Image, min, max float64, useRange bool, convColorFunc func(convertableColor floatcolor.ConvertableColor) color.Color) {
-	if useRange && (min > max) {
-		min, max = max, min
-	}
+func (p *RGBAF64) AsRGBA64ForRange(min, max float64) *image.RGBA64 {
+	return exportRGBA64(p, min, max, true)
+}
+
+func (p *RGBAF64) AsNRGBA64ForRange(min, max float64) *image.NRGBA64 {
+	return exportNRGBA64(p, min, max, true)
+}
+
+func (p *RGBAF64) AsGray() *image.Gray {
+	return exportGray(p, 0, 0, false)
+}
+
+func (p *RGBAF64) AsGray16() *image.Gray16 {
+	return exportGray16(p, 0, 0, false)
+}
+
+func (p *RGBAF64) AsGrayForRange(min, max float64) *image.Gray {
+	return exportGray(p, min, max, true)
+}
+
+func (p *RGBAF64) AsGray16ForRange(min, max float64) *image.Gray16 {
+	return exportGray16(p, min, max, true)
+}
+
+// exportValues returns the values function of quantizeImage for the conversion to images with premultiplied
+// or ordinary alpha, with the color values mapped from [min, max] to [0.0, 1.0] if useRange is set.
+func (p *RGBAF64) exportValues(min, max float64, useRange bool, premultiplied bool) func(x, y int, v []float64) {
+	return func(x, y int, v []float64) {
+		c := p.RGBAF64At(x, y)
+		if premultiplied && !useRange {
+			v[0], v[1], v[2], v[3] = c.R, c.G, c.B, c.A
+			return
+		}
 
-	for y := source.Rect.Min.Y; y < source.Rect.Max.Y; y++ {
-		for x := source.Rect.Min.X; x < source.Rect.Max.X; x++ {
-			c := source.At(x, y)
-			rgbaf64c := c.(floatcolor.RGBAF64)
-			f64c := c.(floatcolor.ConvertableColor)
-			if useRange {
-				alpha := rgbaf64c.A
-				alphaInv := 1.0 / alpha
-				rgbaf64c.R = ((rgbaf64c.R*alphaInv - min) / (max - min)) * alpha
-				rgbaf64c.G = ((rgbaf64c.G*alphaInv - min) / (max - min)) * alpha
-				rgbaf64c.B = ((rgbaf64c.B*alphaInv - min) / (max - min)) * alpha
-			}
-			destination.Set(x, y, convColorFunc(f64c))
+		n := unpremultiply(c.R, c.G, c.B, c.A, c.Hidden)
+		if useRange {
+			n.R = (n.R - min) / (max - min)
+			n.G = (n.G - min) / (max - min)
+			n.B = (n.B - min) / (max - min)
+		}
+		if premultiplied {
+			n.R, n.G, n.B = n.R*n.A, n.G*n.A, n.B*n.A
 		}
+		v[0], v[1], v[2], v[3] = n.R, n.G, n.B, n.A
 	}
 }
 
+// quantization returns the quantization of the image, taking the deprecated Precise flag into account.
+func (p *RGBAF64) quantization() floatcolor.Quantization {
+	return p.Quantization.WithPrecise(p.Precise)
+}
+
 // PixOffset returns the index of the first element of Pix that corresponds to the pixel at (x, y).
 func (p *RGBAF64) PixOffset(x, y int) int {
 	const channels = 4
 	return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*channels
 }
 
+// RGBAF64At returns the color of the pixel at (x, y). Unlike At, it returns the concrete color type,
+// which avoids allocating a color.Color interface value and the type assertion on the caller side.
+func (p *RGBAF64) RGBAF64At(x, y int) floatcolor.RGBAF64 {
+	if !(image.Point{X: x, Y: y}.In(p.Rect)) {
+		return floatcolor.RGBAF64{}
+	}
+	i := p.PixOffset(x, y)
+	s := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+
+	return floatcolor.RGBAF64{R: s[0], G: s[1], B: s[2], A: s[3], Precise: p.Precise, Quantization: p.Quantization, Hidden: hiddenAt(p.Hidden, i)}
+}
+
+// SetRGBAF64 sets the pixel at (x, y) to c. Unlike Set, it stores the values as they are, without going through the color model.
+func (p *RGBAF64) SetRGBAF64(x, y int, c floatcolor.RGBAF64) {
+	if !(image.Point{X: x, Y: y}.In(p.Rect)) {
+		return
+	}
+	i := p.PixOffset(x, y)
+	s := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+
+	s[0], s[1], s[2], s[3] = c.R, c.G, c.B, c.A
+	setHidden(&p.Hidden, p.Pix, i, c.Hidden)
+}
+
+// NRGBAF64At returns the color of the pixel at (x, y) as a floatcolor.NRGBAF64, with ordinary alpha.
+// It gives all float image types a common accessor that does not allocate.
+// Pixels with zero alpha are returned following the zero alpha policy (see floatcolor.SetZeroAlphaPolicy).
+func (p *RGBAF64) NRGBAF64At(x, y int) floatcolor.NRGBAF64 {
+	if !(image.Point{X: x, Y: y}.In(p.Rect)) {
+		return floatcolor.NRGBAF64{}
+	}
+	i := p.PixOffset(x, y)
+	s := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+
+	c := unpremultiply(s[0], s[1], s[2], s[3], hiddenAt(p.Hidden, i))
+	c.Precise = p.Precise
+	c.Quantization = p.Quantization
+
+	return c
+}
+
+// SetNRGBAF64 sets the pixel at (x, y) to c, premultiplied by its alpha (see floatcolor.PremultiplyHidden).
+func (p *RGBAF64) SetNRGBAF64(x, y int, c floatcolor.NRGBAF64) {
+	if !(image.Point{X: x, Y: y}.In(p.Rect)) {
+		return
+	}
+	i := p.PixOffset(x, y)
+	s := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+
+	var hidden [3]float64
+	s[0], s[1], s[2], hidden = floatcolor.PremultiplyHidden(c.R, c.G, c.B, c.A)
+	s[3] = c.A
+	setHidden(&p.Hidden, p.Pix, i, hidden)
+}
+
+// Row returns the part of Pix holding row y of the image, four values (red, green, blue, alpha) per pixel from Rect.Min.X to Rect.Max.X.
+// The returned slice shares its values with the image. It returns nil if y is outside the image bounds.
+func (p *RGBAF64) Row(y int) []float64 {
+	if y < p.Rect.Min.Y || y >= p.Rect.Max.Y {
+		return nil
+	}
+	i := (y - p.Rect.Min.Y) * p.Stride
+	n := p.Rect.Dx() * 4
+
+	return p.Pix[i : i+n : i+n]
+}
+
 func (p *RGBAF64) Set(x, y int, c color.Color) {
 	if !(image.Point{X: x, Y: y}.In(p.Rect)) {
 		return
@@ -142,6 +253,7 @@ func (p *RGBAF64) Set(x, y int, c color.Color) {
 	s[1] = c1.G
 	s[2] = c1.B
 	s[3] = c1.A
+	setHidden(&p.Hidden, p.Pix, i, c1.Hidden)
 }
 
 func (p *RGBAF64) SetRGBA64(x, y int, c color.RGBA64) {
@@ -157,6 +269,7 @@ func (p *RGBAF64) SetRGBA64(x, y int, c color.RGBA64) {
 	s[1] = c1.G
 	s[2] = c1.B
 	s[3] = c1.A
+	setHidden(&p.Hidden, p.Pix, i, c1.Hidden)
 }
 
 // SubImage returns an image representing the portion of the image p visible through r.
@@ -171,10 +284,12 @@ func (p *RGBAF64) SubImage(r image.Rectangle) image.Image {
 	}
 	i := p.PixOffset(r.Min.X, r.Min.Y)
 	return &RGBAF64{
-		Pix:     p.Pix[i:],
-		Stride:  p.Stride,
-		Rect:    r,
-		Precise: p.Precise,
+		Pix:          p.Pix[i:],
+		Stride:       p.Stride,
+		Rect:         r,
+		Precise:      p.Precise,
+		Quantization: p.Quantization,
+		Hidden:       subHidden(p.Hidden, i),
 	}
 }
 
@@ -186,13 +301,7 @@ func (p *RGBAF64) Opaque() bool {
 
 	const channels = 4
 
-	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
-		for x := p.Rect.Min.X; x < p.Rect.Max.X; x++ {
-			if p.Pix[y*p.Stride+x*channels+(channels-1)] != 1.0 {
-				return false
-			}
-		}
-	}
-
-	return true
+	return allPixels(p.Rect, p.Stride, channels, func(i int) bool {
+		return p.Pix[i+(channels-1)] == 1.0
+	})
 }
@@ -0,0 +1,191 @@
+package floatimage
+
+import (
+	"floatimage/pkg/floatcolor"
+	"image"
+	"image/draw"
+	"testing"
+)
+
+// accessorImage is implemented by all float image types.
+type accessorImage interface {
+	FloatImage
+	NRGBAF64At(x, y int) floatcolor.NRGBAF64
+	SetNRGBAF64(x, y int, c floatcolor.NRGBAF64)
+}
+
+func accessorImages() map[string]accessorImage {
+	return map[string]accessorImage{
+		"NRGBAF64": NewNRGBAF64WithBounds(-3, 2, 5, 6),
+		"NRGBAF32": NewNRGBAF32WithBounds(-3, 2, 5, 6),
+		"RGBAF64":  NewRGBAF64WithBounds(-3, 2, 5, 6),
+		"RGBAF32":  NewRGBAF32WithBounds(-3, 2, 5, 6),
+		"GrayF64":  NewGrayF64WithBounds(-3, 2, 5, 6),
+	}
+}
+
+func TestTypedAccessors(t *testing.T) {
+	c := floatcolor.NRGBAF64{R: 0.25, G: 0.5, B: 0.75, A: 0.5}
+
+	for name, img := range accessorImages() {
+		img.SetNRGBAF64(1, 3, c)
+		img.SetNRGBAF64(100, 3, c) // Outside, ignored
+
+		expected := floatcolor.NRGBAF64Model.Convert(img.At(1, 3)).(floatcolor.NRGBAF64)
+		if got := img.NRGBAF64At(1, 3); got != expected {
+			t.Errorf("%s: expected NRGBAF64At to match At with %+v but got %+v", name, expected, got)
+		}
+
+		other := NewLike(img, img.Bounds()).(draw.Image)
+		other.Set(1, 3, c)
+		if img.At(1, 3) != other.At(1, 3) {
+			t.Errorf("%s: expected SetNRGBAF64 to store %+v like Set but got %+v", name, other.At(1, 3), img.At(1, 3))
+		}
+
+		if got := img.NRGBAF64At(100, 3); got != (floatcolor.NRGBAF64{}) {
+			t.Errorf("%s: expected zero color outside the bounds but got %+v", name, got)
+		}
+	}
+
+	nrgbaf32 := NewNRGBAF32(2, 2)
+	nrgbaf32.SetNRGBAF32(1, 1, floatcolor.NRGBAF32{R: 2.5, A: 0.0})
+	if got := nrgbaf32.NRGBAF32At(1, 1); got.R != 2.5 {
+		t.Errorf("expected typed accessors to keep hidden color values but got %+v", got)
+	}
+
+	rgbaf64 := NewRGBAF64(2, 2)
+	rgbaf64.Precise = true
+	rgbaf64.SetRGBAF64(0, 1, floatcolor.RGBAF64{R: 0.5, A: 0.5})
+	if got := rgbaf64.RGBAF64At(0, 1); got != (floatcolor.RGBAF64{R: 0.5, A: 0.5, Precise: true}) {
+		t.Errorf("expected premultiplied color with the Precise setting of the image but got %+v", got)
+	}
+
+	gray := NewGrayF64(2, 2)
+	gray.SetGrayF64(1, 0, floatcolor.GrayF64{Y: 0.3})
+	if got := gray.GrayF64At(1, 0); got.Y != 0.3 {
+		t.Errorf("expected gray value 0.3 but got %+v", got)
+	}
+}
+
+func TestRow(t *testing.T) {
+	img := NewRGBAF32WithBounds(-2, -2, 6, 4)
+	for i := range img.Pix {
+		img.Pix[i] = float32(i)
+	}
+
+	sub := img.SubImage(image.Rect(0, 1, 3, 3)).(*RGBAF32)
+	row := sub.Row(2)
+	if len(row) != 3*4 || cap(row) != 3*4 {
+		t.Fatalf("expected row of 3 pixels but got length %d and capacity %d", len(row), cap(row))
+	}
+	if row[0] != img.Pix[img.PixOffset(0, 2)] || row[11] != img.Pix[img.PixOffset(2, 2)+3] {
+		t.Errorf("expected row to start at (0, 2) and end at (2, 2)")
+	}
+	if sub.Row(0) != nil || sub.Row(3) != nil {
+		t.Errorf("expected no rows outside the bounds")
+	}
+
+	gray := NewGrayF64WithBounds(1, 1, 4, 3)
+	gray.Row(2)[2] = 7
+	if gray.At(3, 2).(floatcolor.GrayF64).Y != 7 {
+		t.Errorf("expected row to share its values with the image")
+	}
+}
+
+func TestAsNRGBAForRange(t *testing.T) {
+	nrgbaf64 := NewNRGBAF64(1, 1)
+	nrgbaf64.SetNRGBAF64(0, 0, floatcolor.NRGBAF64{R: 1.0, G: 2.0, B: 0.0, A: 1.0})
+	if c := nrgbaf64.AsNRGBAForRange(0, 2).NRGBAAt(0, 0); c.R != 127 || c.G != 255 || c.B != 0 {
+		t.Errorf("expected values mapped from [0, 2] but got %+v", c)
+	}
+
+	rgbaf32 := NewRGBAF32(2, 1)
+	rgbaf32.SetNRGBAF64(0, 0, floatcolor.NRGBAF64{R: 1.0, G: 2.0, B: 0.0, A: 0.5})
+	if c := rgbaf32.AsNRGBAForRange(0, 2).NRGBAAt(0, 0); c.R != 127 || c.G != 255 || c.A != 127 {
+		t.Errorf("expected premultiplied values mapped from [0, 2] but got %+v", c)
+	}
+	if c := rgbaf32.AsRGBAForRange(0, 2).RGBAAt(1, 0); c.R != 0 || c.A != 0 {
+		t.Errorf("expected transparent pixel to stay transparent but got %+v", c)
+	}
+
+	gray := NewGrayF64(1, 1)
+	gray.Pix[0] = -1
+	if c := gray.AsRGBAForRange(1, -1).RGBAAt(0, 0); c.R != 0 || c.A != 255 {
+		t.Errorf("expected minimum of the range to map to black but got %+v", c)
+	}
+}
+
+func TestTypedAccessorsDoNotAllocate(t *testing.T) {
+	img := NewNRGBAF32(8, 8)
+	allocs := testing.AllocsPerRun(100, func() {
+		c := img.NRGBAF64At(3, 4)
+		img.SetNRGBAF64(4, 3, c)
+		img.SetNRGBAF32(5, 5, img.NRGBAF32At(2, 2))
+		_ = img.Row(6)
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations but got %v", allocs)
+	}
+
+	// The number of allocations of a conversion does not depend on the number of pixels.
+	defer SetWorkers(0)
+	SetWorkers(1)
+	small, large := NewRGBAF64(4, 4), NewRGBAF64(64, 64)
+	smallAllocs := testing.AllocsPerRun(10, func() { small.AsNRGBA() })
+	largeAllocs := testing.AllocsPerRun(10, func() { large.AsNRGBA() })
+	if smallAllocs != largeAllocs {
+		t.Errorf("expected the same number of allocations for small and large images but got %v and %v", smallAllocs, largeAllocs)
+	}
+}
+
+func BenchmarkAt(b *testing.B) {
+	img := NewRGBAF64(64, 64)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		_ = img.At(n&63, (n>>6)&63).(floatcolor.RGBAF64)
+	}
+}
+
+func BenchmarkRGBAF64At(b *testing.B) {
+	img := NewRGBAF64(64, 64)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		_ = img.RGBAF64At(n&63, (n>>6)&63)
+	}
+}
+
+func BenchmarkNRGBAF64At(b *testing.B) {
+	img := NewRGBAF32(64, 64)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		_ = img.NRGBAF64At(n&63, (n>>6)&63)
+	}
+}
+
+func BenchmarkSetNRGBAF64(b *testing.B) {
+	img := NewRGBAF32(64, 64)
+	c := floatcolor.NRGBAF64{R: 0.5, G: 0.25, B: 1.0, A: 0.5}
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		img.SetNRGBAF64(n&63, (n>>6)&63, c)
+	}
+}
+
+func BenchmarkAsNRGBA(b *testing.B) {
+	img := NewNRGBAF64(256, 256)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		img.AsNRGBA()
+	}
+}
+
+func BenchmarkRGBAF32AsNRGBA(b *testing.B) {
+	img := NewRGBAF32(1024, 1024)
+	for i := range img.Pix {
+		img.Pix[i] = float32(i%7) / 7
+	}
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		img.AsNRGBA()
+	}
+}
@@ -0,0 +1,249 @@
+package floatimage
+
+import (
+	"floatimage/pkg/floatcolor"
+	"image/color"
+	"math"
+	"testing"
+)
+
+var zeroAlphaPolicies = []floatcolor.ZeroAlphaPolicy{floatcolor.ZeroColor, floatcolor.PreserveHiddenColor, floatcolor.EpsilonClamp}
+
+// withZeroAlphaPolicy runs f with the zero alpha policy set, restoring the previous policy afterwards.
+func withZeroAlphaPolicy(policy floatcolor.ZeroAlphaPolicy, f func()) {
+	previous, previousEpsilon := floatcolor.CurrentZeroAlphaPolicy()
+	defer floatcolor.SetZeroAlphaPolicy(previous, previousEpsilon)
+	floatcolor.SetZeroAlphaPolicy(policy, 0)
+	f()
+}
+
+// hiddenColorImage returns an image with ordinary alpha whose pixels have zero, tiny and ordinary alpha values,
+// all with the color (0.25, 0.5, 1.0).
+func hiddenColorImage() *NRGBAF64 {
+	alphas := []float64{0, 1e-30, 1e-9, 1e-7, 1e-6, 2e-6, 1.0 / 0xffff, 0.5, 1.0}
+	img := NewNRGBAF64(len(alphas), 1)
+	for x, a := range alphas {
+		img.SetNRGBAF64(x, 0, floatcolor.NRGBAF64{R: 0.25, G: 0.5, B: 1.0, A: a})
+	}
+	return img
+}
+
+func finiteValues[T float32 | float64](values []T) bool {
+	for _, v := range values {
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestZeroAlphaConversions(t *testing.T) {
+	for _, policy := range zeroAlphaPolicies {
+		withZeroAlphaPolicy(policy, func() {
+			img := hiddenColorImage()
+			rgba := ToRGBAF64(img)
+
+			for _, like := range []FloatImage{NewNRGBAF64(0, 0), NewNRGBAF32(0, 0), NewRGBAF32(0, 0), NewGrayF64(0, 0)} {
+				result := FromRGBAF64(rgba, like)
+				switch p := result.(type) {
+				case *NRGBAF64:
+					if !finiteValues(p.Pix) {
+						t.Fatalf("policy %d: expected finite values but got %v", policy, p.Pix)
+					}
+				case *NRGBAF32:
+					if !finiteValues(p.Pix) {
+						t.Fatalf("policy %d: expected finite values but got %v", policy, p.Pix)
+					}
+				}
+
+				for x := 0; x < img.Rect.Dx(); x++ {
+					c := result.(interface {
+						NRGBAF64At(x, y int) floatcolor.NRGBAF64
+					}).NRGBAF64At(x, 0)
+					if math.IsNaN(c.R) || math.IsInf(c.R, 0) || c.R > 1.0+1e-6 {
+						t.Fatalf("policy %d: expected valid color at %d but got %+v", policy, x, c)
+					}
+				}
+			}
+
+			// Conversions to 8 and 16 bit images
+			for _, f := range []FloatImage{rgba, FromRGBAF64(rgba, NewRGBAF32(0, 0))} {
+				nrgba, ranged := f.AsNRGBA(), f.AsNRGBAForRange(0, 2)
+				if c := nrgba.NRGBAAt(0, 0); policy == floatcolor.PreserveHiddenColor && c != (color.NRGBA{R: 0x3f, G: 0x7f, B: 0xff}) ||
+					policy != floatcolor.PreserveHiddenColor && c != (color.NRGBA{}) {
+					t.Errorf("policy %d: expected zero alpha pixel following the policy but got %+v", policy, c)
+				}
+				if c := ranged.NRGBAAt(8, 0); c != (color.NRGBA{R: 0x1f, G: 0x3f, B: 0x7f, A: 0xff}) {
+					t.Errorf("policy %d: expected ranged opaque pixel but got %+v", policy, c)
+				}
+				if c := f.AsRGBA().RGBAAt(0, 0); c != (color.RGBA{}) {
+					t.Errorf("policy %d: expected transparent black but got %+v", policy, c)
+				}
+				if c := f.(interface{ RGBA64At(x, y int) color.RGBA64 }).RGBA64At(0, 0); c != (color.RGBA64{}) {
+					t.Errorf("policy %d: expected transparent black but got %+v", policy, c)
+				}
+			}
+		})
+	}
+}
+
+func TestZeroAlphaColor(t *testing.T) {
+	for _, policy := range zeroAlphaPolicies {
+		withZeroAlphaPolicy(policy, func() {
+			expected := floatcolor.NRGBAF64{}
+			if policy == floatcolor.PreserveHiddenColor {
+				expected = floatcolor.NRGBAF64{R: 0.25, G: 0.5, B: 1.0}
+			}
+
+			img := hiddenColorImage()
+			if c := FromRGBAF64(ToRGBAF64(img), img).(*NRGBAF64).NRGBAF64At(0, 0); c != expected {
+				t.Errorf("policy %d: expected %+v but got %+v", policy, expected, c)
+			}
+
+			// The premultiplied values of pixels with zero alpha are zero, their color is kept in the hidden plane only
+			rgba := img.Premultiply()
+			if s := rgba.Pix[0:4]; s[0] != 0 || s[1] != 0 || s[2] != 0 || s[3] != 0 {
+				t.Errorf("policy %d: expected zero premultiplied values but got %v", policy, s)
+			}
+			if c := rgba.NRGBAF64At(0, 0); c != expected {
+				t.Errorf("policy %d: expected %+v but got %+v", policy, expected, c)
+			}
+
+			rgbaf32 := NewRGBAF32(1, 1)
+			rgbaf32.SetNRGBAF64(0, 0, floatcolor.NRGBAF64{R: 0.25, G: 0.5, B: 1.0})
+			if s := rgbaf32.Pix[0:4]; s[0] != 0 || s[1] != 0 || s[2] != 0 || s[3] != 0 {
+				t.Errorf("policy %d: expected zero premultiplied values but got %v", policy, s)
+			}
+			if policy != floatcolor.PreserveHiddenColor && rgbaf32.Hidden != nil {
+				t.Errorf("policy %d: expected no hidden plane but got %v", policy, rgbaf32.Hidden)
+			}
+			if c := rgbaf32.NRGBAF64At(0, 0); c != expected {
+				t.Errorf("policy %d: expected %+v but got %+v", policy, expected, c)
+			}
+		})
+	}
+}
+
+func TestPreserveHiddenColor(t *testing.T) {
+	withZeroAlphaPolicy(floatcolor.PreserveHiddenColor, func() {
+		hidden := floatcolor.NRGBAF64{R: 0.25, G: 0.5, B: 1.0}
+		img := hiddenColorImage()
+		for _, rgba := range []FloatImage{ToRGBAF64(img), FromRGBAF64(ToRGBAF64(img), NewRGBAF32(0, 0))} {
+			for _, like := range []FloatImage{NewNRGBAF64(0, 0), NewNRGBAF32(0, 0)} {
+				result := FromRGBAF64(ToRGBAF64(rgba), like).(interface {
+					NRGBAF64At(x, y int) floatcolor.NRGBAF64
+				})
+				if c := result.NRGBAF64At(0, 0); c != hidden {
+					t.Errorf("expected hidden color to survive conversions of %T to %T but got %+v", rgba, like, c)
+				}
+
+				// Tiny alpha values are divided by and keep no hidden color
+				for x := 1; x < img.Rect.Dx(); x++ {
+					if c := result.NRGBAF64At(x, 0); math.Abs(c.B-1.0) > 1e-3 || c.A == 0 {
+						t.Errorf("expected color with alpha %v at %d for %T but got %+v", img.NRGBAF64At(x, 0).A, x, like, c)
+					}
+				}
+			}
+
+			// Filtering and compositing see transparent black
+			if r, g, b, a := rgba.At(0, 0).RGBA(); r != 0 || g != 0 || b != 0 || a != 0 {
+				t.Errorf("expected transparent RGBA for %T but got %d, %d, %d, %d", rgba, r, g, b, a)
+			}
+		}
+
+		rgba := ToRGBAF64(img)
+		for x := 1; x < img.Rect.Dx(); x++ {
+			if h := rgba.RGBAF64At(x, 0).Hidden; h != [3]float64{} {
+				t.Errorf("expected no hidden color for alpha %v but got %v", img.NRGBAF64At(x, 0).A, h)
+			}
+		}
+		if c := rgba.SubImage(rgba.Rect).(*RGBAF64).NRGBAF64At(0, 0); c != hidden {
+			t.Errorf("expected hidden color in sub image but got %+v", c)
+		}
+		if c := FromRGBAF64WithPolicy(rgba, img, floatcolor.ZeroColor, 0).(*NRGBAF64).NRGBAF64At(0, 0); c != (floatcolor.NRGBAF64{}) {
+			t.Errorf("expected the given policy to ignore the hidden color but got %+v", c)
+		}
+		if c := img.Premultiply().Unpremultiply(DefaultAlphaEpsilon).NRGBAF64At(0, 0); c != hidden {
+			t.Errorf("expected hidden color to survive in place conversions but got %+v", c)
+		}
+
+		rgbaf32 := NewRGBAF32(1, 1)
+		rgbaf32.SetNRGBAF64(0, 0, hidden)
+		Map(rgbaf32, func(c floatcolor.NRGBAF64) floatcolor.NRGBAF64 { return c })
+		if c := rgbaf32.NRGBAF64At(0, 0); c != hidden {
+			t.Errorf("expected hidden color to survive Map but got %+v", c)
+		}
+		ProcessTiles(rgbaf32, 1, 1, 0, func(tile FloatImage) {})
+		if c := rgbaf32.NRGBAF64At(0, 0); c != hidden {
+			t.Errorf("expected hidden color to survive ProcessTiles but got %+v", c)
+		}
+
+		// Making a hidden color visible again
+		Map(rgbaf32, func(c floatcolor.NRGBAF64) floatcolor.NRGBAF64 {
+			c.A = 0.5
+			return c
+		})
+		if c := rgbaf32.RGBAF32At(0, 0); c != (floatcolor.RGBAF32{R: 0.125, G: 0.25, B: 0.5, A: 0.5}) {
+			t.Errorf("expected the hidden color with new alpha but got %+v", c)
+		}
+	})
+}
+
+func TestZeroAlphaPolicyPerCallHidden(t *testing.T) {
+	img := hiddenColorImage()
+	var rgba *RGBAF64
+	withZeroAlphaPolicy(floatcolor.PreserveHiddenColor, func() { rgba = ToRGBAF64(img) })
+
+	withZeroAlphaPolicy(floatcolor.ZeroColor, func() {
+		if c := FromRGBAF64(rgba, img).(*NRGBAF64).NRGBAF64At(0, 0); c != (floatcolor.NRGBAF64{}) {
+			t.Errorf("expected the current policy to ignore the hidden color but got %+v", c)
+		}
+		if c := FromRGBAF64WithPolicy(rgba, img, floatcolor.PreserveHiddenColor, 0).(*NRGBAF64).NRGBAF64At(0, 0); c != (floatcolor.NRGBAF64{R: 0.25, G: 0.5, B: 1.0}) {
+			t.Errorf("expected the given policy to keep the hidden color but got %+v", c)
+		}
+	})
+}
+
+func TestZeroAlphaPolicyPerCall(t *testing.T) {
+	withZeroAlphaPolicy(floatcolor.ZeroColor, func() {
+		img := NewRGBAF32(1, 1)
+		img.SetRGBAF32(0, 0, floatcolor.RGBAF32{R: 3e-9, G: 1e-9, B: 0, A: 1e-9})
+		rgba := ToRGBAF64(img)
+
+		if c := FromRGBAF64(rgba, NewNRGBAF64(0, 0)).(*NRGBAF64).NRGBAF64At(0, 0); math.Abs(c.R-3.0) > 1e-6 {
+			t.Errorf("expected the current policy to divide by alpha but got %+v", c)
+		}
+		for _, like := range []FloatImage{NewNRGBAF64(0, 0), NewNRGBAF32(0, 0)} {
+			c := FromRGBAF64WithPolicy(rgba, like, floatcolor.EpsilonClamp, 0).(interface {
+				NRGBAF64At(x, y int) floatcolor.NRGBAF64
+			}).NRGBAF64At(0, 0)
+			if c.R > 1.0 || !(c.R > 0) {
+				t.Errorf("expected the given policy to clamp alpha for %T but got %+v", like, c)
+			}
+		}
+
+		dst := NewNRGBAF64(1, 1)
+		CopyFromRGBAF64WithPolicy(dst, rgba, floatcolor.EpsilonClamp, 1e-3)
+		if c := dst.NRGBAF64At(0, 0); math.Abs(c.R-3e-6) > 1e-9 {
+			t.Errorf("expected alpha to be clamped to the given epsilon but got %+v", c)
+		}
+	})
+}
+
+func TestEpsilonClamp(t *testing.T) {
+	withZeroAlphaPolicy(floatcolor.EpsilonClamp, func() {
+		// Premultiplied values with float32 rounding errors at a tiny alpha
+		img := NewRGBAF32(2, 1)
+		img.SetRGBAF32(0, 0, floatcolor.RGBAF32{R: 3e-9, G: 1e-9, B: 0, A: 1e-9})
+		img.SetRGBAF32(1, 0, floatcolor.RGBAF32{R: 0.25, G: 0.5, B: 1.0, A: 1.0})
+
+		for _, c := range []floatcolor.NRGBAF64{img.NRGBAF64At(0, 0), FromRGBAF64(ToRGBAF64(img), NewNRGBAF64(0, 0)).(*NRGBAF64).NRGBAF64At(0, 0)} {
+			if c.R > 1.0 || c.G > 1.0 || !(c.R > 0) {
+				t.Errorf("expected clamped color values but got %+v", c)
+			}
+		}
+		if c := img.NRGBAF64At(1, 0); c.R != 0.25 || c.A != 1.0 {
+			t.Errorf("expected ordinary alpha to be divided by but got %+v", c)
+		}
+	})
+}
@@ -0,0 +1,243 @@
+package floatimage
+
+import (
+	"floatimage/pkg/floatcolor"
+	"image"
+	"image/draw"
+)
+
+// NewLike returns a new, zeroed float image of the same type as img (and with the same quantization) with the given bounds.
+// Tiled images give a tiled image with the same tile size and type of tiles. Channel views of MultiChannelF32 images
+// give an NRGBAF32 or RGBAF32 image (the type of their color model), as they need an image of named channels to view.
+// Images that are not one of the float image types of this package give an RGBAF64 image.
+func NewLike(img image.Image, r image.Rectangle) FloatImage {
+	switch p := img.(type) {
+	case *NRGBAF64:
+		n := NewNRGBAF64WithBounds(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+		n.Precise = p.Precise
+		n.Quantization = p.Quantization
+		return n
+	case *NRGBAF32:
+		n := NewNRGBAF32WithBounds(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+		n.Precise = p.Precise
+		n.Quantization = p.Quantization
+		return n
+	case *RGBAF64:
+		n := NewRGBAF64WithBounds(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+		n.Precise = p.Precise
+		n.Quantization = p.Quantization
+		return n
+	case *RGBAF32:
+		n := NewRGBAF32WithBounds(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+		n.Precise = p.Precise
+		n.Quantization = p.Quantization
+		return n
+	case *GrayF64:
+		n := NewGrayF64WithBounds(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+		n.Precise = p.Precise
+		n.Quantization = p.Quantization
+		return n
+	case *Tiled:
+		return NewTiled(p.prototype, r, p.TileWidth, p.TileHeight)
+	case *ChannelView:
+		if p.premultiplied {
+			n := NewRGBAF32WithBounds(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+			n.Precise = p.image.Precise
+			n.Quantization = p.image.Quantization
+			return n
+		}
+		n := NewNRGBAF32WithBounds(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+		n.Precise = p.image.Precise
+		n.Quantization = p.image.Quantization
+		return n
+	default:
+		return NewRGBAF64WithBounds(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+	}
+}
+
+// ToRGBAF64 returns a copy of the image as an RGBAF64 image (premultiplied alpha, float64 values) with the same bounds.
+// It is the common working format for image operations, as premultiplied values can be filtered and interpolated directly.
+// Float images keep their full value range (and premultiplied images their hidden colors, see
+// floatcolor.PreserveHiddenColor), other images are converted through their RGBA method.
+func ToRGBAF64(img image.Image) *RGBAF64 {
+	r := img.Bounds()
+	result := NewRGBAF64WithBounds(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+
+	switch p := img.(type) {
+	case *RGBAF64:
+		result.Precise = p.Precise
+		result.Quantization = p.Quantization
+		ParallelFor(r.Dy(), 0, func(start, end int) {
+			for y := start; y < end; y++ {
+				copy(result.Pix[y*result.Stride:y*result.Stride+4*r.Dx()], p.Pix[y*p.Stride:])
+			}
+		})
+		if p.Hidden != nil {
+			result.Hidden = make([]float64, len(result.Pix)/4*3)
+			forEachPixelPair(r, result.Stride, p.Stride, 4, 4, func(i, j int) {
+				storeHidden(result.Hidden, i, hiddenAt(p.Hidden, j))
+			})
+		}
+	case *RGBAF32:
+		result.Precise = p.Precise
+		result.Quantization = p.Quantization
+		if p.Hidden != nil {
+			result.Hidden = make([]float64, len(result.Pix)/4*3)
+		}
+		forEachPixelPair(r, result.Stride, p.Stride, 4, 4, func(i, j int) {
+			d, s := result.Pix[i:i+4:i+4], p.Pix[j:j+4:j+4]
+			d[0], d[1], d[2], d[3] = float64(s[0]), float64(s[1]), float64(s[2]), float64(s[3])
+			storeHidden(result.Hidden, i, hiddenF64(hiddenAt(p.Hidden, j)))
+		})
+	case *NRGBAF64:
+		result.Precise = p.Precise
+		result.Quantization = p.Quantization
+		hidden := keepHidden(&result.Hidden, result.Pix)
+		forEachPixelPair(r, result.Stride, p.Stride, 4, 4, func(i, j int) {
+			d, s := result.Pix[i:i+4:i+4], p.Pix[j:j+4:j+4]
+			var h [3]float64
+			d[0], d[1], d[2], h = floatcolor.PremultiplyHidden(s[0], s[1], s[2], s[3])
+			d[3] = s[3]
+			storeHidden(hidden, i, h)
+		})
+	case *NRGBAF32:
+		result.Precise = p.Precise
+		result.Quantization = p.Quantization
+		hidden := keepHidden(&result.Hidden, result.Pix)
+		forEachPixelPair(r, result.Stride, p.Stride, 4, 4, func(i, j int) {
+			d, s := result.Pix[i:i+4:i+4], p.Pix[j:j+4:j+4]
+			a := float64(s[3])
+			var h [3]float64
+			d[0], d[1], d[2], h = floatcolor.PremultiplyHidden(float64(s[0]), float64(s[1]), float64(s[2]), a)
+			d[3] = a
+			storeHidden(hidden, i, h)
+		})
+	case *GrayF64:
+		result.Precise = p.Precise
+		result.Quantization = p.Quantization
+		forEachPixelPair(r, result.Stride, p.Stride, 4, 1, func(i, j int) {
+			d := result.Pix[i : i+4 : i+4]
+			d[0], d[1], d[2], d[3] = p.Pix[j], p.Pix[j], p.Pix[j], 1.0
+		})
+	default:
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				result.Set(x, y, img.At(x, y))
+			}
+		}
+	}
+
+	return result
+}
+
+// CopyFromRGBAF64 copies the pixels of src into dst, where their bounds intersect.
+// Float images keep the full value range (and premultiplied images the hidden colors of src, see
+// floatcolor.PreserveHiddenColor), other images are set through their color model.
+// Pixels are converted to ordinary alpha following the zero alpha policy (see floatcolor.SetZeroAlphaPolicy).
+func CopyFromRGBAF64(dst draw.Image, src *RGBAF64) {
+	policy, epsilon := floatcolor.CurrentZeroAlphaPolicy()
+	CopyFromRGBAF64WithPolicy(dst, src, policy, epsilon)
+}
+
+// CopyFromRGBAF64WithPolicy is like CopyFromRGBAF64, but converts the pixels of float images with ordinary alpha
+// following the given zero alpha policy and alpha epsilon instead of the ones set by floatcolor.SetZeroAlphaPolicy.
+// Other images are still set through their color model.
+func CopyFromRGBAF64WithPolicy(dst draw.Image, src *RGBAF64, policy floatcolor.ZeroAlphaPolicy, epsilon float64) {
+	r := dst.Bounds().Intersect(src.Rect)
+	if r.Empty() {
+		return
+	}
+
+	srcOffset := src.PixOffset(r.Min.X, r.Min.Y)
+	s, hidden := src.Pix[srcOffset:], subHidden(src.Hidden, srcOffset)
+
+	switch p := dst.(type) {
+	case *RGBAF64:
+		i := p.PixOffset(r.Min.X, r.Min.Y)
+		ParallelFor(r.Dy(), 0, func(start, end int) {
+			for y := start; y < end; y++ {
+				copy(p.Pix[i+y*p.Stride:i+y*p.Stride+4*r.Dx()], s[y*src.Stride:])
+			}
+		})
+		if hidden != nil && p.Hidden == nil {
+			p.Hidden = make([]float64, len(p.Pix)/4*3)
+		}
+		if p.Hidden != nil {
+			d := subHidden(p.Hidden, i)
+			forEachPixelPair(r, p.Stride, src.Stride, 4, 4, func(i, j int) {
+				storeHidden(d, i, hiddenAt(hidden, j))
+			})
+		}
+	case *RGBAF32:
+		i := p.PixOffset(r.Min.X, r.Min.Y)
+		if hidden != nil && p.Hidden == nil {
+			p.Hidden = make([]float32, len(p.Pix)/4*3)
+		}
+		d, dh := p.Pix[i:], subHidden(p.Hidden, i)
+		forEachPixelPair(r, p.Stride, src.Stride, 4, 4, func(i, j int) {
+			d[i], d[i+1], d[i+2], d[i+3] = float32(s[j]), float32(s[j+1]), float32(s[j+2]), float32(s[j+3])
+			storeHidden(dh, i, hiddenOf[float32](hiddenAt(hidden, j)))
+		})
+	case *NRGBAF64:
+		d := p.Pix[p.PixOffset(r.Min.X, r.Min.Y):]
+		forEachPixelPair(r, p.Stride, src.Stride, 4, 4, func(i, j int) {
+			d[i], d[i+1], d[i+2] = floatcolor.UnpremultiplyWithPolicy(s[j], s[j+1], s[j+2], s[j+3], hiddenAt(hidden, j), policy, epsilon)
+			d[i+3] = s[j+3]
+		})
+	case *NRGBAF32:
+		d := p.Pix[p.PixOffset(r.Min.X, r.Min.Y):]
+		forEachPixelPair(r, p.Stride, src.Stride, 4, 4, func(i, j int) {
+			red, green, blue := floatcolor.UnpremultiplyWithPolicy(s[j], s[j+1], s[j+2], s[j+3], hiddenAt(hidden, j), policy, epsilon)
+			d[i], d[i+1], d[i+2], d[i+3] = float32(red), float32(green), float32(blue), float32(s[j+3])
+		})
+	case *GrayF64:
+		d := p.Pix[p.PixOffset(r.Min.X, r.Min.Y):]
+		forEachPixelPair(r, p.Stride, src.Stride, 1, 4, func(i, j int) {
+			d[i] = floatcolor.Luminance(s[j], s[j+1], s[j+2])
+		})
+	case *Tiled:
+		p.ForEachTile(0, func(tile FloatImage) {
+			CopyFromRGBAF64WithPolicy(tile.(draw.Image), src, policy, epsilon)
+		})
+	default:
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				dst.Set(x, y, src.At(x, y))
+			}
+		}
+	}
+}
+
+// FromRGBAF64 returns the pixels of src as a new float image of the same type as like (see NewLike) with the bounds of src.
+func FromRGBAF64(src *RGBAF64, like image.Image) FloatImage {
+	policy, epsilon := floatcolor.CurrentZeroAlphaPolicy()
+	return FromRGBAF64WithPolicy(src, like, policy, epsilon)
+}
+
+// FromRGBAF64WithPolicy is like FromRGBAF64, but follows the given zero alpha policy and alpha epsilon
+// (see CopyFromRGBAF64WithPolicy).
+func FromRGBAF64WithPolicy(src *RGBAF64, like image.Image, policy floatcolor.ZeroAlphaPolicy, epsilon float64) FloatImage {
+	if _, ok := like.(*RGBAF64); ok {
+		return src
+	}
+
+	result := NewLike(like, src.Rect)
+	CopyFromRGBAF64WithPolicy(result.(draw.Image), src, policy, epsilon)
+	return result
+}
+
+// forEachPixelPair calls f with the Pix offsets of every pixel within the rectangle r, of two images with the same bounds.
+// The pixels are processed in parallel bands of rows, so f may only write to the pixel at the given offsets.
+func forEachPixelPair(r image.Rectangle, stride1, stride2 int, channels1, channels2 int, f func(i, j int)) {
+	width := r.Dx()
+	ForEachBand(r, 0, func(band image.Rectangle) {
+		for y := band.Min.Y - r.Min.Y; y < band.Max.Y-r.Min.Y; y++ {
+			i, j := y*stride1, y*stride2
+			for x := 0; x < width; x++ {
+				f(i, j)
+				i += channels1
+				j += channels2
+			}
+		}
+	})
+}
@@ -0,0 +1,167 @@
+package floatimage
+
+import (
+	"floatimage/pkg/floatcolor"
+	"image"
+)
+
+// exporter is implemented by the row-major float images, which share the conversion to 8 and 16 bit images.
+type exporter interface {
+	Bounds() image.Rectangle
+	quantization() floatcolor.Quantization
+	exportValues(min, max float64, useRange bool, premultiplied bool) func(x, y int, v []float64)
+}
+
+// export quantizes the pixels of p to the pixels pix (with the given stride) of an image with the bounds of p,
+// with four channels with premultiplied or ordinary alpha, or the luminance (as if composited over black) only with gray.
+// levels is the largest integer value, 0xff or 0xffff.
+func export(p exporter, pix []uint8, stride int, min, max float64, useRange bool, premultiplied, gray bool, levels float64) {
+	if useRange && (min > max) {
+		min, max = max, min
+	}
+
+	bytesPerValue := 1
+	if levels > 0xff {
+		bytesPerValue = 2
+	}
+
+	q := p.quantization()
+	if q == floatcolor.Truncate || q == floatcolor.RoundHalfEven || q == floatcolor.RoundHalfAwayFromZero {
+		// Quantizations without a pixel position read the Pix slice directly, see exportPix.
+		switch img := p.(type) {
+		case *NRGBAF64:
+			exportPix(img.Pix, img.Stride, img.Rect, 4, false, nil, pix, stride, min, max, useRange, premultiplied, gray, levels, q)
+			return
+		case *NRGBAF32:
+			exportPix(img.Pix, img.Stride, img.Rect, 4, false, nil, pix, stride, min, max, useRange, premultiplied, gray, levels, q)
+			return
+		case *RGBAF64:
+			exportPix(img.Pix, img.Stride, img.Rect, 4, true, img.Hidden, pix, stride, min, max, useRange, premultiplied, gray, levels, q)
+			return
+		case *RGBAF32:
+			exportPix(img.Pix, img.Stride, img.Rect, 4, true, img.Hidden, pix, stride, min, max, useRange, premultiplied, gray, levels, q)
+			return
+		case *GrayF64:
+			exportPix(img.Pix, img.Stride, img.Rect, 1, false, nil, pix, stride, min, max, useRange, premultiplied, gray, levels, q)
+			return
+		}
+	}
+
+	r := p.Bounds()
+	if !gray {
+		values := p.exportValues(min, max, useRange, premultiplied)
+		quantizeImage(r, 4, levels, q, premultiplied, values, storeValues(pix, stride, r.Min, 4, bytesPerValue))
+		return
+	}
+
+	rgba := p.exportValues(min, max, useRange, true)
+	values := func(x, y int, v []float64) {
+		var c [4]float64
+		rgba(x, y, c[:])
+		if c[0] == c[1] && c[1] == c[2] {
+			v[0] = c[0] // Keeps gray values exact
+		} else {
+			v[0] = floatcolor.Luminance(c[0], c[1], c[2])
+		}
+	}
+	quantizeImage(r, 1, levels, q, false, values, storeValues(pix, stride, r.Min, 1, bytesPerValue))
+}
+
+// exportPix is export for the quantizations without a pixel position (Truncate and the roundings), which need no
+// callbacks per pixel: it reads the values of an image with the bounds r directly from its Pix slice src (with the given
+// stride), with four channels (premultiplied with srcPremultiplied, with the hidden colors hidden) or one gray channel,
+// and gives the same result.
+func exportPix[T float32 | float64](src []T, srcStride int, r image.Rectangle, channels int, srcPremultiplied bool, hidden []T,
+	pix []uint8, stride int, min, max float64, useRange bool, premultiplied, gray bool, levels float64, q floatcolor.Quantization) {
+	bytesPerValue := 1
+	if levels > 0xff {
+		bytesPerValue = 2
+	}
+	n := 4 * bytesPerValue
+	if gray {
+		n = bytesPerValue
+	}
+
+	ForEachBand(r, 0, func(band image.Rectangle) {
+		var v [4]float64
+		for y := band.Min.Y - r.Min.Y; y < band.Max.Y-r.Min.Y; y++ {
+			i, j := y*srcStride, y*stride
+			for x := 0; x < r.Dx(); x++ {
+				s := src[i : i+channels : i+channels] // Small cap improves performance, see https://golang.org/issue/27857
+				if channels == 1 {
+					v[0], v[1], v[2], v[3] = float64(s[0]), float64(s[0]), float64(s[0]), 1.0
+				} else {
+					v[0], v[1], v[2], v[3] = float64(s[0]), float64(s[1]), float64(s[2]), float64(s[3])
+				}
+				h := hiddenAt(hidden, i)
+				i += channels
+
+				// The same values as the exportValues methods, premultiplied for gray
+				if !srcPremultiplied || !(premultiplied || gray) || useRange {
+					if srcPremultiplied {
+						v[0], v[1], v[2] = floatcolor.UnpremultiplyHidden(v[0], v[1], v[2], v[3], hiddenF64(h))
+					}
+					if useRange {
+						v[0], v[1], v[2] = (v[0]-min)/(max-min), (v[1]-min)/(max-min), (v[2]-min)/(max-min)
+					}
+					if premultiplied || gray {
+						v[0], v[1], v[2] = v[0]*v[3], v[1]*v[3], v[2]*v[3]
+					}
+				}
+
+				values := v[:]
+				if gray {
+					if v[0] != v[1] || v[1] != v[2] {
+						v[0] = floatcolor.Luminance(v[0], v[1], v[2]) // Keeps gray values exact otherwise
+					}
+					values = v[:1]
+				}
+				for c := range values {
+					values[c] = q.Quantize(values[c]*levels, levels)
+				}
+				if premultiplied && !gray {
+					limitToAlpha(values)
+				}
+
+				putValues(pix[j:j+n:j+n], values, bytesPerValue)
+				j += n
+			}
+		}
+	})
+}
+
+func exportRGBA(p exporter, min, max float64, useRange bool) *image.RGBA {
+	result := image.NewRGBA(p.Bounds())
+	export(p, result.Pix, result.Stride, min, max, useRange, true, false, 0xff)
+	return result
+}
+
+func exportNRGBA(p exporter, min, max float64, useRange bool) *image.NRGBA {
+	result := image.NewNRGBA(p.Bounds())
+	export(p, result.Pix, result.Stride, min, max, useRange, false, false, 0xff)
+	return result
+}
+
+func exportRGBA64(p exporter, min, max float64, useRange bool) *image.RGBA64 {
+	result := image.NewRGBA64(p.Bounds())
+	export(p, result.Pix, result.Stride, min, max, useRange, true, false, 0xffff)
+	return result
+}
+
+func exportNRGBA64(p exporter, min, max float64, useRange bool) *image.NRGBA64 {
+	result := image.NewNRGBA64(p.Bounds())
+	export(p, result.Pix, result.Stride, min, max, useRange, false, false, 0xffff)
+	return result
+}
+
+func exportGray(p exporter, min, max float64, useRange bool) *image.Gray {
+	result := image.NewGray(p.Bounds())
+	export(p, result.Pix, result.Stride, min, max, useRange, true, true, 0xff)
+	return result
+}
+
+func exportGray16(p exporter, min, max float64, useRange bool) *image.Gray16 {
+	result := image.NewGray16(p.Bounds())
+	export(p, result.Pix, result.Stride, min, max, useRange, true, true, 0xffff)
+	return result
+}
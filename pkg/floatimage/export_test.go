@@ -0,0 +1,177 @@
+package floatimage
+
+import (
+	"floatimage/pkg/floatcolor"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// exportImages returns an image of every float image type with the same varied colors, some of them transparent.
+func exportImages(t *testing.T) []FloatImage {
+	t.Helper()
+	source := NewNRGBAF64WithBounds(-2, 1, 9, 6)
+	for y := source.Rect.Min.Y; y < source.Rect.Max.Y; y++ {
+		for x := source.Rect.Min.X; x < source.Rect.Max.X; x++ {
+			source.SetNRGBAF64(x, y, floatcolor.NRGBAF64{
+				R: float64(x+2) / 10, G: float64(y) / 7, B: 0.123456789, A: float64((x+y+8)%5) / 4,
+			})
+		}
+	}
+
+	multi := NewMultiChannelF32WithBounds(-2, 1, 9, 6, "R", "G", "B", "A")
+	view, err := multi.ViewNRGBAF32("R", "G", "B", "A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	copyRect(view, source, source.Rect)
+
+	rgba := ToRGBAF64(source)
+	return []FloatImage{
+		source,
+		FromRGBAF64(rgba, NewNRGBAF32(0, 0)),
+		rgba,
+		FromRGBAF64(rgba, NewRGBAF32(0, 0)),
+		NewTiledFrom(FromRGBAF64(rgba, NewNRGBAF32(0, 0)), 4, 3),
+		view,
+	}
+}
+
+func TestAs16Bit(t *testing.T) {
+	for _, img := range exportImages(t) {
+		rgba64, nrgba64 := img.AsRGBA64(), img.AsNRGBA64()
+		if rgba64.Rect != img.Bounds() || nrgba64.Rect != img.Bounds() {
+			t.Fatalf("%T: expected bounds %v but got %v and %v", img, img.Bounds(), rgba64.Rect, nrgba64.Rect)
+		}
+
+		r := img.Bounds()
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				if c, expected := rgba64.RGBA64At(x, y), img.RGBA64At(x, y); c != expected {
+					t.Fatalf("%T: expected %+v at (%d, %d) but got %+v", img, expected, x, y, c)
+				}
+
+				c, expected := nrgba64.NRGBA64At(x, y), floatcolor.NRGBAF64Model.Convert(img.At(x, y)).(floatcolor.NRGBAF64)
+				if c.A != uint16(expected.A*0xffff) || expected.A > 0 && (c.R != uint16(expected.R*0xffff) || c.B != uint16(expected.B*0xffff)) {
+					t.Fatalf("%T: expected %+v at (%d, %d) but got %+v", img, expected, x, y, c)
+				}
+			}
+		}
+
+		// The 16 bit conversion keeps more precision than the 8 bit one
+		c := nrgba64.NRGBA64At(0, 1)
+		if uint8(c.B>>8) != img.AsNRGBA().NRGBAAt(0, 1).B || c.B&0xff == 0 || c.A != 0xffff {
+			t.Errorf("%T: expected 16 bit blue value but got %x", img, c.B)
+		}
+	}
+}
+
+func TestAsGray(t *testing.T) {
+	for _, img := range exportImages(t) {
+		gray, gray16 := img.AsGray(), img.AsGray16()
+		r := img.Bounds()
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				expected := floatcolor.GrayF64Model.Convert(img.At(x, y)).(floatcolor.GrayF64).Y
+				if c := gray16.Gray16At(x, y); c.Y != uint16(expected*0xffff) {
+					t.Fatalf("%T: expected %x at (%d, %d) but got %x", img, uint16(expected*0xffff), x, y, c.Y)
+				}
+				if c := gray.GrayAt(x, y); c.Y != uint8(expected*0xff) {
+					t.Fatalf("%T: expected %x at (%d, %d) but got %x", img, uint8(expected*0xff), x, y, c.Y)
+				}
+			}
+		}
+	}
+
+	img := NewGrayF64(3, 1)
+	copy(img.Pix, []float64{0, 0.5, 1.0})
+	if c := img.AsGray16(); c.Pix[2] != 0x7f || c.Pix[3] != 0xff || c.Gray16At(2, 0).Y != 0xffff {
+		t.Errorf("expected big-endian gray values but got %v", c.Pix)
+	}
+	if c := img.AsGray().GrayAt(2, 0); c.Y != 0xff {
+		t.Errorf("expected white but got %+v", c)
+	}
+}
+
+func TestAs16BitForRange(t *testing.T) {
+	img := NewRGBAF32(2, 1)
+	img.Quantization = floatcolor.RoundHalfEven
+	img.SetNRGBAF64(0, 0, floatcolor.NRGBAF64{R: 1.0, G: 2.0, B: 0.0, A: 0.5})
+
+	if c := img.AsNRGBA64ForRange(0, 2).NRGBA64At(0, 0); c != (color.NRGBA64{R: 0x8000, G: 0xffff, B: 0, A: 0x8000}) {
+		t.Errorf("expected values mapped from [0, 2] but got %+v", c)
+	}
+	if c := img.AsRGBA64ForRange(2, 0).RGBA64At(0, 0); c != (color.RGBA64{R: 0x4000, G: 0x8000, B: 0, A: 0x8000}) {
+		t.Errorf("expected premultiplied values mapped from [0, 2] but got %+v", c)
+	}
+	if c := img.AsGray16ForRange(0, 2).Gray16At(0, 0); c.Y != uint16(floatcolor.RoundHalfEven.Quantize(floatcolor.Luminance(0.25, 0.5, 0)*0xffff, 0xffff)) {
+		t.Errorf("expected luminance of the mapped values but got %+v", c)
+	}
+	if c := img.AsGrayForRange(0, 2).GrayAt(1, 0); c.Y != 0 {
+		t.Errorf("expected transparent pixel to be black but got %+v", c)
+	}
+}
+
+func TestAs16BitDithering(t *testing.T) {
+	img := gradientImage(floatcolor.FloydSteinberg)
+	for _, exported := range []image.Image{img.AsNRGBA64(), img.AsGray16()} {
+		r := exported.Bounds()
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := color.NRGBA64Model.Convert(exported.At(x, 0)).(color.NRGBA64)
+			if exact := img.NRGBAF64At(x, 0).R * 0xffff; float64(c.R) < exact-1 || float64(c.R) > exact+1 {
+				t.Fatalf("expected %v at %d but got %+v", exact, x, c)
+			}
+		}
+	}
+}
+
+// callbackExporter hides the type of the image from export, so it takes the callback path of the dithering strategies.
+type callbackExporter struct {
+	exporter
+}
+
+func TestExportPix(t *testing.T) {
+	gray := NewGrayF64WithBounds(-2, 1, 9, 6)
+	for i := range gray.Pix {
+		gray.Pix[i] = float64(i%9)/8 - 0.1
+	}
+
+	for _, img := range append(exportImages(t), gray) {
+		p, ok := img.(exporter)
+		if !ok {
+			continue
+		}
+		for _, q := range []floatcolor.Quantization{floatcolor.Truncate, floatcolor.RoundHalfEven, floatcolor.RoundHalfAwayFromZero} {
+			switch p := img.(type) {
+			case *NRGBAF64:
+				p.Quantization = q
+			case *NRGBAF32:
+				p.Quantization = q
+			case *RGBAF64:
+				p.Quantization = q
+			case *RGBAF32:
+				p.Quantization = q
+			case *GrayF64:
+				p.Quantization = q
+			}
+			for _, useRange := range []bool{false, true} {
+				min, max := 0.0, 1.0
+				if useRange {
+					min, max = -0.5, 2.0
+				}
+				for i, f := range []func(p exporter) []uint8{
+					func(p exporter) []uint8 { return exportRGBA(p, min, max, useRange).Pix },
+					func(p exporter) []uint8 { return exportNRGBA(p, min, max, useRange).Pix },
+					func(p exporter) []uint8 { return exportRGBA64(p, min, max, useRange).Pix },
+					func(p exporter) []uint8 { return exportNRGBA64(p, min, max, useRange).Pix },
+					func(p exporter) []uint8 { return exportGray(p, min, max, useRange).Pix },
+					func(p exporter) []uint8 { return exportGray16(p, min, max, useRange).Pix },
+				} {
+					if direct, callback := f(p), f(callbackExporter{p}); string(direct) != string(callback) {
+						t.Errorf("%T with quantization %d, range %v: expected conversion %d to give the same pixels as the callback path", img, q, useRange, i)
+					}
+				}
+			}
+		}
+	}
+}
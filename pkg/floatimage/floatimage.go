@@ -9,6 +9,18 @@ type FloatImage interface {
 	AsRGBAForRange(min, max float64) *image.RGBA
 	AsNRGBAForRange(min, max float64) *image.NRGBA
 
+	AsRGBA64() *image.RGBA64
+	AsNRGBA64() *image.NRGBA64
+
+	AsRGBA64ForRange(min, max float64) *image.RGBA64
+	AsNRGBA64ForRange(min, max float64) *image.NRGBA64
+
+	AsGray() *image.Gray
+	AsGray16() *image.Gray16
+
+	AsGrayForRange(min, max float64) *image.Gray
+	AsGray16ForRange(min, max float64) *image.Gray16
+
 	image.Image
 	image.RGBA64Image
 }
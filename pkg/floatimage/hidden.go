@@ -0,0 +1,90 @@
+package floatimage
+
+import (
+	"floatimage/pkg/floatcolor"
+	"image"
+)
+
+// The Hidden planes of the premultiplied images hold three values (the hidden color, see
+// floatcolor.PreserveHiddenColor) per pixel of Pix, at the Pix offset of the pixel divided by four and times three.
+
+// hiddenAt returns the hidden color of the pixel at the Pix offset i, zero for images without hidden colors.
+func hiddenAt[T float32 | float64](hidden []T, i int) [3]T {
+	if hidden == nil {
+		return [3]T{}
+	}
+	j := i / 4 * 3
+	return [3]T{hidden[j], hidden[j+1], hidden[j+2]}
+}
+
+// setHidden stores the hidden color h of the pixel at the Pix offset i, allocating the hidden plane of an image
+// with the Pix slice pix when the first hidden color is stored.
+func setHidden[T float32 | float64](hidden *[]T, pix []T, i int, h [3]T) {
+	if *hidden == nil {
+		if h == ([3]T{}) {
+			return
+		}
+		*hidden = make([]T, len(pix)/4*3)
+	}
+	storeHidden(*hidden, i, h)
+}
+
+// storeHidden stores the hidden color h of the pixel at the Pix offset i if the image has a hidden plane.
+func storeHidden[T float32 | float64](hidden []T, i int, h [3]T) {
+	if hidden == nil {
+		return
+	}
+	j := i / 4 * 3
+	hidden[j], hidden[j+1], hidden[j+2] = h[0], h[1], h[2]
+}
+
+// keepHidden returns the hidden plane of an image with the Pix slice pix for operations storing the colors of
+// many pixels (with storeHidden): with the PreserveHiddenColor policy the plane is allocated beforehand,
+// so the pixels can be processed in parallel.
+func keepHidden[T float32 | float64](hidden *[]T, pix []T) []T {
+	if policy, _ := floatcolor.CurrentZeroAlphaPolicy(); policy == floatcolor.PreserveHiddenColor && *hidden == nil {
+		*hidden = make([]T, len(pix)/4*3)
+	}
+	return *hidden
+}
+
+// copyHidden copies the hidden colors of the pixels within r from the hidden plane src of an image to the one
+// of an image with the Pix slice dstPix, given the Pix offsets and strides of r in both images. The hidden colors
+// of dst are set to zero if src has none.
+func copyHidden[T float32 | float64](dst *[]T, dstPix []T, dstOffset, dstStride int, src []T, srcOffset, srcStride int, r image.Rectangle) {
+	if src == nil && *dst == nil {
+		return
+	}
+	if *dst == nil {
+		*dst = make([]T, len(dstPix)/4*3)
+	}
+	d, n := (*dst)[dstOffset/4*3:], 3*r.Dx()
+	if src != nil {
+		copyRows(d, dstStride/4*3, src[srcOffset/4*3:], srcStride/4*3, n, r.Dy())
+		return
+	}
+	for y := 0; y < r.Dy(); y++ {
+		row := d[y*dstStride/4*3 : y*dstStride/4*3+n]
+		for i := range row {
+			row[i] = 0
+		}
+	}
+}
+
+// subHidden returns the hidden plane of the sub image starting at the Pix offset i.
+func subHidden[T float32 | float64](hidden []T, i int) []T {
+	if hidden == nil {
+		return nil
+	}
+	return hidden[i/4*3:]
+}
+
+// hiddenF64 returns the hidden color h with float64 values.
+func hiddenF64[T float32 | float64](h [3]T) [3]float64 {
+	return [3]float64{float64(h[0]), float64(h[1]), float64(h[2])}
+}
+
+// hiddenOf returns the hidden color h with values of type T.
+func hiddenOf[T float32 | float64](h [3]float64) [3]T {
+	return [3]T{T(h[0]), T(h[1]), T(h[2])}
+}
@@ -0,0 +1,95 @@
+package floatimage
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"sync/atomic"
+)
+
+var (
+	// ErrNegativeDimensions is the error of a rectangle whose maximum point lies left of or above its minimum point.
+	ErrNegativeDimensions = errors.New("negative dimensions")
+	// ErrDimensionsOverflow is the error of a rectangle whose pixel values take more bytes than fit into an int
+	// or than the runtime can allocate at once.
+	ErrDimensionsOverflow = errors.New("dimensions overflow")
+	// ErrPixelBudgetExceeded is the error of a rectangle with more pixels than the maximum pixel budget.
+	ErrPixelBudgetExceeded = errors.New("pixel budget exceeded")
+)
+
+// DimensionError is the error of the checked constructors for image bounds that cannot be allocated.
+// Err is ErrNegativeDimensions, ErrDimensionsOverflow or ErrPixelBudgetExceeded, test for them with errors.Is.
+type DimensionError struct {
+	// Type is the name of the image type, e.g. "NRGBAF64".
+	Type string
+	// Rect is the rejected bounds.
+	Rect image.Rectangle
+	// MaxPixels is the pixel budget the bounds were checked against, 0 if there was none.
+	MaxPixels int
+	// Err is the reason the bounds were rejected.
+	Err error
+}
+
+func (e *DimensionError) Error() string {
+	if errors.Is(e.Err, ErrPixelBudgetExceeded) {
+		return fmt.Sprintf("image: New%s Rectangle %v has more than %d pixels: %v", e.Type, e.Rect, e.MaxPixels, e.Err)
+	}
+	return fmt.Sprintf("image: New%s Rectangle %v has huge or negative dimensions: %v", e.Type, e.Rect, e.Err)
+}
+
+func (e *DimensionError) Unwrap() error { return e.Err }
+
+// maxAllocation is the size in bytes of the largest slice the runtime allocates (2^48 bytes on 64-bit platforms),
+// make panics for larger slices even if their length fits into an int.
+const maxAllocation = 1 << 48
+
+var maxPixels atomic.Int64
+
+// SetMaxPixels sets the global maximum number of pixels of the images created by the checked constructors
+// (e.g. NewNRGBAF64Checked) that are not given a budget of their own. Zero or a negative value removes the limit,
+// which is the default. The constructors that panic (e.g. NewNRGBAF64) are not limited.
+func SetMaxPixels(pixels int) {
+	if pixels < 0 {
+		pixels = 0
+	}
+	maxPixels.Store(int64(pixels))
+}
+
+// MaxPixels returns the global maximum number of pixels of the checked constructors, 0 if there is no limit.
+func MaxPixels() int {
+	return int(maxPixels.Load())
+}
+
+// checkedBufferLength returns the length of the Pix slice of an image of the named type with the given number of channels
+// of valueSize bytes and bounds r, or a *DimensionError if r has negative dimensions, its size in bytes overflows the int type
+// or exceeds the allocation limit of the runtime, or its number of pixels exceeds budget. A budget of zero means the global budget (see SetMaxPixels), a negative budget means no limit.
+func checkedBufferLength(channels, valueSize int, r image.Rectangle, budget int, imageTypeName string) (int, error) {
+	if budget == 0 {
+		budget = MaxPixels()
+	}
+	if budget < 0 {
+		budget = 0
+	}
+	fail := func(err error) (int, error) {
+		return 0, &DimensionError{Type: imageTypeName, Rect: r, MaxPixels: budget, Err: err}
+	}
+
+	if r.Max.X < r.Min.X || r.Max.Y < r.Min.Y {
+		return fail(ErrNegativeDimensions)
+	}
+	// The differences are computed unsigned, as they overflow the int type for coordinates far apart.
+	width, height := uint64(r.Max.X)-uint64(r.Min.X), uint64(r.Max.Y)-uint64(r.Min.Y)
+	if width > math.MaxInt || height > math.MaxInt {
+		return fail(ErrDimensionsOverflow)
+	}
+	length := mul3NonNeg(channels, int(width), int(height))
+	size := mul3NonNeg(length, valueSize, 1)
+	if length < 0 || size < 0 || uint64(size) > maxAllocation {
+		return fail(ErrDimensionsOverflow)
+	}
+	if budget > 0 && width*height > uint64(budget) {
+		return fail(ErrPixelBudgetExceeded)
+	}
+	return length, nil
+}
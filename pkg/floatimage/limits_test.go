@@ -0,0 +1,102 @@
+package floatimage
+
+import (
+	"errors"
+	"image"
+	"math"
+	"testing"
+)
+
+// newChecked creates an image of every checked constructor with the bounds r and the budget maxPixels.
+func newChecked(r image.Rectangle, maxPixels int) []error {
+	var errs []error
+	_, err := NewNRGBAF64Checked(r, maxPixels)
+	errs = append(errs, err)
+	_, err = NewNRGBAF32Checked(r, maxPixels)
+	errs = append(errs, err)
+	_, err = NewRGBAF64Checked(r, maxPixels)
+	errs = append(errs, err)
+	_, err = NewRGBAF32Checked(r, maxPixels)
+	errs = append(errs, err)
+	_, err = NewGrayF64Checked(r, maxPixels)
+	errs = append(errs, err)
+	_, err = NewMultiChannelF32Checked(r, maxPixels, "R", "G", "B")
+	return append(errs, err)
+}
+
+func TestCheckedConstructors(t *testing.T) {
+	img, err := NewRGBAF32Checked(image.Rect(-2, 3, 5, 7), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Rect != image.Rect(-2, 3, 5, 7) || len(img.Pix) != 4*7*4 || img.Stride != 4*7 {
+		t.Errorf("expected a 7x4 image but got bounds %v, stride %d and %d values", img.Rect, img.Stride, len(img.Pix))
+	}
+	if empty, err := NewGrayF64Checked(image.Rectangle{}, 0); err != nil || len(empty.Pix) != 0 {
+		t.Errorf("expected empty image but got %v", err)
+	}
+
+	for _, test := range []struct {
+		r         image.Rectangle
+		maxPixels int
+		expected  error
+	}{
+		{image.Rectangle{Min: image.Pt(0, 0), Max: image.Pt(-1, 5)}, 0, ErrNegativeDimensions},
+		{image.Rectangle{Min: image.Pt(0, 3), Max: image.Pt(5, 2)}, 0, ErrNegativeDimensions},
+		{image.Rectangle{Min: image.Pt(math.MinInt, 0), Max: image.Pt(math.MaxInt, 1)}, 0, ErrDimensionsOverflow},
+		{image.Rect(0, 0, 1<<40, 1<<40), 0, ErrDimensionsOverflow},
+		{image.Rect(0, 0, math.MaxInt/2, 1), 0, ErrDimensionsOverflow},
+		{image.Rect(0, 0, 1<<28, 1<<28), 0, ErrDimensionsOverflow},
+		{image.Rect(0, 0, 101, 100), 10000, ErrPixelBudgetExceeded},
+		{image.Rect(0, 0, 100, 100), 10000, nil},
+	} {
+		for _, err := range newChecked(test.r, test.maxPixels) {
+			if !errors.Is(err, test.expected) || (err == nil) != (test.expected == nil) {
+				t.Errorf("expected %v for %v but got %v", test.expected, test.r, err)
+			}
+			var dimensionError *DimensionError
+			if err != nil && (!errors.As(err, &dimensionError) || dimensionError.Rect != test.r) {
+				t.Errorf("expected a DimensionError for %v but got %#v", test.r, err)
+			}
+		}
+	}
+
+	if _, err := NewMultiChannelF32Checked(image.Rect(0, 0, 1, 1), 0, "R", "R"); err == nil {
+		t.Errorf("expected an error for duplicate channel names")
+	}
+}
+
+func TestMaxPixels(t *testing.T) {
+	defer SetMaxPixels(MaxPixels())
+	SetMaxPixels(100)
+
+	if MaxPixels() != 100 {
+		t.Fatalf("expected budget of 100 pixels but got %d", MaxPixels())
+	}
+	for _, err := range newChecked(image.Rect(0, 0, 11, 10), 0) {
+		var dimensionError *DimensionError
+		if !errors.As(err, &dimensionError) || !errors.Is(err, ErrPixelBudgetExceeded) || dimensionError.MaxPixels != 100 {
+			t.Errorf("expected the global budget to be exceeded but got %v", err)
+		}
+	}
+	for _, err := range newChecked(image.Rect(0, 0, 11, 10), 200) {
+		if err != nil {
+			t.Errorf("expected the budget of the call to replace the global budget but got %v", err)
+		}
+	}
+	for _, err := range newChecked(image.Rect(0, 0, 11, 10), -1) {
+		if err != nil {
+			t.Errorf("expected no budget but got %v", err)
+		}
+	}
+
+	// The constructors that panic are not limited
+	if img := NewNRGBAF64(11, 10); len(img.Pix) != 440 {
+		t.Errorf("expected an unlimited image but got %d values", len(img.Pix))
+	}
+
+	SetMaxPixels(-5)
+	if MaxPixels() != 0 {
+		t.Errorf("expected no global budget but got %d", MaxPixels())
+	}
+}
@@ -0,0 +1,92 @@
+package floatimage
+
+import (
+	"floatimage/pkg/floatcolor"
+	"image"
+	"image/draw"
+)
+
+// Map replaces every pixel of the image with the result of f, in place.
+// The function is given (and returns) the pixel color with ordinary (non premultiplied) alpha,
+// regardless of how the image stores its pixels. Premultiplied pixels with zero alpha
+// are given to f with zero red, green, and blue values, or their hidden color with the
+// floatcolor.PreserveHiddenColor policy.
+//
+// Float images are processed directly on their Pix slice,
+// other images go through At and Set and the NRGBAF64 color model.
+func Map(img draw.Image, f func(c floatcolor.NRGBAF64) floatcolor.NRGBAF64) {
+	mapPixels(img, f, forEachPixel)
+}
+
+// MapParallel is like Map, but processes float images in bands of rows on up to workers goroutines
+// (the default number of workers if workers <= 0, see SetWorkers). The function f must be safe for concurrent use.
+// Other images are processed sequentially, as their Set method need not be safe for concurrent use.
+func MapParallel(img draw.Image, workers int, f func(c floatcolor.NRGBAF64) floatcolor.NRGBAF64) {
+	mapPixels(img, f, func(r image.Rectangle, stride int, channels int, g func(i int)) {
+		parallelForEachPixel(r, stride, channels, workers, g)
+	})
+}
+
+// mapPixels implements Map and MapParallel, forEach visits the Pix offsets of the pixels of float images.
+func mapPixels(img draw.Image, f func(c floatcolor.NRGBAF64) floatcolor.NRGBAF64, forEach func(r image.Rectangle, stride int, channels int, f func(i int))) {
+	switch p := img.(type) {
+	case *NRGBAF64:
+		forEach(p.Rect, p.Stride, 4, func(i int) {
+			s := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+			c := f(floatcolor.NRGBAF64{R: s[0], G: s[1], B: s[2], A: s[3]})
+			s[0], s[1], s[2], s[3] = c.R, c.G, c.B, c.A
+		})
+	case *NRGBAF32:
+		forEach(p.Rect, p.Stride, 4, func(i int) {
+			s := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+			c := f(floatcolor.NRGBAF64{R: float64(s[0]), G: float64(s[1]), B: float64(s[2]), A: float64(s[3])})
+			s[0], s[1], s[2], s[3] = float32(c.R), float32(c.G), float32(c.B), float32(c.A)
+		})
+	case *RGBAF64:
+		hidden := keepHidden(&p.Hidden, p.Pix)
+		forEach(p.Rect, p.Stride, 4, func(i int) {
+			s := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+			c := f(unpremultiply(s[0], s[1], s[2], s[3], hiddenAt(hidden, i)))
+			var h [3]float64
+			s[0], s[1], s[2], h = floatcolor.PremultiplyHidden(c.R, c.G, c.B, c.A)
+			s[3] = c.A
+			storeHidden(hidden, i, h)
+		})
+	case *RGBAF32:
+		hidden := keepHidden(&p.Hidden, p.Pix)
+		forEach(p.Rect, p.Stride, 4, func(i int) {
+			s := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+			c := f(unpremultiply(float64(s[0]), float64(s[1]), float64(s[2]), float64(s[3]), hiddenF64(hiddenAt(hidden, i))))
+			r, g, b, h := floatcolor.PremultiplyHidden(c.R, c.G, c.B, c.A)
+			s[0], s[1], s[2], s[3] = float32(r), float32(g), float32(b), float32(c.A)
+			storeHidden(hidden, i, hiddenOf[float32](h))
+		})
+	default:
+		bounds := img.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := floatcolor.NRGBAF64Model.Convert(img.At(x, y)).(floatcolor.NRGBAF64)
+				img.Set(x, y, f(c))
+			}
+		}
+	}
+}
+
+// forEachPixel calls f with the Pix offset of every pixel within the rectangle r.
+func forEachPixel(r image.Rectangle, stride int, channels int, f func(i int)) {
+	width := r.Dx()
+	for y := 0; y < r.Dy(); y++ {
+		i := y * stride
+		for x := 0; x < width; x++ {
+			f(i)
+			i += channels
+		}
+	}
+}
+
+// unpremultiply returns the premultiplied values with the hidden color as a color with ordinary alpha,
+// following the zero alpha policy (see floatcolor.SetZeroAlphaPolicy).
+func unpremultiply(r, g, b, a float64, hidden [3]float64) floatcolor.NRGBAF64 {
+	r, g, b = floatcolor.UnpremultiplyHidden(r, g, b, a, hidden)
+	return floatcolor.NRGBAF64{R: r, G: g, B: b, A: a}
+}
@@ -0,0 +1,46 @@
+package floatimage
+
+import (
+	"floatimage/pkg/floatcolor"
+	"testing"
+)
+
+func TestMapSubImage(t *testing.T) {
+	invert := func(c floatcolor.NRGBAF64) floatcolor.NRGBAF64 {
+		return floatcolor.NRGBAF64{R: 1.0 - c.R, G: 1.0 - c.G, B: 1.0 - c.B, A: c.A}
+	}
+
+	nrgbaf64 := NewNRGBAF64WithBounds(-2, -2, 2, 2)
+	Map(nrgbaf64.SubImage(nrgbaf64.Rect.Inset(1)).(*NRGBAF64), invert)
+
+	for y := -2; y < 2; y++ {
+		for x := -2; x < 2; x++ {
+			expected := 0.0
+			if x >= -1 && x < 1 && y >= -1 && y < 1 {
+				expected = 1.0
+			}
+			if v := nrgbaf64.Pix[nrgbaf64.PixOffset(x, y)]; v != expected {
+				t.Errorf("expected %v at (%d, %d) but got %v", expected, x, y, v)
+			}
+		}
+	}
+}
+
+func TestMapPremultiplied(t *testing.T) {
+	rgbaf32 := NewRGBAF32(2, 1)
+	rgbaf32.Set(0, 0, floatcolor.RGBAF32{R: 0.25, G: 0.0, B: 0.5, A: 0.5})
+
+	var seen []floatcolor.NRGBAF64
+	Map(rgbaf32, func(c floatcolor.NRGBAF64) floatcolor.NRGBAF64 {
+		seen = append(seen, c)
+		c.G = 1.0
+		return c
+	})
+
+	if seen[0] != (floatcolor.NRGBAF64{R: 0.5, G: 0.0, B: 1.0, A: 0.5}) || seen[1] != (floatcolor.NRGBAF64{}) {
+		t.Errorf("expected non premultiplied colors but got %+v", seen)
+	}
+	if c := rgbaf32.At(0, 0).(floatcolor.RGBAF32); c.G != 0.5 {
+		t.Errorf("expected premultiplied result but got %+v", c)
+	}
+}
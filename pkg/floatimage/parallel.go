@@ -0,0 +1,135 @@
+package floatimage
+
+import (
+	"image"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// rangesPerWorker is the number of ranges each worker gets on average.
+// More ranges than workers even out the load when some parts of an image are more expensive than others.
+const rangesPerWorker = 4
+
+var defaultWorkers atomic.Int32
+
+// SetWorkers sets the number of goroutines used by the parallel image operations of this package (and the packages on top of it)
+// when no worker count is given. Zero or a negative value resets the default, which is runtime.GOMAXPROCS(0).
+// One worker makes all operations run sequentially on the calling goroutine.
+func SetWorkers(workers int) {
+	if workers < 0 {
+		workers = 0
+	}
+	defaultWorkers.Store(int32(workers))
+}
+
+// Workers returns the default number of goroutines used by parallel image operations.
+func Workers() int {
+	if workers := int(defaultWorkers.Load()); workers > 0 {
+		return workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// ParallelFor splits [0, n) into consecutive ranges [start, end) and calls f for each range,
+// on up to workers goroutines at the same time (the default number of workers if workers <= 0).
+// It returns when all calls have returned. Every index is part of exactly one range, so results
+// are deterministic as long as f only writes to data belonging to its own range.
+func ParallelFor(n, workers int, f func(start, end int)) {
+	if n <= 0 {
+		return
+	}
+	if workers <= 0 {
+		workers = Workers()
+	}
+	if workers == 1 || n == 1 {
+		f(0, n)
+		return
+	}
+
+	ranges := workers * rangesPerWorker
+	if ranges > n {
+		ranges = n
+	}
+	if workers > ranges {
+		workers = ranges
+	}
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= ranges {
+					return
+				}
+				f(n*i/ranges, n*(i+1)/ranges)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// ForEachBand splits the rectangle into horizontal bands of whole rows and calls f for each band
+// on up to workers goroutines at the same time (the default number of workers if workers <= 0), see ParallelFor.
+func ForEachBand(r image.Rectangle, workers int, f func(band image.Rectangle)) {
+	if r.Empty() {
+		return
+	}
+
+	ParallelFor(r.Dy(), workers, func(start, end int) {
+		f(image.Rect(r.Min.X, r.Min.Y+start, r.Max.X, r.Min.Y+end))
+	})
+}
+
+// ForEachTile splits the rectangle into tiles of (at most) tileWidth x tileHeight pixels, aligned to the top left corner of the rectangle,
+// and calls f for each tile on up to workers goroutines at the same time (the default number of workers if workers <= 0).
+func ForEachTile(r image.Rectangle, tileWidth, tileHeight int, workers int, f func(tile image.Rectangle)) {
+	if r.Empty() || tileWidth <= 0 || tileHeight <= 0 {
+		return
+	}
+
+	columns := (r.Dx() + tileWidth - 1) / tileWidth
+	rows := (r.Dy() + tileHeight - 1) / tileHeight
+
+	ParallelFor(columns*rows, workers, func(start, end int) {
+		for i := start; i < end; i++ {
+			x0, y0 := r.Min.X+(i%columns)*tileWidth, r.Min.Y+(i/columns)*tileHeight
+			f(image.Rect(x0, y0, x0+tileWidth, y0+tileHeight).Intersect(r))
+		}
+	})
+}
+
+// allPixels reports whether f is true for the Pix offsets of all pixels within r, of an image whose Pix slice starts at r.Min.
+// The pixels are checked in parallel and the check stops at the first pixel where f is false.
+func allPixels(r image.Rectangle, stride, channels int, f func(i int) bool) bool {
+	var failed atomic.Bool
+
+	ForEachBand(r, 0, func(band image.Rectangle) {
+		for y := band.Min.Y; y < band.Max.Y && !failed.Load(); y++ {
+			i := (y - r.Min.Y) * stride
+			for x := 0; x < r.Dx(); x++ {
+				if !f(i) {
+					failed.Store(true)
+					return
+				}
+				i += channels
+			}
+		}
+	})
+
+	return !failed.Load()
+}
+
+// parallelForEachPixel is like forEachPixel, but calls f in bands of rows on up to workers goroutines.
+func parallelForEachPixel(r image.Rectangle, stride int, channels int, workers int, f func(i int)) {
+	ForEachBand(r, workers, func(band image.Rectangle) {
+		offset := (band.Min.Y - r.Min.Y) * stride
+		forEachPixel(band, stride, channels, func(i int) {
+			f(offset + i)
+		})
+	})
+}
@@ -0,0 +1,137 @@
+package floatimage
+
+import (
+	"floatimage/pkg/floatcolor"
+	"image"
+	"image/color"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParallelForCoversEveryIndexOnce(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 7, 100, 1001} {
+		for _, workers := range []int{0, 1, 3, 64} {
+			counts := make([]int32, n)
+			ParallelFor(n, workers, func(start, end int) {
+				if start >= end {
+					t.Errorf("n %d, workers %d: expected non empty range but got [%d, %d)", n, workers, start, end)
+				}
+				for i := start; i < end; i++ {
+					atomic.AddInt32(&counts[i], 1)
+				}
+			})
+
+			for i, count := range counts {
+				if count != 1 {
+					t.Fatalf("n %d, workers %d: expected index %d to be visited once but got %d", n, workers, i, count)
+				}
+			}
+		}
+	}
+}
+
+func TestForEachBandAndTile(t *testing.T) {
+	r := image.Rect(-3, 5, 14, 26)
+	img := NewGrayF64WithBounds(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+
+	ForEachBand(r, 4, func(band image.Rectangle) {
+		if band.Min.X != r.Min.X || band.Max.X != r.Max.X || !band.In(r) {
+			t.Errorf("expected band of whole rows within %v but got %v", r, band)
+		}
+		for y := band.Min.Y; y < band.Max.Y; y++ {
+			for x := band.Min.X; x < band.Max.X; x++ {
+				img.Pix[img.PixOffset(x, y)]++
+			}
+		}
+	})
+
+	ForEachTile(r, 4, 6, 4, func(tile image.Rectangle) {
+		if tile.Empty() || !tile.In(r) || tile.Dx() > 4 || tile.Dy() > 6 {
+			t.Errorf("expected tile of at most 4x6 pixels within %v but got %v", r, tile)
+		}
+		for y := tile.Min.Y; y < tile.Max.Y; y++ {
+			for x := tile.Min.X; x < tile.Max.X; x++ {
+				img.Pix[img.PixOffset(x, y)]++
+			}
+		}
+	})
+
+	for i, v := range img.Pix {
+		if v != 2 {
+			t.Fatalf("expected every pixel to be in one band and one tile but pixel %d was visited %v times", i, v)
+		}
+	}
+}
+
+func TestMapParallelIsDeterministic(t *testing.T) {
+	f := func(c floatcolor.NRGBAF64) floatcolor.NRGBAF64 {
+		return floatcolor.NRGBAF64{R: c.R * c.R, G: 1 - c.G, B: c.B + c.A, A: c.A}
+	}
+
+	sequential := NewRGBAF32WithBounds(-5, -5, 40, 33)
+	for i := range sequential.Pix {
+		sequential.Pix[i] = float32(i%17) / 16
+	}
+	parallel := NewRGBAF32WithBounds(-5, -5, 40, 33)
+	copy(parallel.Pix, sequential.Pix)
+
+	Map(sequential.SubImage(image.Rect(0, 0, 40, 30)).(*RGBAF32), f)
+	MapParallel(parallel.SubImage(image.Rect(0, 0, 40, 30)).(*RGBAF32), 8, f)
+
+	for i := range sequential.Pix {
+		if sequential.Pix[i] != parallel.Pix[i] {
+			t.Fatalf("expected parallel result %v at %d to be the same as sequential result %v", parallel.Pix[i], i, sequential.Pix[i])
+		}
+	}
+}
+
+func TestParallelConversionIsDeterministic(t *testing.T) {
+	defer SetWorkers(0)
+
+	img := NewNRGBAF64WithBounds(3, -2, 50, 41)
+	for i := range img.Pix {
+		img.Pix[i] = float64(i%23) / 22
+	}
+
+	SetWorkers(1)
+	nrgba := img.AsNRGBA()
+	rgbaf64 := ToRGBAF64(img)
+
+	SetWorkers(7)
+	if Workers() != 7 {
+		t.Fatalf("expected 7 workers but got %d", Workers())
+	}
+	parallelNRGBA := img.AsNRGBA()
+	parallelRGBAF64 := ToRGBAF64(img)
+
+	for i := range nrgba.Pix {
+		if nrgba.Pix[i] != parallelNRGBA.Pix[i] {
+			t.Fatalf("expected parallel conversion to NRGBA to be the same as sequential at %d", i)
+		}
+	}
+	for i := range rgbaf64.Pix {
+		if rgbaf64.Pix[i] != parallelRGBAF64.Pix[i] {
+			t.Fatalf("expected parallel conversion to RGBAF64 to be the same as sequential at %d", i)
+		}
+	}
+}
+
+func TestOpaqueWithOffsetBounds(t *testing.T) {
+	img := NewNRGBAF32WithBounds(10, 20, 30, 45)
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	if !img.Opaque() {
+		t.Errorf("expected opaque image")
+	}
+
+	img.Set(29, 44, floatcolor.NRGBAF32{A: 0.5})
+	if img.Opaque() {
+		t.Errorf("expected image with a translucent pixel in the bottom right corner to not be opaque")
+	}
+	if !img.SubImage(image.Rect(10, 20, 29, 45)).(*NRGBAF32).Opaque() {
+		t.Errorf("expected sub image without the translucent pixel to be opaque")
+	}
+}
@@ -0,0 +1,115 @@
+package floatimage
+
+import (
+	"floatimage/pkg/floatcolor"
+	"image"
+)
+
+// DefaultAlphaEpsilon is an alpha threshold for Unpremultiply. Dividing premultiplied color values by an alpha
+// at or below it amplifies their rounding errors (of float32 values in particular) beyond any useful precision.
+const DefaultAlphaEpsilon = floatcolor.DefaultAlphaEpsilon
+
+// Premultiply multiplies the color values of all pixels with their alpha in place and returns the image as an RGBAF64
+// image sharing the Pix slice (and bounds, stride and quantization) with p. Unlike a conversion through the color model
+// it does not allocate a new image. p holds premultiplied values afterwards and should not be used anymore.
+//
+// The color of pixels with zero alpha becomes zero. With the floatcolor.PreserveHiddenColor policy
+// it is kept in the Hidden plane of the result.
+func (p *NRGBAF64) Premultiply() *RGBAF64 {
+	result := &RGBAF64{Pix: p.Pix, Stride: p.Stride, Rect: p.Rect, Precise: p.Precise, Quantization: p.Quantization}
+	premultiplyPix(p.Pix, p.Stride, p.Rect, keepHidden(&result.Hidden, result.Pix))
+	return result
+}
+
+// Premultiply multiplies the color values of all pixels with their alpha in place and returns the image as an RGBAF32
+// image sharing the Pix slice (and bounds, stride and quantization) with p. Unlike a conversion through the color model
+// it does not allocate a new image. p holds premultiplied values afterwards and should not be used anymore.
+//
+// The color of pixels with zero alpha becomes zero. With the floatcolor.PreserveHiddenColor policy
+// it is kept in the Hidden plane of the result.
+func (p *NRGBAF32) Premultiply() *RGBAF32 {
+	result := &RGBAF32{Pix: p.Pix, Stride: p.Stride, Rect: p.Rect, Precise: p.Precise, Quantization: p.Quantization}
+	premultiplyPix(p.Pix, p.Stride, p.Rect, keepHidden(&result.Hidden, result.Pix))
+	return result
+}
+
+// Unpremultiply divides the color values of all pixels by their alpha in place and returns the image as an NRGBAF64
+// image sharing the Pix slice (and bounds, stride and quantization) with p. Unlike a conversion through the color model
+// it does not allocate a new image. p holds values with ordinary alpha afterwards and should not be used anymore.
+//
+// Pixels with an alpha at or below epsilon (e.g. DefaultAlphaEpsilon, or 0 to divide by any positive alpha) have no
+// recoverable color: their color values are set to zero and their alpha is kept, so no pixel becomes NaN or infinite.
+// With the floatcolor.PreserveHiddenColor policy pixels with zero alpha get their hidden color instead.
+func (p *RGBAF64) Unpremultiply(epsilon float64) *NRGBAF64 {
+	var hidden []float64
+	if policy, _ := floatcolor.CurrentZeroAlphaPolicy(); policy == floatcolor.PreserveHiddenColor {
+		hidden = p.Hidden
+	}
+	unpremultiplyPix(p.Pix, p.Stride, p.Rect, epsilon, hidden)
+	return &NRGBAF64{Pix: p.Pix, Stride: p.Stride, Rect: p.Rect, Precise: p.Precise, Quantization: p.Quantization}
+}
+
+// Unpremultiply divides the color values of all pixels by their alpha in place and returns the image as an NRGBAF32
+// image sharing the Pix slice (and bounds, stride and quantization) with p. Unlike a conversion through the color model
+// it does not allocate a new image. p holds values with ordinary alpha afterwards and should not be used anymore.
+//
+// Pixels with an alpha at or below epsilon (e.g. DefaultAlphaEpsilon, or 0 to divide by any positive alpha) have no
+// recoverable color: their color values are set to zero and their alpha is kept, so no pixel becomes NaN or infinite.
+// With the floatcolor.PreserveHiddenColor policy pixels with zero alpha get their hidden color instead.
+func (p *RGBAF32) Unpremultiply(epsilon float64) *NRGBAF32 {
+	var hidden []float32
+	if policy, _ := floatcolor.CurrentZeroAlphaPolicy(); policy == floatcolor.PreserveHiddenColor {
+		hidden = p.Hidden
+	}
+	unpremultiplyPix(p.Pix, p.Stride, p.Rect, float32(epsilon), hidden)
+	return &NRGBAF32{Pix: p.Pix, Stride: p.Stride, Rect: p.Rect, Precise: p.Precise, Quantization: p.Quantization}
+}
+
+// premultiplyPix multiplies the color values with alpha for the pixels within r of an image with four channels,
+// keeping the color of pixels with zero (or negative) alpha in hidden unless it is nil.
+func premultiplyPix[T float32 | float64](pix []T, stride int, r image.Rectangle, hidden []T) {
+	ForEachBand(r, 0, func(band image.Rectangle) {
+		n := 4 * r.Dx()
+		for y := band.Min.Y; y < band.Max.Y; y++ {
+			i := (y - r.Min.Y) * stride
+			row := pix[i : i+n : i+n]
+			for j := 0; j < n; j += 4 {
+				s := row[j : j+4 : j+4] // Small cap improves performance, see https://golang.org/issue/27857
+				if s[3] <= 0 && hidden != nil {
+					storeHidden(hidden, i+j, [3]T{s[0], s[1], s[2]})
+				}
+				s[0] *= s[3]
+				s[1] *= s[3]
+				s[2] *= s[3]
+			}
+		}
+	})
+}
+
+// unpremultiplyPix divides the color values by alpha for the pixels within r of an image with four channels,
+// setting them to zero where alpha is at or below epsilon, or to the hidden color where alpha is zero (or negative)
+// unless hidden is nil.
+func unpremultiplyPix[T float32 | float64](pix []T, stride int, r image.Rectangle, epsilon T, hidden []T) {
+	ForEachBand(r, 0, func(band image.Rectangle) {
+		n := 4 * r.Dx()
+		for y := band.Min.Y; y < band.Max.Y; y++ {
+			i := (y - r.Min.Y) * stride
+			row := pix[i : i+n : i+n]
+			for j := 0; j < n; j += 4 {
+				s := row[j : j+4 : j+4] // Small cap improves performance, see https://golang.org/issue/27857
+				if s[3] <= 0 && hidden != nil {
+					h := hiddenAt(hidden, i+j)
+					s[0], s[1], s[2] = h[0], h[1], h[2]
+					continue
+				}
+				if s[3] <= epsilon {
+					s[0], s[1], s[2] = 0, 0, 0
+					continue
+				}
+				s[0] /= s[3]
+				s[1] /= s[3]
+				s[2] /= s[3]
+			}
+		}
+	})
+}
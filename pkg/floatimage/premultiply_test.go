@@ -0,0 +1,91 @@
+package floatimage
+
+import (
+	"floatimage/pkg/floatcolor"
+	"image"
+	"math"
+	"testing"
+)
+
+func TestPremultiply(t *testing.T) {
+	img := NewNRGBAF64WithBounds(-1, 2, 3, 5)
+	for i := range img.Pix {
+		img.Pix[i] = float64(i%9) / 8
+	}
+	original := append([]float64(nil), img.Pix...)
+	expected := ToRGBAF64(img)
+
+	rgba := img.Premultiply()
+	if &rgba.Pix[0] != &img.Pix[0] || rgba.Rect != img.Rect || rgba.Stride != img.Stride {
+		t.Fatalf("expected premultiplied image to share the pixels of the original")
+	}
+	for i := range expected.Pix {
+		if math.Abs(rgba.Pix[i]-expected.Pix[i]) > 1e-15 {
+			t.Fatalf("expected premultiplied value %v at %d but got %v", expected.Pix[i], i, rgba.Pix[i])
+		}
+	}
+
+	nrgba := rgba.Unpremultiply(0)
+	for i := 0; i < len(original); i += 4 {
+		if original[i+3] == 0 {
+			if nrgba.Pix[i] != 0 || nrgba.Pix[i+1] != 0 || nrgba.Pix[i+2] != 0 {
+				t.Fatalf("expected zero color for zero alpha at %d but got %v", i, nrgba.Pix[i:i+4])
+			}
+			continue
+		}
+		for c := 0; c < 4; c++ {
+			if math.Abs(nrgba.Pix[i+c]-original[i+c]) > 1e-15 {
+				t.Fatalf("expected round trip value %v at %d but got %v", original[i+c], i+c, nrgba.Pix[i+c])
+			}
+		}
+	}
+}
+
+func TestUnpremultiplyEpsilon(t *testing.T) {
+	img := NewRGBAF32(4, 1)
+	img.SetRGBAF32(0, 0, floatcolor.RGBAF32{R: 0.1, G: 0.2, B: 0.3, A: 0.5})
+	img.SetRGBAF32(1, 0, floatcolor.RGBAF32{R: 1e-8, G: 1e-8, B: 1e-8, A: 1e-8})
+	img.SetRGBAF32(2, 0, floatcolor.RGBAF32{R: 0.5, G: 0.5, B: 0.5, A: 0})
+	img.SetRGBAF32(3, 0, floatcolor.RGBAF32{R: 1e-8, G: 1e-8, B: 1e-8, A: 1e-7})
+
+	nrgba := img.Unpremultiply(DefaultAlphaEpsilon)
+	expected := []floatcolor.NRGBAF32{
+		{R: 0.2, G: 0.4, B: 0.6, A: 0.5},
+		{A: 1e-8},
+		{},
+		{A: 1e-7},
+	}
+	for x, e := range expected {
+		c := nrgba.NRGBAF32At(x, 0)
+		if math.Abs(float64(c.R-e.R)) > 1e-6 || math.Abs(float64(c.G-e.G)) > 1e-6 || math.Abs(float64(c.B-e.B)) > 1e-6 || c.A != e.A {
+			t.Errorf("expected %+v at (%d, 0) but got %+v", e, x, c)
+		}
+	}
+
+	// Without epsilon tiny alpha values are divided by
+	img = NewRGBAF32(1, 1)
+	img.SetRGBAF32(0, 0, floatcolor.RGBAF32{R: 1e-8, A: 1e-8})
+	if c := img.Unpremultiply(0).NRGBAF32At(0, 0); c.R != 1.0 {
+		t.Errorf("expected tiny alpha to be divided by without epsilon but got %+v", c)
+	}
+}
+
+func TestPremultiplySubImage(t *testing.T) {
+	img := NewNRGBAF32(4, 4)
+	for i := range img.Pix {
+		img.Pix[i] = 0.5
+	}
+
+	img.SubImage(image.Rect(1, 1, 3, 3)).(*NRGBAF32).Premultiply()
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			expected := float32(0.5)
+			if x >= 1 && x < 3 && y >= 1 && y < 3 {
+				expected = 0.25
+			}
+			if c := img.NRGBAF32At(x, y); c.R != expected || c.A != 0.5 {
+				t.Errorf("expected red %v at (%d, %d) but got %+v", expected, x, y, c)
+			}
+		}
+	}
+}
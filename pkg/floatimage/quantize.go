@@ -0,0 +1,231 @@
+package floatimage
+
+import (
+	"floatimage/pkg/floatcolor"
+	"image"
+	"image/color"
+	"math"
+	"sync"
+)
+
+// bayer8 is the 8x8 ordered dither (Bayer) index matrix, row by row.
+var bayer8 = [64]uint8{
+	0, 32, 8, 40, 2, 34, 10, 42,
+	48, 16, 56, 24, 50, 18, 58, 26,
+	12, 44, 4, 36, 14, 46, 6, 38,
+	60, 28, 52, 20, 62, 30, 54, 22,
+	3, 35, 11, 43, 1, 33, 9, 41,
+	51, 19, 59, 27, 49, 17, 57, 25,
+	15, 47, 7, 39, 13, 45, 5, 37,
+	63, 31, 55, 23, 61, 29, 53, 21,
+}
+
+const blueNoiseSize = 64
+
+var (
+	blueNoiseOnce       sync.Once
+	blueNoiseThresholds []float64
+)
+
+// blueNoise returns the blue noise threshold map of blueNoiseSize x blueNoiseSize values in (0, 1), row by row.
+// It is built on first use, which takes a few milliseconds.
+func blueNoise() []float64 {
+	blueNoiseOnce.Do(func() {
+		blueNoiseThresholds = makeBlueNoise(blueNoiseSize)
+	})
+	return blueNoiseThresholds
+}
+
+// makeBlueNoise returns a size x size threshold map with blue noise characteristics, built like the void and cluster
+// method (Ulichney 1993) does for its dense half: every further point goes into the largest void of the points so far,
+// found as the minimum of their energy, a sum of toroidally wrapped gaussians. The order of the points gives the thresholds.
+func makeBlueNoise(size int) []float64 {
+	const sigma = 1.5
+	n := size * size
+
+	// kernel holds the energy a point contributes at a toroidal offset (dx, dy), at index dy*size+dx.
+	kernel := make([]float64, n)
+	for dy := 0; dy < size; dy++ {
+		for dx := 0; dx < size; dx++ {
+			wx, wy := float64(dx), float64(dy)
+			if dx > size/2 {
+				wx = float64(size - dx)
+			}
+			if dy > size/2 {
+				wy = float64(size - dy)
+			}
+			kernel[dy*size+dx] = math.Exp(-(wx*wx + wy*wy) / (2 * sigma * sigma))
+		}
+	}
+
+	// A tiny deterministic jitter breaks the ties between equally large voids, which would give regular patterns.
+	energy := make([]float64, n)
+	state := uint32(2463534242)
+	for i := range energy {
+		state ^= state << 13
+		state ^= state >> 17
+		state ^= state << 5
+		energy[i] = float64(state) / (1 << 32) * 1e-6
+	}
+
+	used := make([]bool, n)
+	thresholds := make([]float64, n)
+	for rank := 0; rank < n; rank++ {
+		best := -1
+		for i, e := range energy {
+			if !used[i] && (best < 0 || e < energy[best]) {
+				best = i
+			}
+		}
+
+		used[best] = true
+		thresholds[best] = (float64(rank) + 0.5) / float64(n)
+
+		bx, by := best%size, best/size
+		for y := 0; y < size; y++ {
+			row := kernel[((y-by+size)%size)*size:]
+			e := energy[y*size : (y+1)*size]
+			for x := range e {
+				e[x] += row[(x-bx+size)%size]
+			}
+		}
+	}
+
+	return thresholds
+}
+
+// quantizeAt returns v clamped to [0, max] and quantized to an integer with q for the pixel at (x, y).
+// FloydSteinberg needs the neighbouring pixels and rounds instead, see quantizeImage.
+func quantizeAt(v, max float64, q floatcolor.Quantization, x, y int) float64 {
+	switch q {
+	case floatcolor.Bayer:
+		v = clampValue(v, max) + (float64(bayer8[(y&7)<<3|(x&7)])+0.5)/64
+	case floatcolor.BlueNoise:
+		v = clampValue(v, max) + blueNoise()[(y&(blueNoiseSize-1))*blueNoiseSize+(x&(blueNoiseSize-1))]
+	default:
+		return q.Quantize(v, max)
+	}
+	return floatcolor.Truncate.Quantize(v, max)
+}
+
+// clampValue returns v clamped to [0, max], and 0 for NaN.
+func clampValue(v, max float64) float64 {
+	if v > max {
+		return max
+	}
+	if !(v > 0) {
+		return 0
+	}
+	return v
+}
+
+// limitToAlpha limits the color values of quantized premultiplied values to the alpha value (the last one),
+// which the independent quantization of the channels may have exceeded.
+func limitToAlpha(v []float64) {
+	a := v[len(v)-1]
+	for c := 0; c < len(v)-1; c++ {
+		if v[c] > a {
+			v[c] = a
+		}
+	}
+}
+
+// quantizeRGBA64 returns the premultiplied values r, g, b, a of the pixel at (x, y) quantized with q as color.RGBA64.
+func quantizeRGBA64(r, g, b, a float64, q floatcolor.Quantization, x, y int) color.RGBA64 {
+	v := [4]float64{
+		quantizeAt(r*0xffff, 0xffff, q, x, y),
+		quantizeAt(g*0xffff, 0xffff, q, x, y),
+		quantizeAt(b*0xffff, 0xffff, q, x, y),
+		quantizeAt(a*0xffff, 0xffff, q, x, y),
+	}
+	limitToAlpha(v[:])
+
+	return color.RGBA64{R: uint16(v[0]), G: uint16(v[1]), B: uint16(v[2]), A: uint16(v[3])}
+}
+
+// quantizeImage quantizes the channel values of all pixels within r to integers in [0, max] with q and hands them to store.
+// values reads the (up to four) channel values of a pixel, typically in [0.0, 1.0]. With premultiplied the color values are
+// limited to the alpha value (the last channel) after quantization. The pixels are processed in parallel bands of rows,
+// except for FloydSteinberg which diffuses the quantization errors from pixel to pixel.
+func quantizeImage(r image.Rectangle, channels int, max float64, q floatcolor.Quantization, premultiplied bool, values, store func(x, y int, v []float64)) {
+	if q == floatcolor.FloydSteinberg {
+		diffuseErrors(r, channels, max, premultiplied, values, store)
+		return
+	}
+
+	ForEachBand(r, 0, func(band image.Rectangle) {
+		var buffer [4]float64
+		v := buffer[:channels]
+		for y := band.Min.Y; y < band.Max.Y; y++ {
+			for x := band.Min.X; x < band.Max.X; x++ {
+				values(x, y, v)
+				for c := range v {
+					v[c] = quantizeAt(v[c]*max, max, q, x, y)
+				}
+				if premultiplied {
+					limitToAlpha(v)
+				}
+				store(x, y, v)
+			}
+		}
+	})
+}
+
+// diffuseErrors implements the FloydSteinberg quantization of quantizeImage: every value is rounded and the rounding error
+// is distributed to the pixels right (7/16), bottom left (3/16), below (5/16) and bottom right (1/16) of it.
+func diffuseErrors(r image.Rectangle, channels int, max float64, premultiplied bool, values, store func(x, y int, v []float64)) {
+	// Errors of the current and the next row, with one pixel of padding on both sides.
+	n := (r.Dx() + 2) * channels
+	current, next := make([]float64, n), make([]float64, n)
+
+	var buffer [4]float64
+	v := buffer[:channels]
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			values(x, y, v)
+			i := (x - r.Min.X + 1) * channels
+			for c := range v {
+				t := clampValue(v[c]*max, max) + current[i+c]
+				quantized := floatcolor.RoundHalfEven.Quantize(t, max)
+				e := t - quantized
+				current[i+channels+c] += e * 7 / 16
+				next[i-channels+c] += e * 3 / 16
+				next[i+c] += e * 5 / 16
+				next[i+channels+c] += e * 1 / 16
+				v[c] = quantized
+			}
+			if premultiplied {
+				limitToAlpha(v)
+			}
+			store(x, y, v)
+		}
+
+		current, next = next, current
+		for i := range next {
+			next[i] = 0
+		}
+	}
+}
+
+// storeValues returns a store function for quantizeImage writing the quantized values of a pixel into the pixels pix
+// (with the given stride) of an image whose bounds start at min, with 1 or 2 bytes (big-endian) per value.
+func storeValues(pix []uint8, stride int, min image.Point, channels, bytesPerValue int) func(x, y int, v []float64) {
+	n := channels * bytesPerValue
+	return func(x, y int, v []float64) {
+		i := (y-min.Y)*stride + (x-min.X)*n
+		putValues(pix[i:i+n:i+n], v, bytesPerValue)
+	}
+}
+
+// putValues writes the quantized values v into s, with 1 or 2 bytes (big-endian) per value.
+func putValues(s []uint8, v []float64, bytesPerValue int) {
+	if bytesPerValue == 1 {
+		for c := range v {
+			s[c] = uint8(v[c])
+		}
+		return
+	}
+	for c := range v {
+		s[2*c], s[2*c+1] = uint8(uint16(v[c])>>8), uint8(v[c])
+	}
+}
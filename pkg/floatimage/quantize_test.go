@@ -0,0 +1,169 @@
+package floatimage
+
+import (
+	"floatimage/pkg/floatcolor"
+	"image"
+	"math"
+	"testing"
+)
+
+var ditherings = []floatcolor.Quantization{floatcolor.Bayer, floatcolor.BlueNoise, floatcolor.FloydSteinberg}
+
+// gradientImage returns an opaque horizontal gray gradient from 0.2 to 0.22, which spans about five 8 bit levels.
+func gradientImage(q floatcolor.Quantization) *NRGBAF64 {
+	img := NewNRGBAF64(256, 64)
+	img.Quantization = q
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 256; x++ {
+			v := 0.2 + 0.02*float64(x)/255
+			img.SetNRGBAF64(x, y, floatcolor.NRGBAF64{R: v, G: v, B: v, A: 1.0})
+		}
+	}
+	return img
+}
+
+// blockError returns the largest difference between the mean quantized and the mean exact red value (in 8 bit levels)
+// of the size x size blocks of img.
+func blockError(img *NRGBAF64, quantized *image.NRGBA, size int) float64 {
+	largest := 0.0
+	for by := 0; by < img.Rect.Dy(); by += size {
+		for bx := 0; bx < img.Rect.Dx(); bx += size {
+			sum, exact := 0.0, 0.0
+			for y := by; y < by+size; y++ {
+				for x := bx; x < bx+size; x++ {
+					sum += float64(quantized.NRGBAAt(x, y).R)
+					exact += img.NRGBAF64At(x, y).R * 0xff
+				}
+			}
+			largest = math.Max(largest, math.Abs(sum-exact)/float64(size*size))
+		}
+	}
+	return largest
+}
+
+func TestQuantizationGradient(t *testing.T) {
+	truncated := gradientImage(floatcolor.Truncate)
+	if e := blockError(truncated, truncated.AsNRGBA(), 16); e < 0.5 {
+		t.Fatalf("expected banding of the truncated gradient but got a block error of %v", e)
+	}
+
+	for _, q := range ditherings {
+		img := gradientImage(q)
+		nrgba := img.AsNRGBA()
+		if e := blockError(img, nrgba, 16); e > 0.1 {
+			t.Errorf("quantization %d: expected the dithered gradient to keep the mean values but got a block error of %v", q, e)
+		}
+
+		// The dithered pixels only use the two levels next to the exact value
+		for y := 0; y < 64; y++ {
+			for x := 0; x < 256; x++ {
+				c, exact := nrgba.NRGBAAt(x, y), img.NRGBAF64At(x, y).R*0xff
+				if math.Abs(float64(c.R)-exact) >= 1.0 || c.R != c.G || c.A != 0xff {
+					t.Fatalf("quantization %d: unexpected pixel %+v at (%d, %d) for %v", q, c, x, y, exact)
+				}
+			}
+		}
+
+		if again := img.AsNRGBA(); string(again.Pix) != string(nrgba.Pix) {
+			t.Errorf("quantization %d: expected deterministic dithering", q)
+		}
+	}
+}
+
+func TestRoundHalfEven(t *testing.T) {
+	for v, expected := range map[float64]float64{2.5: 2, 3.5: 4, 3.49: 3, -1: 0, 300: 255, math.NaN(): 0} {
+		if got := floatcolor.RoundHalfEven.Quantize(v, 255); got != expected {
+			t.Errorf("expected %v for %v but got %v", expected, v, got)
+		}
+	}
+	if got := floatcolor.RoundHalfAwayFromZero.Quantize(2.5, 255); got != 3 {
+		t.Errorf("expected value rounded half away from zero but got %v", got)
+	}
+	if got := floatcolor.Truncate.Quantize(3.99, 255); got != 3 {
+		t.Errorf("expected truncated value but got %v", got)
+	}
+
+	img := NewGrayF64(4, 1)
+	for x := range img.Pix {
+		img.Pix[x] = float64(x) + 0.5
+	}
+	img.Quantization = floatcolor.RoundHalfEven
+	ranged := img.AsNRGBAForRange(0, 0xff)
+	for x, expected := range []uint8{0, 2, 2, 4} {
+		if c := ranged.NRGBAAt(x, 0); c.R != expected {
+			t.Errorf("expected %d at %d but got %+v", expected, x, c)
+		}
+	}
+}
+
+func TestPreciseQuantization(t *testing.T) {
+	precise := gradientImage(floatcolor.Truncate)
+	precise.Precise = true
+	rounded := gradientImage(floatcolor.RoundHalfAwayFromZero)
+
+	if string(precise.AsNRGBA().Pix) != string(rounded.AsNRGBA().Pix) || string(precise.AsRGBA().Pix) != string(rounded.AsRGBA().Pix) {
+		t.Errorf("expected Precise to quantize like RoundHalfAwayFromZero")
+	}
+	if precise.RGBA64At(100, 3) != rounded.RGBA64At(100, 3) {
+		t.Errorf("expected Precise to quantize like RoundHalfAwayFromZero")
+	}
+
+	// Precise keeps rounding halves up, like math.Round
+	img := NewGrayF64(4, 1)
+	for x := range img.Pix {
+		img.Pix[x] = float64(x) + 0.5
+	}
+	img.Precise = true
+	ranged := img.AsNRGBAForRange(0, 0xff)
+	for x, expected := range []uint8{1, 2, 3, 4} {
+		if c := ranged.NRGBAAt(x, 0); c.R != expected {
+			t.Errorf("expected %d at %d but got %+v", expected, x, c)
+		}
+	}
+	if c := (floatcolor.GrayF64{Y: 2.5 / 0xff, Precise: true}).AsNRGBA(); c.R != 3 {
+		t.Errorf("expected value rounded half away from zero but got %+v", c)
+	}
+
+	// Precise does not override an explicit quantization
+	precise.Quantization = floatcolor.Bayer
+	if string(precise.AsNRGBA().Pix) != string(gradientImage(floatcolor.Bayer).AsNRGBA().Pix) {
+		t.Errorf("expected the quantization to take precedence over Precise")
+	}
+
+	c := floatcolor.NRGBAF64{R: 0.5, A: 1.0, Precise: true}
+	if r, _, _, _ := c.RGBA(); r != 0x8000 {
+		t.Errorf("expected rounded color value but got %x", r)
+	}
+	c.SetQuantization(floatcolor.Truncate)
+	c.Precise = false
+	if r, _, _, _ := c.RGBA(); r != 0x7fff {
+		t.Errorf("expected truncated color value but got %x", r)
+	}
+}
+
+func TestQuantizationPremultiplied(t *testing.T) {
+	for _, q := range append([]floatcolor.Quantization{floatcolor.Truncate, floatcolor.RoundHalfEven, floatcolor.RoundHalfAwayFromZero}, ditherings...) {
+		img := NewRGBAF32(64, 64)
+		img.Quantization = q
+		for y := 0; y < 64; y++ {
+			for x := 0; x < 64; x++ {
+				a := float64(y) / 63 * 0.01
+				img.SetNRGBAF64(x, y, floatcolor.NRGBAF64{R: 1.0, G: float64(x) / 63, B: 0.5, A: a})
+			}
+		}
+
+		rgba := img.AsRGBA()
+		for i := 0; i < len(rgba.Pix); i += 4 {
+			if s := rgba.Pix[i : i+4]; s[0] > s[3] || s[1] > s[3] || s[2] > s[3] {
+				t.Fatalf("quantization %d: expected color values not above alpha but got %v", q, s)
+			}
+		}
+		for y := 0; y < 64; y++ {
+			for x := 0; x < 64; x++ {
+				if c := img.RGBA64At(x, y); c.R > c.A || c.G > c.A || c.B > c.A {
+					t.Fatalf("quantization %d: expected color values not above alpha but got %+v", q, c)
+				}
+			}
+		}
+	}
+}
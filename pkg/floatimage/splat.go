@@ -0,0 +1,104 @@
+package floatimage
+
+import (
+	"floatimage/pkg/floatcolor"
+	"image"
+	"image/draw"
+	"runtime"
+	"sync"
+)
+
+// stripesPerWorker is the number of locks of a Splatter per available CPU, enough to make it unlikely
+// that two goroutines splatting to different pixels wait for each other.
+const stripesPerWorker = 64
+
+// stripe is a lock padded to its own cache line, so goroutines holding neighbouring locks do not slow each other down.
+type stripe struct {
+	sync.Mutex
+	_ [56]byte
+}
+
+// Splatter adds color contributions to arbitrary pixels of an image from many goroutines at the same time,
+// as needed by light tracing and bidirectional rendering methods. The pixels are guarded by striped locks:
+// every pixel belongs to one of a fixed number of locks, so goroutines only wait for each other when they
+// add to pixels sharing a lock at the same moment.
+//
+// All writes to the image have to go through the splatter while goroutines are adding to it.
+// Reading the image is safe once all goroutines have returned.
+type Splatter struct {
+	rect    image.Rectangle
+	width   int
+	stripes []stripe
+	mask    int
+	add     func(x, y int, c floatcolor.RGBAF64)
+}
+
+// NewSplatter returns a splatter adding to the pixels of img.
+// Images that are not one of the float image types of this package are updated through At and Set.
+func NewSplatter(img draw.Image) *Splatter {
+	n := 1
+	for n < stripesPerWorker*runtime.GOMAXPROCS(0) {
+		n *= 2
+	}
+
+	s := &Splatter{
+		rect:    img.Bounds(),
+		width:   img.Bounds().Dx(),
+		stripes: make([]stripe, n),
+		mask:    n - 1,
+	}
+
+	switch p := img.(type) {
+	case *NRGBAF64:
+		s.add = func(x, y int, c floatcolor.RGBAF64) {
+			i := p.PixOffset(x, y)
+			d := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+			d[0], d[1], d[2], d[3] = d[0]+c.R, d[1]+c.G, d[2]+c.B, d[3]+c.A
+		}
+	case *NRGBAF32:
+		s.add = func(x, y int, c floatcolor.RGBAF64) {
+			i := p.PixOffset(x, y)
+			d := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+			d[0], d[1], d[2], d[3] = d[0]+float32(c.R), d[1]+float32(c.G), d[2]+float32(c.B), d[3]+float32(c.A)
+		}
+	case *RGBAF64:
+		s.add = func(x, y int, c floatcolor.RGBAF64) {
+			i := p.PixOffset(x, y)
+			d := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+			d[0], d[1], d[2], d[3] = d[0]+c.R, d[1]+c.G, d[2]+c.B, d[3]+c.A
+		}
+	case *RGBAF32:
+		s.add = func(x, y int, c floatcolor.RGBAF64) {
+			i := p.PixOffset(x, y)
+			d := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+			d[0], d[1], d[2], d[3] = d[0]+float32(c.R), d[1]+float32(c.G), d[2]+float32(c.B), d[3]+float32(c.A)
+		}
+	case *GrayF64:
+		s.add = func(x, y int, c floatcolor.RGBAF64) {
+			p.Pix[p.PixOffset(x, y)] += floatcolor.Luminance(c.R, c.G, c.B)
+		}
+	default:
+		s.add = func(x, y int, c floatcolor.RGBAF64) {
+			d := floatcolor.RGBAF64Model.Convert(img.At(x, y)).(floatcolor.RGBAF64)
+			img.Set(x, y, floatcolor.RGBAF64{R: d.R + c.R, G: d.G + c.G, B: d.B + c.B, A: d.A + c.A})
+		}
+	}
+
+	return s
+}
+
+// Add adds the channel values of c to the stored channel values of pixel (x, y). The values are added as they are,
+// without converting between premultiplied and ordinary alpha, which suits accumulating radiance with any image type.
+// Gray images add the luminance of c. Pixels outside the image bounds are ignored.
+// Add is safe for concurrent use. The sum of all contributions to a pixel does not depend on the order
+// they are added in, apart from floating point rounding.
+func (s *Splatter) Add(x, y int, c floatcolor.RGBAF64) {
+	if !(image.Point{X: x, Y: y}.In(s.rect)) {
+		return
+	}
+
+	lock := &s.stripes[((y-s.rect.Min.Y)*s.width+x-s.rect.Min.X)&s.mask]
+	lock.Lock()
+	s.add(x, y, c)
+	lock.Unlock()
+}
@@ -0,0 +1,141 @@
+package floatimage
+
+import (
+	"floatimage/pkg/floatcolor"
+	"image"
+	"image/draw"
+	"math"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+// splatConcurrently adds goroutines x count contributions to img, each goroutine to pseudo random pixels
+// including the same hot pixel (1, 1) and pixels outside the bounds, and returns the expected number of contributions per pixel.
+func splatConcurrently(img draw.Image, goroutines, count int) map[image.Point]int {
+	s := NewSplatter(img)
+	r := img.Bounds()
+
+	expected := make(map[image.Point]int)
+	points := make([][]image.Point, goroutines)
+	for g := range points {
+		rnd := rand.New(rand.NewSource(int64(g)))
+		for i := 0; i < count; i++ {
+			p := image.Point{X: r.Min.X + rnd.Intn(r.Dx()+2) - 1, Y: r.Min.Y + rnd.Intn(r.Dy()+2) - 1}
+			if i%4 == 0 {
+				p = r.Min.Add(image.Point{X: 1, Y: 1})
+			}
+			points[g] = append(points[g], p)
+			if p.In(r) {
+				expected[p]++
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	for g := range points {
+		wg.Add(1)
+		go func(points []image.Point) {
+			defer wg.Done()
+			for _, p := range points {
+				s.Add(p.X, p.Y, floatcolor.RGBAF64{R: 1, G: 0.5, B: 0.25, A: 0.125})
+			}
+		}(points[g])
+	}
+	wg.Wait()
+
+	return expected
+}
+
+func TestSplatter(t *testing.T) {
+	images := map[string]draw.Image{
+		"NRGBAF64": NewNRGBAF64WithBounds(-4, 2, 12, 10),
+		"NRGBAF32": NewNRGBAF32WithBounds(-4, 2, 12, 10),
+		"RGBAF64":  NewRGBAF64WithBounds(-4, 2, 12, 10),
+		"RGBAF32":  NewRGBAF32WithBounds(-4, 2, 12, 10),
+		"GrayF64":  NewGrayF64WithBounds(-4, 2, 12, 10),
+	}
+
+	for name, img := range images {
+		expected := splatConcurrently(img, 8, 2000)
+		r := img.Bounds()
+
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				n := float64(expected[image.Point{X: x, Y: y}])
+
+				want := [4]float64{n, n / 2, n / 4, n / 8}
+				if _, ok := img.(*GrayF64); ok {
+					want = [4]float64{n * floatcolor.Luminance(1, 0.5, 0.25)}
+				}
+
+				got := storedValues(img, x, y)
+				if got != want && !(name == "GrayF64" && math.Abs(got[0]-want[0]) < 1e-9*want[0]) {
+					t.Fatalf("%s: expected sum %v at (%d, %d) but got %v", name, want, x, y, got)
+				}
+			}
+		}
+	}
+}
+
+// storedValues returns the channel values of pixel (x, y) of a float image as they are stored.
+func storedValues(img draw.Image, x, y int) [4]float64 {
+	switch p := img.(type) {
+	case *NRGBAF64:
+		c := p.NRGBAF64At(x, y)
+		return [4]float64{c.R, c.G, c.B, c.A}
+	case *NRGBAF32:
+		c := p.NRGBAF32At(x, y)
+		return [4]float64{float64(c.R), float64(c.G), float64(c.B), float64(c.A)}
+	case *RGBAF64:
+		c := p.RGBAF64At(x, y)
+		return [4]float64{c.R, c.G, c.B, c.A}
+	case *RGBAF32:
+		c := p.RGBAF32At(x, y)
+		return [4]float64{float64(c.R), float64(c.G), float64(c.B), float64(c.A)}
+	case *GrayF64:
+		return [4]float64{p.GrayF64At(x, y).Y}
+	}
+	return [4]float64{}
+}
+
+func TestSplatterOtherImage(t *testing.T) {
+	img := image.NewRGBA64(image.Rect(0, 0, 4, 4))
+	s := NewSplatter(img)
+	s.Add(2, 3, floatcolor.RGBAF64{R: 0.5, A: 0.5})
+	s.Add(2, 3, floatcolor.RGBAF64{R: 0.5, A: 0.5})
+
+	if c := img.RGBA64At(2, 3); c.R < 0xfffe || c.G != 0 || c.A < 0xfffe { // 16 bit values are truncated
+		t.Errorf("expected summed color through Set but got %+v", c)
+	}
+}
+
+// benchmarkSplat adds contributions to pseudo random pixels of a 512x512 image from parallel goroutines.
+func benchmarkSplat(b *testing.B, add func(x, y int, c floatcolor.RGBAF64)) {
+	c := floatcolor.RGBAF64{R: 0.1, G: 0.2, B: 0.3, A: 1}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := rand.Uint32()
+		for pb.Next() {
+			i = i*1664525 + 1013904223
+			add(int(i>>7)&511, int(i>>20)&511, c)
+		}
+	})
+}
+
+func BenchmarkSplatter(b *testing.B) {
+	s := NewSplatter(NewRGBAF32(512, 512))
+	benchmarkSplat(b, s.Add)
+}
+
+func BenchmarkSplatMutex(b *testing.B) {
+	img := NewRGBAF32(512, 512)
+	var mutex sync.Mutex
+	benchmarkSplat(b, func(x, y int, c floatcolor.RGBAF64) {
+		mutex.Lock()
+		i := img.PixOffset(x, y)
+		d := img.Pix[i : i+4 : i+4]
+		d[0], d[1], d[2], d[3] = d[0]+float32(c.R), d[1]+float32(c.G), d[2]+float32(c.B), d[3]+float32(c.A)
+		mutex.Unlock()
+	})
+}
@@ -0,0 +1,385 @@
+package floatimage
+
+import (
+	"floatimage/pkg/floatcolor"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// DefaultTileSize is a tile width and height that keeps a tile of four float32 channels within 64 KiB,
+// small enough to stay in the cache of a CPU core while it is worked on.
+const DefaultTileSize = 64
+
+// tileImage is implemented by all float image types of this package.
+type tileImage interface {
+	FloatImage
+	draw.RGBA64Image
+	NRGBAF64At(x, y int) floatcolor.NRGBAF64
+	SetNRGBAF64(x, y int, c floatcolor.NRGBAF64)
+}
+
+// Tiled is a float image stored as a grid of tiles instead of rows. Every tile is a separate image of one of
+// the float image types of this package, so the pixels of a tile are close together in memory. This suits renderers
+// and filters that work on one tile at a time (in parallel) better than the row-major layout of the other images.
+//
+// The tiles are aligned to the top left corner of the image. Tiles along the right and bottom edges are smaller
+// if the image size is not a multiple of the tile size.
+type Tiled struct {
+	// Rect is the image's bounds.
+	Rect image.Rectangle
+	// TileWidth and TileHeight are the size of the tiles.
+	TileWidth, TileHeight int
+
+	columns, rows int
+	tiles         []tileImage
+	prototype     FloatImage
+}
+
+// NewTiled returns a new tiled image with bounds r, whose tiles are images of the same type as like
+// (and with the same quantization, see NewLike), or as the tiles of like if it is a tiled image itself.
+// Tile sizes that are not positive are replaced by DefaultTileSize.
+func NewTiled(like image.Image, r image.Rectangle, tileWidth, tileHeight int) *Tiled {
+	if t, ok := like.(*Tiled); ok {
+		like = t.prototype
+	}
+	if tileWidth <= 0 {
+		tileWidth = DefaultTileSize
+	}
+	if tileHeight <= 0 {
+		tileHeight = DefaultTileSize
+	}
+
+	r = r.Canon()
+	t := &Tiled{
+		Rect:       r,
+		TileWidth:  tileWidth,
+		TileHeight: tileHeight,
+		columns:    (r.Dx() + tileWidth - 1) / tileWidth,
+		rows:       (r.Dy() + tileHeight - 1) / tileHeight,
+	}
+
+	t.tiles = make([]tileImage, t.columns*t.rows)
+	for i := range t.tiles {
+		t.tiles[i] = NewLike(like, t.tileBounds(i%t.columns, i/t.columns)).(tileImage)
+	}
+	t.prototype = NewLike(like, image.Rectangle{})
+
+	return t
+}
+
+// NewTiledFrom returns a tiled copy of img, with tiles of the same type as img.
+func NewTiledFrom(img image.Image, tileWidth, tileHeight int) *Tiled {
+	t := NewTiled(img, img.Bounds(), tileWidth, tileHeight)
+	t.ForEachTile(0, func(tile FloatImage) {
+		copyRect(tile.(tileImage), img, tile.Bounds())
+	})
+	return t
+}
+
+func (t *Tiled) ColorModel() color.Model { return t.prototype.ColorModel() }
+
+func (t *Tiled) Bounds() image.Rectangle { return t.Rect }
+
+// Tiles returns the number of tile columns and rows.
+func (t *Tiled) Tiles() (columns, rows int) {
+	return t.columns, t.rows
+}
+
+// Tile returns the tile in the given column and row, or nil if there is no such tile.
+// The tile shares its pixels with the tiled image and has the bounds of the part of the image it covers.
+func (t *Tiled) Tile(column, row int) FloatImage {
+	if column < 0 || column >= t.columns || row < 0 || row >= t.rows {
+		return nil
+	}
+	return t.tiles[row*t.columns+column]
+}
+
+// ForEachTile calls f for every tile of the image, on up to workers goroutines at the same time
+// (the default number of workers if workers <= 0, see SetWorkers). f may change the pixels of the tile it is given.
+func (t *Tiled) ForEachTile(workers int, f func(tile FloatImage)) {
+	ParallelFor(len(t.tiles), workers, func(start, end int) {
+		for i := start; i < end; i++ {
+			f(t.tiles[i])
+		}
+	})
+}
+
+// Flatten returns a copy of the image as a row-major float image of the same type as the tiles.
+func (t *Tiled) Flatten() FloatImage {
+	result := NewLike(t.prototype, t.Rect).(tileImage)
+	t.ForEachTile(0, func(tile FloatImage) {
+		copyRect(result, tile, tile.Bounds())
+	})
+	return result
+}
+
+func (t *Tiled) tileBounds(column, row int) image.Rectangle {
+	min := t.Rect.Min.Add(image.Point{X: column * t.TileWidth, Y: row * t.TileHeight})
+	return image.Rectangle{Min: min, Max: min.Add(image.Point{X: t.TileWidth, Y: t.TileHeight})}.Intersect(t.Rect)
+}
+
+// tileAt returns the tile containing pixel (x, y), or nil if the pixel is outside the image.
+func (t *Tiled) tileAt(x, y int) tileImage {
+	if !(image.Point{X: x, Y: y}.In(t.Rect)) {
+		return nil
+	}
+	return t.tiles[(y-t.Rect.Min.Y)/t.TileHeight*t.columns+(x-t.Rect.Min.X)/t.TileWidth]
+}
+
+func (t *Tiled) At(x, y int) color.Color {
+	tile := t.tileAt(x, y)
+	if tile == nil {
+		return color.RGBA64{}
+	}
+	return tile.At(x, y)
+}
+
+func (t *Tiled) RGBA64At(x, y int) color.RGBA64 {
+	tile := t.tileAt(x, y)
+	if tile == nil {
+		return color.RGBA64{}
+	}
+	return tile.RGBA64At(x, y)
+}
+
+func (t *Tiled) Set(x, y int, c color.Color) {
+	if tile := t.tileAt(x, y); tile != nil {
+		tile.Set(x, y, c)
+	}
+}
+
+func (t *Tiled) SetRGBA64(x, y int, c color.RGBA64) {
+	if tile := t.tileAt(x, y); tile != nil {
+		tile.SetRGBA64(x, y, c)
+	}
+}
+
+// NRGBAF64At returns the color of the pixel at (x, y) as a floatcolor.NRGBAF64, see the NRGBAF64At method of the tiles.
+func (t *Tiled) NRGBAF64At(x, y int) floatcolor.NRGBAF64 {
+	tile := t.tileAt(x, y)
+	if tile == nil {
+		return floatcolor.NRGBAF64{}
+	}
+	return tile.NRGBAF64At(x, y)
+}
+
+// SetNRGBAF64 sets the pixel at (x, y) to c, see the SetNRGBAF64 method of the tiles.
+func (t *Tiled) SetNRGBAF64(x, y int, c floatcolor.NRGBAF64) {
+	if tile := t.tileAt(x, y); tile != nil {
+		tile.SetNRGBAF64(x, y, c)
+	}
+}
+
+func (t *Tiled) AsRGBA() *image.RGBA {
+	rgbaImage := image.NewRGBA(t.Rect)
+	t.convertTiles(rgbaImage.Pix, rgbaImage.Stride, 4, func(tile FloatImage) ([]uint8, int) {
+		c := tile.AsRGBA()
+		return c.Pix, c.Stride
+	})
+	return rgbaImage
+}
+
+func (t *Tiled) AsNRGBA() *image.NRGBA {
+	nrgbaImage := image.NewNRGBA(t.Rect)
+	t.convertTiles(nrgbaImage.Pix, nrgbaImage.Stride, 4, func(tile FloatImage) ([]uint8, int) {
+		c := tile.AsNRGBA()
+		return c.Pix, c.Stride
+	})
+	return nrgbaImage
+}
+
+func (t *Tiled) AsRGBAForRange(min, max float64) *image.RGBA {
+	rgbaImage := image.NewRGBA(t.Rect)
+	t.convertTiles(rgbaImage.Pix, rgbaImage.Stride, 4, func(tile FloatImage) ([]uint8, int) {
+		c := tile.AsRGBAForRange(min, max)
+		return c.Pix, c.Stride
+	})
+	return rgbaImage
+}
+
+func (t *Tiled) AsNRGBAForRange(min, max float64) *image.NRGBA {
+	nrgbaImage := image.NewNRGBA(t.Rect)
+	t.convertTiles(nrgbaImage.Pix, nrgbaImage.Stride, 4, func(tile FloatImage) ([]uint8, int) {
+		c := tile.AsNRGBAForRange(min, max)
+		return c.Pix, c.Stride
+	})
+	return nrgbaImage
+}
+
+func (t *Tiled) AsRGBA64() *image.RGBA64 {
+	result := image.NewRGBA64(t.Rect)
+	t.convertTiles(result.Pix, result.Stride, 8, func(tile FloatImage) ([]uint8, int) {
+		c := tile.AsRGBA64()
+		return c.Pix, c.Stride
+	})
+	return result
+}
+
+func (t *Tiled) AsNRGBA64() *image.NRGBA64 {
+	result := image.NewNRGBA64(t.Rect)
+	t.convertTiles(result.Pix, result.Stride, 8, func(tile FloatImage) ([]uint8, int) {
+		c := tile.AsNRGBA64()
+		return c.Pix, c.Stride
+	})
+	return result
+}
+
+func (t *Tiled) AsRGBA64ForRange(min, max float64) *image.RGBA64 {
+	result := image.NewRGBA64(t.Rect)
+	t.convertTiles(result.Pix, result.Stride, 8, func(tile FloatImage) ([]uint8, int) {
+		c := tile.AsRGBA64ForRange(min, max)
+		return c.Pix, c.Stride
+	})
+	return result
+}
+
+func (t *Tiled) AsNRGBA64ForRange(min, max float64) *image.NRGBA64 {
+	result := image.NewNRGBA64(t.Rect)
+	t.convertTiles(result.Pix, result.Stride, 8, func(tile FloatImage) ([]uint8, int) {
+		c := tile.AsNRGBA64ForRange(min, max)
+		return c.Pix, c.Stride
+	})
+	return result
+}
+
+func (t *Tiled) AsGray() *image.Gray {
+	result := image.NewGray(t.Rect)
+	t.convertTiles(result.Pix, result.Stride, 1, func(tile FloatImage) ([]uint8, int) {
+		c := tile.AsGray()
+		return c.Pix, c.Stride
+	})
+	return result
+}
+
+func (t *Tiled) AsGray16() *image.Gray16 {
+	result := image.NewGray16(t.Rect)
+	t.convertTiles(result.Pix, result.Stride, 2, func(tile FloatImage) ([]uint8, int) {
+		c := tile.AsGray16()
+		return c.Pix, c.Stride
+	})
+	return result
+}
+
+func (t *Tiled) AsGrayForRange(min, max float64) *image.Gray {
+	result := image.NewGray(t.Rect)
+	t.convertTiles(result.Pix, result.Stride, 1, func(tile FloatImage) ([]uint8, int) {
+		c := tile.AsGrayForRange(min, max)
+		return c.Pix, c.Stride
+	})
+	return result
+}
+
+func (t *Tiled) AsGray16ForRange(min, max float64) *image.Gray16 {
+	result := image.NewGray16(t.Rect)
+	t.convertTiles(result.Pix, result.Stride, 2, func(tile FloatImage) ([]uint8, int) {
+		c := tile.AsGray16ForRange(min, max)
+		return c.Pix, c.Stride
+	})
+	return result
+}
+
+// convertTiles converts every tile with convert and copies the result with bytesPerPixel bytes per pixel into pix,
+// the pixels (with the given stride) of an image with the bounds of the tiled image. Every tile is quantized on its own,
+// so the Floyd-Steinberg error diffusion does not cross the tile borders.
+func (t *Tiled) convertTiles(pix []uint8, stride int, bytesPerPixel int, convert func(tile FloatImage) ([]uint8, int)) {
+	t.ForEachTile(0, func(tile FloatImage) {
+		r := tile.Bounds()
+		tilePix, tileStride := convert(tile)
+		i := (r.Min.Y-t.Rect.Min.Y)*stride + (r.Min.X-t.Rect.Min.X)*bytesPerPixel
+		copyRows(pix[i:], stride, tilePix, tileStride, bytesPerPixel*r.Dx(), r.Dy())
+	})
+}
+
+// Opaque scans the entire image and reports whether it is fully opaque.
+func (t *Tiled) Opaque() bool {
+	for _, tile := range t.tiles {
+		if o, ok := tile.(interface{ Opaque() bool }); ok && !o.Opaque() {
+			return false
+		}
+	}
+	return true
+}
+
+// ProcessTiles splits img into tiles of (at most) tileWidth x tileHeight pixels and calls f for each tile
+// on up to workers goroutines at the same time (the default number of workers if workers <= 0, see SetWorkers).
+// f works on a contiguous copy of the tile, a float image of the same type as img with the bounds of the tile,
+// which is written back to img when f returns. This gives renderers and filters working on the row-major float images
+// the cache behaviour of a tiled image. Images that are not float images of this package are processed sequentially
+// with RGBAF64 tiles, as their Set method need not be safe for concurrent use.
+func ProcessTiles(img draw.Image, tileWidth, tileHeight int, workers int, f func(tile FloatImage)) {
+	if _, ok := img.(tileImage); !ok {
+		workers = 1
+	}
+	// The hidden planes are allocated before the tiles are copied back concurrently
+	switch p := img.(type) {
+	case *RGBAF64:
+		keepHidden(&p.Hidden, p.Pix)
+	case *RGBAF32:
+		keepHidden(&p.Hidden, p.Pix)
+	}
+
+	var like image.Image = img
+	if t, ok := img.(*Tiled); ok {
+		like = t.prototype
+	}
+
+	ForEachTile(img.Bounds(), tileWidth, tileHeight, workers, func(r image.Rectangle) {
+		tile := NewLike(like, r).(tileImage)
+		copyRect(tile, img, r)
+		f(tile)
+		copyRect(img, tile, r)
+	})
+}
+
+// copyRect copies the pixels within r from src to dst. Images of the same float image type are copied row by row,
+// other images go through At and Set.
+func copyRect(dst draw.Image, src image.Image, r image.Rectangle) {
+	r = r.Intersect(dst.Bounds()).Intersect(src.Bounds())
+	if r.Empty() {
+		return
+	}
+
+	switch d := dst.(type) {
+	case *NRGBAF64:
+		if s, ok := src.(*NRGBAF64); ok {
+			copyRows(d.Pix[d.PixOffset(r.Min.X, r.Min.Y):], d.Stride, s.Pix[s.PixOffset(r.Min.X, r.Min.Y):], s.Stride, 4*r.Dx(), r.Dy())
+			return
+		}
+	case *NRGBAF32:
+		if s, ok := src.(*NRGBAF32); ok {
+			copyRows(d.Pix[d.PixOffset(r.Min.X, r.Min.Y):], d.Stride, s.Pix[s.PixOffset(r.Min.X, r.Min.Y):], s.Stride, 4*r.Dx(), r.Dy())
+			return
+		}
+	case *RGBAF64:
+		if s, ok := src.(*RGBAF64); ok {
+			copyRows(d.Pix[d.PixOffset(r.Min.X, r.Min.Y):], d.Stride, s.Pix[s.PixOffset(r.Min.X, r.Min.Y):], s.Stride, 4*r.Dx(), r.Dy())
+			copyHidden(&d.Hidden, d.Pix, d.PixOffset(r.Min.X, r.Min.Y), d.Stride, s.Hidden, s.PixOffset(r.Min.X, r.Min.Y), s.Stride, r)
+			return
+		}
+	case *RGBAF32:
+		if s, ok := src.(*RGBAF32); ok {
+			copyRows(d.Pix[d.PixOffset(r.Min.X, r.Min.Y):], d.Stride, s.Pix[s.PixOffset(r.Min.X, r.Min.Y):], s.Stride, 4*r.Dx(), r.Dy())
+			copyHidden(&d.Hidden, d.Pix, d.PixOffset(r.Min.X, r.Min.Y), d.Stride, s.Hidden, s.PixOffset(r.Min.X, r.Min.Y), s.Stride, r)
+			return
+		}
+	case *GrayF64:
+		if s, ok := src.(*GrayF64); ok {
+			copyRows(d.Pix[d.PixOffset(r.Min.X, r.Min.Y):], d.Stride, s.Pix[s.PixOffset(r.Min.X, r.Min.Y):], s.Stride, r.Dx(), r.Dy())
+			return
+		}
+	}
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			dst.Set(x, y, src.At(x, y))
+		}
+	}
+}
+
+// copyRows copies rows values of length n from src to dst, with the given strides between the rows.
+func copyRows[T uint8 | float32 | float64](dst []T, dstStride int, src []T, srcStride int, n, rows int) {
+	for y := 0; y < rows; y++ {
+		copy(dst[y*dstStride:y*dstStride+n], src[y*srcStride:y*srcStride+n])
+	}
+}
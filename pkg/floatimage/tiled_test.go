@@ -0,0 +1,119 @@
+package floatimage
+
+import (
+	"floatimage/pkg/floatcolor"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func testRowMajorImage() *NRGBAF32 {
+	img := NewNRGBAF32WithBounds(-3, 5, 34, 26)
+	for i := range img.Pix {
+		img.Pix[i] = float32(i%29) / 28
+	}
+	return img
+}
+
+func TestTiled(t *testing.T) {
+	img := testRowMajorImage()
+	tiled := NewTiledFrom(img, 8, 6)
+
+	if tiled.Bounds() != img.Rect || tiled.ColorModel() != floatcolor.NRGBAF32Model {
+		t.Fatalf("expected bounds %v and NRGBAF32 model but got %v and %v", img.Rect, tiled.Bounds(), tiled.ColorModel())
+	}
+	if columns, rows := tiled.Tiles(); columns != 5 || rows != 4 {
+		t.Fatalf("expected 5x4 tiles but got %dx%d", columns, rows)
+	}
+	if r := tiled.Tile(4, 3).Bounds(); r != image.Rect(29, 23, 34, 26) {
+		t.Errorf("expected smaller bottom right tile %v but got %v", image.Rect(29, 23, 34, 26), r)
+	}
+	if tiled.Tile(5, 0) != nil {
+		t.Errorf("expected no tile outside the grid")
+	}
+
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			if tiled.At(x, y) != img.At(x, y) || tiled.RGBA64At(x, y) != img.RGBA64At(x, y) {
+				t.Fatalf("expected the same pixel at (%d, %d) but got %v and %v", x, y, tiled.At(x, y), img.At(x, y))
+			}
+		}
+	}
+
+	flat := tiled.Flatten().(*NRGBAF32)
+	for i := range img.Pix {
+		if flat.Pix[i] != img.Pix[i] {
+			t.Fatalf("expected flattened image to be the same as the original at %d", i)
+		}
+	}
+
+	asNRGBA, tiledAsNRGBA := img.AsNRGBAForRange(0.2, 0.8), tiled.AsNRGBAForRange(0.2, 0.8)
+	asRGBA, tiledAsRGBA := img.AsRGBA(), tiled.AsRGBA()
+	for i := range asNRGBA.Pix {
+		if asNRGBA.Pix[i] != tiledAsNRGBA.Pix[i] || asRGBA.Pix[i] != tiledAsRGBA.Pix[i] {
+			t.Fatalf("expected conversions of the tiled image to be the same as of the original at %d", i)
+		}
+	}
+
+	tiled.Set(33, 25, color.Transparent)
+	if tiled.Opaque() || img.At(33, 25) == tiled.At(33, 25) {
+		t.Errorf("expected tiled copy to be changed independent of the original")
+	}
+}
+
+func TestTiledForEachTile(t *testing.T) {
+	tiled := NewTiled(NewRGBAF64(0, 0), image.Rect(0, 0, 20, 10), 0, 0)
+	if tiled.TileWidth != DefaultTileSize || tiled.TileHeight != DefaultTileSize {
+		t.Errorf("expected default tile size but got %dx%d", tiled.TileWidth, tiled.TileHeight)
+	}
+
+	tiled = NewTiled(NewRGBAF64(0, 0), image.Rect(0, 0, 20, 10), 3, 4)
+	tiled.ForEachTile(4, func(tile FloatImage) {
+		p := tile.(*RGBAF64)
+		for i := range p.Pix {
+			p.Pix[i] = 1.0
+		}
+	})
+
+	if !tiled.Opaque() {
+		t.Errorf("expected all tiles to be written")
+	}
+	like, ok := NewLike(tiled, image.Rect(0, 0, 20, 10)).(*Tiled)
+	if !ok || like.TileWidth != 3 || like.TileHeight != 4 {
+		t.Fatalf("expected images like a tiled image to be tiled with the same tile size but got %T", like)
+	}
+	if _, ok := like.Tile(0, 0).(*RGBAF64); !ok {
+		t.Errorf("expected images like a tiled image to have the type of its tiles but got %T", like.Tile(0, 0))
+	}
+}
+
+func TestProcessTiles(t *testing.T) {
+	img := testRowMajorImage()
+	expected := testRowMajorImage()
+	invert := func(c floatcolor.NRGBAF64) floatcolor.NRGBAF64 {
+		return floatcolor.NRGBAF64{R: 1 - c.R, G: 1 - c.G, B: 1 - c.B, A: c.A}
+	}
+	Map(expected, invert)
+
+	ProcessTiles(img, 16, 7, 4, func(tile FloatImage) {
+		p := tile.(*NRGBAF32)
+		if p.Stride != 4*p.Rect.Dx() {
+			t.Errorf("expected contiguous tile but got stride %d for bounds %v", p.Stride, p.Rect)
+		}
+		Map(p, invert)
+	})
+
+	for i := range img.Pix {
+		if img.Pix[i] != expected.Pix[i] {
+			t.Fatalf("expected processed tiles to be written back at %d", i)
+		}
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, 5, 5))
+	ProcessTiles(rgba, 2, 2, 0, func(tile FloatImage) {
+		tile.(*RGBAF64).Set(tile.Bounds().Min.X, tile.Bounds().Min.Y, color.White)
+	})
+	if rgba.RGBAAt(4, 2) != (color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}) || rgba.RGBAAt(3, 3).A != 0 {
+		t.Errorf("expected tiles of other images to be written back")
+	}
+}
@@ -0,0 +1,225 @@
+package gradient
+
+import (
+	"floatimage/pkg/floatcolor"
+	"math"
+	"sort"
+)
+
+// Space is the color space in which gradient colors are interpolated.
+type Space int
+
+const (
+	// LinearRGB interpolates the linear red, green, and blue values.
+	LinearRGB Space = iota
+	// SRGB interpolates gamma encoded sRGB red, green, and blue values.
+	SRGB
+	// OKLab interpolates in the perceptually uniform OKLab color space.
+	OKLab
+	// OKLCh interpolates lightness, chroma, and hue of OKLab, where hue takes the shortest path around the hue circle.
+	OKLCh
+)
+
+// Extend determines how a gradient is extended for positions outside [0.0, 1.0].
+type Extend int
+
+const (
+	// Pad uses the color of the first or last stop outside the gradient range.
+	Pad Extend = iota
+	// Repeat repeats the gradient.
+	Repeat
+	// Reflect repeats the gradient, every other time in reverse.
+	Reflect
+)
+
+// Easing maps a position in [0.0, 1.0] between two stops to an interpolation amount in [0.0, 1.0].
+type Easing func(t float64) float64
+
+var (
+	// EaseLinear interpolates linearly between stops.
+	EaseLinear Easing = func(t float64) float64 { return t }
+	// EaseIn starts slowly at each stop and accelerates towards the next.
+	EaseIn Easing = func(t float64) float64 { return t * t }
+	// EaseOut starts fast at each stop and slows down towards the next.
+	EaseOut Easing = func(t float64) float64 { return t * (2 - t) }
+	// EaseInOut starts and ends slowly at each stop (smoothstep).
+	EaseInOut Easing = func(t float64) float64 { return t * t * (3 - 2*t) }
+)
+
+// Stop is a color at a position (offset) in the range [0.0, 1.0] of a gradient.
+type Stop struct {
+	Offset float64
+	Color  floatcolor.NRGBAF64
+}
+
+// Gradient is a color ramp defined by an arbitrary number of color stops.
+// Stop colors are supposed to be linear RGB (not gamma encoded) colors.
+//
+// Colors are interpolated with premultiplied alpha, so that fully transparent stops does not
+// bleed their (invisible) color into the neighbouring stops.
+type Gradient struct {
+	// Stops holds the color stops ordered by offset.
+	Stops []Stop
+	// Space is the color space used for interpolation.
+	Space Space
+	// Easing is applied to the position between every pair of stops. Nil means linear interpolation.
+	Easing Easing
+	// Extend determines how the gradient is extended outside the range [0.0, 1.0].
+	Extend Extend
+}
+
+// New creates a new gradient with the given stops, interpolated linearly in linear RGB.
+func New(stops ...Stop) *Gradient {
+	g := &Gradient{Stops: append([]Stop(nil), stops...), Space: LinearRGB, Extend: Pad}
+	g.sortStops()
+	return g
+}
+
+// NewEvenlySpaced creates a new gradient with the given colors as evenly spaced stops in [0.0, 1.0].
+func NewEvenlySpaced(colors ...floatcolor.NRGBAF64) *Gradient {
+	stops := make([]Stop, len(colors))
+	for i, c := range colors {
+		offset := 0.0
+		if len(colors) > 1 {
+			offset = float64(i) / float64(len(colors)-1)
+		}
+		stops[i] = Stop{Offset: offset, Color: c}
+	}
+
+	return New(stops...)
+}
+
+// AddStop adds a color stop to the gradient.
+func (g *Gradient) AddStop(offset float64, c floatcolor.NRGBAF64) {
+	g.Stops = append(g.Stops, Stop{Offset: offset, Color: c})
+	g.sortStops()
+}
+
+func (g *Gradient) sortStops() {
+	sort.SliceStable(g.Stops, func(i, j int) bool { return g.Stops[i].Offset < g.Stops[j].Offset })
+}
+
+// At returns the gradient color at the position t. An undefined position (NaN) gives the color of the first stop.
+func (g *Gradient) At(t float64) floatcolor.NRGBAF64 {
+	n := len(g.Stops)
+	if n == 0 {
+		return floatcolor.NRGBAF64{}
+	}
+
+	t = g.extend(t)
+
+	if t <= g.Stops[0].Offset || math.IsNaN(t) {
+		return g.Stops[0].Color
+	}
+	if t >= g.Stops[n-1].Offset {
+		return g.Stops[n-1].Color
+	}
+
+	i := sort.Search(n, func(i int) bool { return g.Stops[i].Offset > t }) // First stop after t
+	s0, s1 := g.Stops[i-1], g.Stops[i]
+
+	u := (t - s0.Offset) / (s1.Offset - s0.Offset)
+	if g.Easing != nil {
+		u = g.Easing(u)
+	}
+
+	return interpolate(s0.Color, s1.Color, u, g.Space)
+}
+
+func (g *Gradient) extend(t float64) float64 {
+	switch g.Extend {
+	case Repeat:
+		return t - math.Floor(t)
+	case Reflect:
+		t = math.Mod(math.Abs(t), 2.0)
+		if t > 1.0 {
+			t = 2.0 - t
+		}
+		return t
+	default:
+		return t
+	}
+}
+
+// interpolate mixes two colors in the given color space using premultiplied alpha.
+func interpolate(c0, c1 floatcolor.NRGBAF64, u float64, space Space) floatcolor.NRGBAF64 {
+	alpha := c0.A*(1.0-u) + c1.A*u
+
+	v0 := toSpace(c0, space)
+	v1 := toSpace(c1, space)
+
+	w0, w1 := c0.A*(1.0-u), c1.A*u
+	if alpha == 0 {
+		// Fully transparent, fall back to interpolation without alpha weights
+		w0, w1 = 1.0-u, u
+		alpha = 1.0
+	}
+
+	var v [3]float64
+	for i := 0; i < 3; i++ {
+		v[i] = (v0[i]*w0 + v1[i]*w1) / alpha
+	}
+
+	if space == OKLCh {
+		v[2] = interpolateHue(v0, v1, u)
+	}
+
+	quantization := c0.Quantization
+	if quantization == floatcolor.Truncate {
+		quantization = c1.Quantization
+	}
+
+	r, g, b := fromSpace(v, space)
+	return floatcolor.NRGBAF64{R: r, G: g, B: b, A: c0.A*(1.0-u) + c1.A*u, Precise: c0.Precise || c1.Precise, Quantization: quantization}
+}
+
+// interpolateHue interpolates the hue (in radians) of two OKLCh values along the shortest path.
+// The hue of an achromatic color is undefined and the hue of the other color is used instead.
+func interpolateHue(v0, v1 [3]float64, u float64) float64 {
+	const achromatic = 1e-6
+
+	h0, h1 := v0[2], v1[2]
+	if v0[1] < achromatic {
+		h0 = h1
+	}
+	if v1[1] < achromatic {
+		h1 = h0
+	}
+
+	d := h1 - h0
+	if d > math.Pi {
+		d -= 2 * math.Pi
+	} else if d < -math.Pi {
+		d += 2 * math.Pi
+	}
+
+	return h0 + d*u
+}
+
+func toSpace(c floatcolor.NRGBAF64, space Space) [3]float64 {
+	switch space {
+	case SRGB:
+		return [3]float64{floatcolor.LinearToSRGB(c.R), floatcolor.LinearToSRGB(c.G), floatcolor.LinearToSRGB(c.B)}
+	case OKLab:
+		L, A, B := floatcolor.LinearRGBToOKLab(c.R, c.G, c.B)
+		return [3]float64{L, A, B}
+	case OKLCh:
+		l, ch, h := floatcolor.OKLabToOKLCh(floatcolor.LinearRGBToOKLab(c.R, c.G, c.B))
+		return [3]float64{l, ch, h}
+	default:
+		return [3]float64{c.R, c.G, c.B}
+	}
+}
+
+func fromSpace(v [3]float64, space Space) (r, g, b float64) {
+	switch space {
+	case SRGB:
+		return floatcolor.SRGBToLinear(v[0]), floatcolor.SRGBToLinear(v[1]), floatcolor.SRGBToLinear(v[2])
+	case OKLab:
+		return floatcolor.OKLabToLinearRGB(v[0], v[1], v[2])
+	case OKLCh:
+		return floatcolor.OKLabToLinearRGB(floatcolor.OKLChToOKLab(v[0], v[1], v[2]))
+	default:
+		return v[0], v[1], v[2]
+	}
+}
@@ -0,0 +1,151 @@
+package gradient
+
+import (
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"math"
+	"testing"
+)
+
+var (
+	red   = floatcolor.NewNRGBAF64(1, 0, 0, 1)
+	green = floatcolor.NewNRGBAF64(0, 1, 0, 1)
+	blue  = floatcolor.NewNRGBAF64(0, 0, 1, 1)
+	black = floatcolor.NewNRGBAF64(0, 0, 0, 1)
+	white = floatcolor.NewNRGBAF64(1, 1, 1, 1)
+)
+
+func almostEqual(c1, c2 floatcolor.NRGBAF64, tolerance float64) bool {
+	return math.Abs(c1.R-c2.R) <= tolerance && math.Abs(c1.G-c2.G) <= tolerance &&
+		math.Abs(c1.B-c2.B) <= tolerance && math.Abs(c1.A-c2.A) <= tolerance
+}
+
+func TestStopsAndExtend(t *testing.T) {
+	g := New(Stop{Offset: 1.0, Color: blue}, Stop{Offset: 0.0, Color: red}, Stop{Offset: 0.5, Color: green})
+
+	tests := []struct {
+		extend   Extend
+		t        float64
+		expected floatcolor.NRGBAF64
+	}{
+		{Pad, 0.0, red}, {Pad, 0.5, green}, {Pad, 1.0, blue},
+		{Pad, -1.0, red}, {Pad, 2.0, blue},
+		{Pad, 0.25, floatcolor.NewNRGBAF64(0.5, 0.5, 0, 1)},
+		{Repeat, 1.25, floatcolor.NewNRGBAF64(0.5, 0.5, 0, 1)},
+		{Reflect, 1.25, floatcolor.NewNRGBAF64(0, 0.5, 0.5, 1)},
+		{Reflect, -0.25, floatcolor.NewNRGBAF64(0.5, 0.5, 0, 1)},
+	}
+
+	for _, test := range tests {
+		g.Extend = test.extend
+		if c := g.At(test.t); !almostEqual(c, test.expected, 1e-12) {
+			t.Errorf("extend %d at %v: expected %+v but got %+v", test.extend, test.t, test.expected, c)
+		}
+	}
+}
+
+func TestSpaces(t *testing.T) {
+	g := NewEvenlySpaced(black, white)
+
+	g.Space = LinearRGB
+	if c := g.At(0.5); !almostEqual(c, floatcolor.NewNRGBAF64(0.5, 0.5, 0.5, 1), 1e-12) {
+		t.Errorf("linear RGB: unexpected mid color %+v", c)
+	}
+
+	g.Space = SRGB
+	mid := floatcolor.SRGBToLinear(0.5)
+	if c := g.At(0.5); !almostEqual(c, floatcolor.NewNRGBAF64(mid, mid, mid, 1), 1e-12) {
+		t.Errorf("sRGB: unexpected mid color %+v", c)
+	}
+
+	for _, space := range []Space{OKLab, OKLCh} {
+		g.Space = space
+		c := g.At(0.5)
+		if L, _, _ := floatcolor.LinearRGBToOKLab(c.R, c.G, c.B); math.Abs(L-0.5) > 1e-6 {
+			t.Errorf("space %d: expected OKLab lightness 0.5 but got %v", space, L)
+		}
+		if !almostEqual(g.At(0), black, 1e-9) || !almostEqual(g.At(1), white, 1e-9) {
+			t.Errorf("space %d: end points do not round trip", space)
+		}
+	}
+}
+
+func TestOKLChShortestHuePath(t *testing.T) {
+	// Red (hue ~29°) to blue (hue ~264°) is shortest through magenta, not through green.
+	g := NewEvenlySpaced(red, blue)
+	g.Space = OKLCh
+
+	c := g.At(0.5)
+	if c.G > c.R || c.G > c.B {
+		t.Errorf("expected a magenta-ish mid color but got %+v", c)
+	}
+}
+
+func TestTransparentStop(t *testing.T) {
+	g := NewEvenlySpaced(floatcolor.NewNRGBAF64(1, 0, 0, 1), floatcolor.NewNRGBAF64(0, 0, 1, 0))
+
+	// With premultiplied interpolation the invisible blue does not tint the half transparent red.
+	if c := g.At(0.5); !almostEqual(c, floatcolor.NewNRGBAF64(1, 0, 0, 0.5), 1e-12) {
+		t.Errorf("unexpected color %+v", c)
+	}
+}
+
+func TestEasing(t *testing.T) {
+	g := NewEvenlySpaced(black, white)
+	g.Easing = EaseIn
+
+	if c := g.At(0.5); !almostEqual(c, floatcolor.NewNRGBAF64(0.25, 0.25, 0.25, 1), 1e-12) {
+		t.Errorf("unexpected eased color %+v", c)
+	}
+}
+
+func TestDraw(t *testing.T) {
+	g := NewEvenlySpaced(black, white)
+
+	img := floatimage.NewRGBAF32(10, 10)
+	g.DrawLinear(img, 0, 0, 10, 0)
+	for x := 0; x < 10; x++ {
+		expected := (float32(x) + 0.5) / 10
+		if v := img.Pix[img.PixOffset(x, 5)]; math.Abs(float64(v-expected)) > 1e-6 {
+			t.Errorf("linear: expected %v at x=%d but got %v", expected, x, v)
+		}
+	}
+
+	nrgbaf64 := floatimage.NewNRGBAF64WithBounds(-5, -5, 5, 5)
+	g.DrawRadial(nrgbaf64, 0, 0, 5)
+	if v := nrgbaf64.Pix[nrgbaf64.PixOffset(0, 0)]; math.Abs(v-math.Sqrt(0.5)/5) > 1e-12 {
+		t.Errorf("radial: unexpected center value %v", v)
+	}
+	if v := nrgbaf64.Pix[nrgbaf64.PixOffset(-5, -5)]; v != 1.0 {
+		t.Errorf("radial: unexpected corner value %v", v)
+	}
+
+	g.DrawDiamond(nrgbaf64, 0, 0, 5)
+	if v := nrgbaf64.Pix[nrgbaf64.PixOffset(2, 0)]; math.Abs(v-3.0/5) > 1e-12 {
+		t.Errorf("diamond: unexpected value %v", v)
+	}
+
+	g.DrawConic(nrgbaf64, 0, 0, 0)
+	if v := nrgbaf64.Pix[nrgbaf64.PixOffset(-1, 3)]; math.Abs(v-(math.Atan2(3.5, -0.5)/(2*math.Pi))) > 1e-12 {
+		t.Errorf("conic: unexpected value %v", v)
+	}
+}
+
+func TestDegenerateShapes(t *testing.T) {
+	g := NewEvenlySpaced(black, white)
+	for _, extend := range []Extend{Pad, Repeat, Reflect} {
+		g.Extend = extend
+		if c := g.At(math.NaN()); c != black {
+			t.Errorf("extend %d: expected first stop for NaN but got %+v", extend, c)
+		}
+	}
+
+	img := floatimage.NewNRGBAF64(4, 4)
+	g.Extend = Pad
+	g.DrawRadial(img, 0.5, 0.5, 0)
+	g.DrawRadial(img, 0.5, 0.5, -1)
+	g.DrawDiamond(img, 0.5, 0.5, 0)
+	if c := img.NRGBAF64At(0, 0); c != white {
+		t.Errorf("expected last stop for a zero radius but got %+v", c)
+	}
+}
@@ -0,0 +1,90 @@
+package gradient
+
+import (
+	"image/draw"
+	"math"
+)
+
+// Shape maps an image position to a gradient position.
+// Image positions are given in pixel coordinates where the center of pixel (x, y) is at (x+0.5, y+0.5).
+type Shape func(x, y float64) float64
+
+// Linear returns a shape for a linear gradient going from (x0, y0) (position 0.0) to (x1, y1) (position 1.0).
+func Linear(x0, y0, x1, y1 float64) Shape {
+	dx, dy := x1-x0, y1-y0
+	lengthSquared := dx*dx + dy*dy
+	if lengthSquared == 0 {
+		return func(x, y float64) float64 { return 0.0 }
+	}
+
+	return func(x, y float64) float64 {
+		return ((x-x0)*dx + (y-y0)*dy) / lengthSquared
+	}
+}
+
+// Radial returns a shape for a circular gradient with position 0.0 at the center (cx, cy)
+// and position 1.0 at the given radius. A radius of zero or less puts every position beyond the radius (position 1.0).
+func Radial(cx, cy, radius float64) Shape {
+	if !(radius > 0) {
+		return func(x, y float64) float64 { return 1.0 }
+	}
+
+	return func(x, y float64) float64 {
+		return math.Hypot(x-cx, y-cy) / radius
+	}
+}
+
+// Conic returns a shape for a conic (sweep) gradient around the center (cx, cy).
+// Position 0.0 is at the angle startAngle (in radians, clockwise in image coordinates from the positive x-axis)
+// and the position increases to 1.0 after one full revolution.
+func Conic(cx, cy, startAngle float64) Shape {
+	return func(x, y float64) float64 {
+		angle := math.Atan2(y-cy, x-cx) - startAngle
+		t := angle / (2 * math.Pi)
+		return t - math.Floor(t)
+	}
+}
+
+// Diamond returns a shape for a diamond shaped gradient with position 0.0 at the center (cx, cy)
+// and position 1.0 at the given (Manhattan distance) radius. A radius of zero or less puts every position beyond the radius (position 1.0).
+func Diamond(cx, cy, radius float64) Shape {
+	if !(radius > 0) {
+		return func(x, y float64) float64 { return 1.0 }
+	}
+
+	return func(x, y float64) float64 {
+		return (math.Abs(x-cx) + math.Abs(y-cy)) / radius
+	}
+}
+
+// Draw rasterizes the gradient with the given shape into the destination image.
+// Every pixel within the destination bounds is set, the gradient is sampled at the pixel centers.
+// The destination is typically a float image, in which case the color values are kept as is.
+func (g *Gradient) Draw(dst draw.Image, shape Shape) {
+	bounds := dst.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			dst.Set(x, y, g.At(shape(float64(x)+0.5, float64(y)+0.5)))
+		}
+	}
+}
+
+// DrawLinear rasterizes a linear gradient from (x0, y0) to (x1, y1) into the destination image.
+func (g *Gradient) DrawLinear(dst draw.Image, x0, y0, x1, y1 float64) {
+	g.Draw(dst, Linear(x0, y0, x1, y1))
+}
+
+// DrawRadial rasterizes a radial gradient around (cx, cy) into the destination image.
+func (g *Gradient) DrawRadial(dst draw.Image, cx, cy, radius float64) {
+	g.Draw(dst, Radial(cx, cy, radius))
+}
+
+// DrawConic rasterizes a conic gradient around (cx, cy) into the destination image.
+func (g *Gradient) DrawConic(dst draw.Image, cx, cy, startAngle float64) {
+	g.Draw(dst, Conic(cx, cy, startAngle))
+}
+
+// DrawDiamond rasterizes a diamond gradient around (cx, cy) into the destination image.
+func (g *Gradient) DrawDiamond(dst draw.Image, cx, cy, radius float64) {
+	g.Draw(dst, Diamond(cx, cy, radius))
+}
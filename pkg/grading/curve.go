@@ -0,0 +1,110 @@
+package grading
+
+import (
+	"math"
+	"sort"
+)
+
+// Point is a control point of a curve.
+type Point struct {
+	X, Y float64
+}
+
+// Curve is a smooth tone curve through a set of control points.
+// It is a monotone cubic (Fritsch-Carlson) spline, i.e. it does not overshoot between control points
+// and is monotonic wherever the control points are. Outside the control points the curve
+// continues linearly with the slope of its end points.
+type Curve struct {
+	points  []Point
+	tangent []float64
+}
+
+// NewCurve creates a new curve through the given control points.
+// Points are sorted by X and points with duplicate X values are ignored.
+func NewCurve(points ...Point) *Curve {
+	sorted := append([]Point(nil), points...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].X < sorted[j].X })
+
+	c := &Curve{}
+	for _, p := range sorted {
+		if len(c.points) > 0 && c.points[len(c.points)-1].X == p.X {
+			continue
+		}
+		c.points = append(c.points, p)
+	}
+
+	c.computeTangents()
+	return c
+}
+
+// IdentityCurve returns the straight curve through (0, 0) and (1, 1).
+func IdentityCurve() *Curve {
+	return NewCurve(Point{0, 0}, Point{1, 1})
+}
+
+func (c *Curve) computeTangents() {
+	n := len(c.points)
+	c.tangent = make([]float64, n)
+	if n < 2 {
+		return
+	}
+
+	secant := make([]float64, n-1)
+	for i := 0; i < n-1; i++ {
+		secant[i] = (c.points[i+1].Y - c.points[i].Y) / (c.points[i+1].X - c.points[i].X)
+	}
+
+	c.tangent[0] = secant[0]
+	c.tangent[n-1] = secant[n-2]
+	for i := 1; i < n-1; i++ {
+		if secant[i-1]*secant[i] <= 0 {
+			c.tangent[i] = 0 // Local extremum
+		} else {
+			c.tangent[i] = (secant[i-1] + secant[i]) / 2
+		}
+	}
+
+	// Limit the tangents to keep the spline monotonic (Fritsch-Carlson).
+	for i := 0; i < n-1; i++ {
+		if secant[i] == 0 {
+			c.tangent[i], c.tangent[i+1] = 0, 0
+			continue
+		}
+
+		a, b := c.tangent[i]/secant[i], c.tangent[i+1]/secant[i]
+		if s := a*a + b*b; s > 9 {
+			t := 3 / math.Sqrt(s)
+			c.tangent[i] = t * a * secant[i]
+			c.tangent[i+1] = t * b * secant[i]
+		}
+	}
+}
+
+// Eval returns the curve value at x.
+func (c *Curve) Eval(x float64) float64 {
+	n := len(c.points)
+	switch {
+	case n == 0:
+		return x
+	case n == 1:
+		return c.points[0].Y + x - c.points[0].X
+	case x <= c.points[0].X:
+		return c.points[0].Y + (x-c.points[0].X)*c.tangent[0]
+	case x >= c.points[n-1].X:
+		return c.points[n-1].Y + (x-c.points[n-1].X)*c.tangent[n-1]
+	}
+
+	i := sort.Search(n, func(i int) bool { return c.points[i].X > x }) - 1
+	p0, p1 := c.points[i], c.points[i+1]
+	h := p1.X - p0.X
+	t := (x - p0.X) / h
+
+	// Cubic Hermite basis functions
+	t2, t3 := t*t, t*t*t
+	h00 := 2*t3 - 3*t2 + 1
+	h10 := t3 - 2*t2 + t
+	h01 := -2*t3 + 3*t2
+	h11 := t3 - t2
+
+	return h00*p0.Y + h10*h*c.tangent[i] + h01*p1.Y + h11*h*c.tangent[i+1]
+}
@@ -0,0 +1,179 @@
+package grading
+
+import (
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"image/draw"
+	"math"
+)
+
+// Operation is a color grading operation working on linear (not gamma encoded) red, green, and blue values.
+type Operation interface {
+	Apply(rgb [3]float64) [3]float64
+}
+
+// OperationFunc is a function acting as a grading operation.
+type OperationFunc func(rgb [3]float64) [3]float64
+
+// Apply calls f(rgb).
+func (f OperationFunc) Apply(rgb [3]float64) [3]float64 { return f(rgb) }
+
+// Pipeline is a sequence of grading operations applied in order.
+type Pipeline []Operation
+
+// NewPipeline creates a new pipeline of the given operations.
+func NewPipeline(operations ...Operation) Pipeline {
+	return append(Pipeline(nil), operations...)
+}
+
+// Apply applies all operations of the pipeline in order to the red, green, and blue values.
+func (p Pipeline) Apply(rgb [3]float64) [3]float64 {
+	for _, operation := range p {
+		rgb = operation.Apply(rgb)
+	}
+	return rgb
+}
+
+// ApplyColor applies the pipeline to a color. Alpha is kept as is.
+func (p Pipeline) ApplyColor(c floatcolor.NRGBAF64) floatcolor.NRGBAF64 {
+	rgb := p.Apply([3]float64{c.R, c.G, c.B})
+	return floatcolor.NRGBAF64{R: rgb[0], G: rgb[1], B: rgb[2], A: c.A, Precise: c.Precise, Quantization: c.Quantization}
+}
+
+// ApplyImage applies the pipeline to every pixel of the image in place, in a single pass over the pixels.
+// The operations are applied to the non premultiplied red, green, and blue values and alpha is kept as is.
+func (p Pipeline) ApplyImage(img draw.Image) {
+	floatimage.Map(img, p.ApplyColor)
+}
+
+// ApplyImageParallel is like ApplyImage, but processes float images in bands of rows on up to workers goroutines
+// (see floatimage.MapParallel), so the operations must be safe for concurrent use.
+func (p Pipeline) ApplyImageParallel(img draw.Image, workers int) {
+	floatimage.MapParallel(img, workers, p.ApplyColor)
+}
+
+// Exposure scales the values by 2^Stops.
+type Exposure struct {
+	Stops float64
+}
+
+func (e Exposure) Apply(rgb [3]float64) [3]float64 {
+	f := math.Exp2(e.Stops)
+	return [3]float64{rgb[0] * f, rgb[1] * f, rgb[2] * f}
+}
+
+// Contrast changes the contrast around a pivot value (typically mid gray, 0.18).
+// The contrast is applied as a power function, i.e. as a linear contrast in logarithmic (stops) space,
+// so values at the pivot are kept and the value range stays positive.
+// Amount 1.0 leaves the values unchanged, higher values increase the contrast.
+// A Pivot of zero or less (e.g. of the zero value) is replaced by MidGray.
+type Contrast struct {
+	Amount float64
+	Pivot  float64
+}
+
+// MidGray is the scene linear value of mid gray (18% reflectance), the default pivot of Contrast.
+const MidGray = 0.18
+
+func (c Contrast) Apply(rgb [3]float64) [3]float64 {
+	pivot := c.Pivot
+	if !(pivot > 0) {
+		pivot = MidGray
+	}
+
+	for i, v := range rgb {
+		if v > 0 {
+			rgb[i] = pivot * math.Pow(v/pivot, c.Amount)
+		}
+	}
+	return rgb
+}
+
+// CDL is an ASC Color Decision List transform: out = (in*Slope + Offset)^Power
+// followed by a saturation adjustment using Rec. 709 luma weights.
+// Values are clamped to zero before the power function, as specified by ASC CDL.
+type CDL struct {
+	Slope      [3]float64
+	Offset     [3]float64
+	Power      [3]float64
+	Saturation float64
+}
+
+// NewCDL returns an identity CDL (slope 1, offset 0, power 1, saturation 1).
+func NewCDL() CDL {
+	return CDL{Slope: [3]float64{1, 1, 1}, Power: [3]float64{1, 1, 1}, Saturation: 1.0}
+}
+
+func (cdl CDL) Apply(rgb [3]float64) [3]float64 {
+	for i, v := range rgb {
+		v = v*cdl.Slope[i] + cdl.Offset[i]
+		if v < 0 {
+			v = 0
+		}
+		rgb[i] = math.Pow(v, cdl.Power[i])
+	}
+
+	return Saturation{Amount: cdl.Saturation}.Apply(rgb)
+}
+
+// Saturation scales the distance of the values from their luma (Rec. 709 weights).
+// Amount 0.0 gives a gray scale result and 1.0 leaves the values unchanged.
+type Saturation struct {
+	Amount float64
+}
+
+func (s Saturation) Apply(rgb [3]float64) [3]float64 {
+	luma := floatcolor.Luminance(rgb[0], rgb[1], rgb[2])
+	for i, v := range rgb {
+		rgb[i] = luma + s.Amount*(v-luma)
+	}
+	return rgb
+}
+
+// LiftGammaGain is the classic three way color corrector:
+// out = (Gain * (in + Lift * (1 - in)))^(1 / Gamma).
+// Lift mostly affects the shadows, Gamma the mid tones and Gain the highlights.
+type LiftGammaGain struct {
+	Lift  [3]float64
+	Gamma [3]float64
+	Gain  [3]float64
+}
+
+// NewLiftGammaGain returns an identity lift/gamma/gain (lift 0, gamma 1, gain 1).
+func NewLiftGammaGain() LiftGammaGain {
+	return LiftGammaGain{Gamma: [3]float64{1, 1, 1}, Gain: [3]float64{1, 1, 1}}
+}
+
+func (lgg LiftGammaGain) Apply(rgb [3]float64) [3]float64 {
+	for i, v := range rgb {
+		v = lgg.Gain[i] * (v + lgg.Lift[i]*(1-v))
+		if v > 0 {
+			v = math.Pow(v, 1/lgg.Gamma[i])
+		}
+		rgb[i] = v
+	}
+	return rgb
+}
+
+// Curves applies a tone curve to each channel, followed by a master curve applied to all channels.
+// Nil curves are skipped.
+type Curves struct {
+	Red, Green, Blue *Curve
+	Master           *Curve
+}
+
+func (c Curves) Apply(rgb [3]float64) [3]float64 {
+	for i, curve := range [3]*Curve{c.Red, c.Green, c.Blue} {
+		if curve != nil {
+			rgb[i] = curve.Eval(rgb[i])
+		}
+	}
+
+	if c.Master != nil {
+		for i, v := range rgb {
+			rgb[i] = c.Master.Eval(v)
+		}
+	}
+
+	return rgb
+}
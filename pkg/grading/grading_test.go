@@ -0,0 +1,102 @@
+package grading
+
+import (
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"math"
+	"testing"
+)
+
+func almostEqual(rgb1, rgb2 [3]float64, tolerance float64) bool {
+	return math.Abs(rgb1[0]-rgb2[0]) <= tolerance && math.Abs(rgb1[1]-rgb2[1]) <= tolerance && math.Abs(rgb1[2]-rgb2[2]) <= tolerance
+}
+
+func TestOperations(t *testing.T) {
+	in := [3]float64{0.18, 0.36, 0.09}
+
+	tests := []struct {
+		name      string
+		operation Operation
+		expected  [3]float64
+	}{
+		{"exposure", Exposure{Stops: 1}, [3]float64{0.36, 0.72, 0.18}},
+		{"contrast", Contrast{Amount: 2, Pivot: 0.18}, [3]float64{0.18, 0.72, 0.045}},
+		{"contrast default pivot", Contrast{Amount: 2}, [3]float64{0.18, 0.72, 0.045}},
+		{"contrast identity", Contrast{Amount: 1, Pivot: 0.5}, in},
+		{"identity cdl", NewCDL(), in},
+		{"cdl", CDL{Slope: [3]float64{2, 1, 1}, Offset: [3]float64{0, -0.36, 0}, Power: [3]float64{1, 1, 2}, Saturation: 1}, [3]float64{0.36, 0, 0.0081}},
+		{"identity lift gamma gain", NewLiftGammaGain(), in},
+		{"gain", LiftGammaGain{Gamma: [3]float64{1, 1, 1}, Gain: [3]float64{2, 2, 2}}, [3]float64{0.36, 0.72, 0.18}},
+		{"lift", LiftGammaGain{Lift: [3]float64{1, 1, 1}, Gamma: [3]float64{1, 1, 1}, Gain: [3]float64{1, 1, 1}}, [3]float64{1, 1, 1}},
+		{"gamma", LiftGammaGain{Gamma: [3]float64{0.5, 0.5, 0.5}, Gain: [3]float64{1, 1, 1}}, [3]float64{0.0324, 0.1296, 0.0081}},
+		{"desaturate", Saturation{Amount: 0}, [3]float64{0.302238, 0.302238, 0.302238}},
+		{"identity curves", Curves{Master: IdentityCurve()}, in},
+	}
+
+	for _, test := range tests {
+		if out := test.operation.Apply(in); !almostEqual(out, test.expected, 1e-3) {
+			t.Errorf("%s: expected %v but got %v", test.name, test.expected, out)
+		}
+	}
+}
+
+func TestCurveIsMonotone(t *testing.T) {
+	c := NewCurve(Point{0, 0}, Point{0.25, 0.1}, Point{0.5, 0.5}, Point{0.6, 0.9}, Point{1, 1})
+
+	for _, p := range c.points {
+		if v := c.Eval(p.X); math.Abs(v-p.Y) > 1e-12 {
+			t.Errorf("expected curve through %+v but got %v", p, v)
+		}
+	}
+
+	previous := c.Eval(-0.1)
+	for x := -0.1; x <= 1.1; x += 0.001 {
+		v := c.Eval(x)
+		if v < previous-1e-12 {
+			t.Fatalf("curve is not monotone at %v", x)
+		}
+		previous = v
+	}
+
+	if v := c.Eval(2.0); math.Abs(v-(1+c.tangent[len(c.tangent)-1])) > 1e-12 {
+		t.Errorf("expected linear extrapolation but got %v", v)
+	}
+}
+
+func TestPipelineOnImage(t *testing.T) {
+	pipeline := NewPipeline(
+		Exposure{Stops: -1},
+		OperationFunc(func(rgb [3]float64) [3]float64 { return [3]float64{rgb[2], rgb[1], rgb[0]} }),
+	)
+
+	img := floatimage.NewRGBAF32(2, 2)
+	img.Set(0, 0, floatcolor.NRGBAF64{R: 1.0, G: 0.5, B: 0.0, A: 0.5})
+	pipeline.ApplyImage(img)
+
+	c := floatcolor.NRGBAF64Model.Convert(img.At(0, 0)).(floatcolor.NRGBAF64)
+	if !almostEqual([3]float64{c.R, c.G, c.B}, [3]float64{0.0, 0.25, 0.5}, 1e-6) || c.A != 0.5 {
+		t.Errorf("unexpected graded color %+v", c)
+	}
+	// The sequential ApplyImage calls the operations one after another
+	calls, inFlight := 0, 0
+	counter := OperationFunc(func(rgb [3]float64) [3]float64 {
+		inFlight++
+		if inFlight > 1 {
+			t.Errorf("expected no concurrent calls")
+		}
+		calls++
+		inFlight--
+		return rgb
+	})
+	NewPipeline(counter).ApplyImage(floatimage.NewRGBAF32(64, 64))
+	if calls != 64*64 {
+		t.Errorf("expected one call per pixel but got %d", calls)
+	}
+
+	parallel := floatimage.NewRGBAF32(2, 2)
+	parallel.Set(0, 0, floatcolor.NRGBAF64{R: 1.0, G: 0.5, B: 0.0, A: 0.5})
+	pipeline.ApplyImageParallel(parallel, 2)
+	if parallel.At(0, 0) != img.At(0, 0) {
+		t.Errorf("expected the parallel pipeline to give the same color but got %+v", parallel.At(0, 0))
+	}
+}
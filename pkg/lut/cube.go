@@ -0,0 +1,309 @@
+package lut
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// max1DSize and max3DSize limit the LUT sizes accepted when reading .cube files.
+const (
+	max1DSize = 65536
+	max3DSize = 256
+)
+
+// ReadCubeFile reads a .cube LUT file (Adobe or DaVinci Resolve flavour).
+func ReadCubeFile(filename string) (*Cube, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadCube(f)
+}
+
+// ReadCube parses a .cube LUT (Adobe or DaVinci Resolve flavour).
+// Supported keywords are TITLE, LUT_1D_SIZE, LUT_3D_SIZE, DOMAIN_MIN, DOMAIN_MAX,
+// LUT_1D_INPUT_RANGE, and LUT_3D_INPUT_RANGE. Comments start with '#'.
+func ReadCube(r io.Reader) (*Cube, error) {
+	cube := &Cube{}
+
+	size1D, size3D := 0, 0
+	domainMin, domainMax := [3]float64{0, 0, 0}, [3]float64{1, 1, 1}
+	range1D, range3D := [2]float64{0, 1}, [2]float64{0, 1}
+	hasRange1D, hasRange3D := false, false
+	var data [][3]float64
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		keyword := fields[0]
+
+		if isNumber(keyword) {
+			if len(fields) != 3 {
+				return nil, fmt.Errorf("lut: line %d: expected 3 values but got %d", lineNumber, len(fields))
+			}
+			v, err := parseFloats(fields)
+			if err != nil {
+				return nil, fmt.Errorf("lut: line %d: %w", lineNumber, err)
+			}
+			data = append(data, [3]float64{v[0], v[1], v[2]})
+			continue
+		}
+
+		if len(data) > 0 {
+			return nil, fmt.Errorf("lut: line %d: keyword %s after table data", lineNumber, keyword)
+		}
+
+		var err error
+		switch keyword {
+		case "TITLE":
+			cube.Title = unquote(strings.TrimSpace(strings.TrimPrefix(line, "TITLE")))
+		case "LUT_1D_SIZE":
+			size1D, err = parseSize(fields, 2, max1DSize)
+		case "LUT_3D_SIZE":
+			size3D, err = parseSize(fields, 2, max3DSize)
+		case "DOMAIN_MIN":
+			domainMin, err = parseTriple(fields)
+		case "DOMAIN_MAX":
+			domainMax, err = parseTriple(fields)
+		case "LUT_1D_INPUT_RANGE":
+			range1D, err = parsePair(fields)
+			hasRange1D = true
+		case "LUT_3D_INPUT_RANGE":
+			range3D, err = parsePair(fields)
+			hasRange3D = true
+		default:
+			// Unknown keywords (e.g. vendor extensions) are ignored.
+		}
+		if err != nil {
+			return nil, fmt.Errorf("lut: line %d: %s: %w", lineNumber, keyword, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if size1D == 0 && size3D == 0 {
+		return nil, errors.New("lut: missing LUT_1D_SIZE or LUT_3D_SIZE")
+	}
+
+	expected := size1D + size3D*size3D*size3D
+	if len(data) != expected {
+		return nil, fmt.Errorf("lut: expected %d table entries but got %d", expected, len(data))
+	}
+
+	// DOMAIN_MIN/DOMAIN_MAX applies to the LUT of an Adobe cube, the input ranges to the LUTs of a Resolve cube.
+	if size1D > 0 {
+		min, max := domainMin, domainMax
+		if hasRange1D {
+			min, max = [3]float64{range1D[0], range1D[0], range1D[0]}, [3]float64{range1D[1], range1D[1], range1D[1]}
+		}
+		cube.LUT1D = &LUT1D{DomainMin: min, DomainMax: max, Table: data[:size1D]}
+	}
+
+	if size3D > 0 {
+		min, max := domainMin, domainMax
+		if hasRange3D {
+			min, max = [3]float64{range3D[0], range3D[0], range3D[0]}, [3]float64{range3D[1], range3D[1], range3D[1]}
+		}
+		cube.LUT3D = &LUT3D{DomainMin: min, DomainMax: max, Size: size3D, Table: data[size1D:]}
+	}
+
+	return cube, nil
+}
+
+// WriteCubeFile writes the cube to a .cube LUT file.
+func (cube *Cube) WriteCubeFile(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := cube.WriteCube(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// WriteCube writes the cube in the .cube LUT format.
+// A cube with a single LUT is written in the Adobe flavour (with DOMAIN_MIN/DOMAIN_MAX),
+// a cube with both a 1D and a 3D LUT is written in the DaVinci Resolve flavour (with input ranges).
+// The format has no escapes, so it returns an error for titles with quotes or line breaks.
+func (cube *Cube) WriteCube(w io.Writer) error {
+	if cube.LUT1D == nil && cube.LUT3D == nil {
+		return errors.New("lut: cube has no LUT to write")
+	}
+
+	if strings.ContainsAny(cube.Title, "\"\r\n") {
+		return fmt.Errorf("lut: cube title %q contains a quote or line break", cube.Title)
+	}
+
+	bw := bufio.NewWriter(w)
+
+	if cube.Title != "" {
+		fmt.Fprintf(bw, "TITLE \"%s\"\n", cube.Title)
+	}
+
+	both := cube.LUT1D != nil && cube.LUT3D != nil
+
+	if cube.LUT1D != nil {
+		fmt.Fprintf(bw, "LUT_1D_SIZE %d\n", len(cube.LUT1D.Table))
+		if both {
+			writeInputRange(bw, "LUT_1D_INPUT_RANGE", cube.LUT1D.DomainMin, cube.LUT1D.DomainMax)
+		} else {
+			writeDomain(bw, cube.LUT1D.DomainMin, cube.LUT1D.DomainMax)
+		}
+	}
+
+	if cube.LUT3D != nil {
+		fmt.Fprintf(bw, "LUT_3D_SIZE %d\n", cube.LUT3D.Size)
+		if both {
+			writeInputRange(bw, "LUT_3D_INPUT_RANGE", cube.LUT3D.DomainMin, cube.LUT3D.DomainMax)
+		} else {
+			writeDomain(bw, cube.LUT3D.DomainMin, cube.LUT3D.DomainMax)
+		}
+	}
+
+	bw.WriteString("\n")
+
+	if cube.LUT1D != nil {
+		writeTable(bw, cube.LUT1D.Table)
+	}
+	if cube.LUT3D != nil {
+		writeTable(bw, cube.LUT3D.Table)
+	}
+
+	return bw.Flush()
+}
+
+func writeDomain(w *bufio.Writer, min, max [3]float64) {
+	if min == [3]float64{0, 0, 0} && max == [3]float64{1, 1, 1} {
+		return // Default domain
+	}
+
+	fmt.Fprintf(w, "DOMAIN_MIN %s %s %s\n", formatFloat(min[0]), formatFloat(min[1]), formatFloat(min[2]))
+	fmt.Fprintf(w, "DOMAIN_MAX %s %s %s\n", formatFloat(max[0]), formatFloat(max[1]), formatFloat(max[2]))
+}
+
+func writeInputRange(w *bufio.Writer, keyword string, min, max [3]float64) {
+	if min[0] != min[1] || min[0] != min[2] || max[0] != max[1] || max[0] != max[2] {
+		// The Resolve input range is the same for all channels, use the widest range.
+		min[0] = minOf(min[0], min[1], min[2])
+		max[0] = maxOf(max[0], max[1], max[2])
+	}
+
+	fmt.Fprintf(w, "%s %s %s\n", keyword, formatFloat(min[0]), formatFloat(max[0]))
+}
+
+func writeTable(w *bufio.Writer, table [][3]float64) {
+	for _, v := range table {
+		fmt.Fprintf(w, "%s %s %s\n", formatFloat(v[0]), formatFloat(v[1]), formatFloat(v[2]))
+	}
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func isNumber(s string) bool {
+	c := s[0]
+	return (c >= '0' && c <= '9') || c == '-' || c == '+' || c == '.'
+}
+
+func parseFloats(fields []string) ([]float64, error) {
+	values := make([]float64, len(fields))
+	for i, field := range fields {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+
+	return values, nil
+}
+
+// unquote returns the title s without its surrounding quotes, if any.
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func parseSize(fields []string, min, max int) (int, error) {
+	if len(fields) != 2 {
+		return 0, errors.New("expected a single size value")
+	}
+
+	size, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, err
+	}
+	if size < min || size > max {
+		return 0, fmt.Errorf("size %d is outside the supported range [%d, %d]", size, min, max)
+	}
+
+	return size, nil
+}
+
+func parseTriple(fields []string) ([3]float64, error) {
+	if len(fields) != 4 {
+		return [3]float64{}, errors.New("expected 3 values")
+	}
+
+	v, err := parseFloats(fields[1:])
+	if err != nil {
+		return [3]float64{}, err
+	}
+
+	return [3]float64{v[0], v[1], v[2]}, nil
+}
+
+func parsePair(fields []string) ([2]float64, error) {
+	if len(fields) != 3 {
+		return [2]float64{}, errors.New("expected 2 values")
+	}
+
+	v, err := parseFloats(fields[1:])
+	if err != nil {
+		return [2]float64{}, err
+	}
+
+	return [2]float64{v[0], v[1]}, nil
+}
+
+func minOf(a, b, c float64) float64 {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+func maxOf(a, b, c float64) float64 {
+	if b > a {
+		a = b
+	}
+	if c > a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,302 @@
+package lut
+
+import (
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"image/draw"
+	"math"
+	"strconv"
+)
+
+// Interpolation is the method used to interpolate between the entries of a 3D LUT.
+type Interpolation int
+
+const (
+	// Trilinear interpolates between the eight surrounding lattice points.
+	Trilinear Interpolation = iota
+	// Tetrahedral interpolates between the four lattice points of the enclosing tetrahedron.
+	// It is cheaper than trilinear interpolation and preserves the neutral (gray) axis.
+	Tetrahedral
+)
+
+// LUT1D is a one dimensional lookup table with individual curves for red, green, and blue.
+type LUT1D struct {
+	// DomainMin and DomainMax are the input values mapped to the first and last table entries.
+	DomainMin, DomainMax [3]float64
+	// Table holds the output red, green, and blue values.
+	Table [][3]float64
+}
+
+// LUT3D is a three dimensional lookup table (a lattice of Size x Size x Size output colors).
+type LUT3D struct {
+	// DomainMin and DomainMax are the input values mapped to the first and last lattice points.
+	DomainMin, DomainMax [3]float64
+	// Size is the number of lattice points along each axis.
+	Size int
+	// Table holds the output red, green, and blue values with the red index changing fastest,
+	// i.e. the entry for lattice point (r, g, b) is at Table[r + g*Size + b*Size*Size].
+	Table [][3]float64
+	// Interpolation is the method used to interpolate between lattice points.
+	Interpolation Interpolation
+}
+
+// Cube is the content of a .cube LUT file. It holds a 1D LUT, a 3D LUT or both.
+// When both are present (as in DaVinci Resolve LUTs), the 1D LUT is a shaper applied before the 3D LUT.
+type Cube struct {
+	// Title is the optional title of the file, without quotes or line breaks.
+	Title string
+	LUT1D *LUT1D
+	LUT3D *LUT3D
+}
+
+// NewLUT1D returns a new identity 1D LUT with the given size and the domain [0.0, 1.0].
+// It panics if size is less than 2.
+func NewLUT1D(size int) *LUT1D {
+	checkSize(size, "NewLUT1D")
+	l := &LUT1D{DomainMin: [3]float64{0, 0, 0}, DomainMax: [3]float64{1, 1, 1}, Table: make([][3]float64, size)}
+	for i := range l.Table {
+		v := float64(i) / float64(size-1)
+		l.Table[i] = [3]float64{v, v, v}
+	}
+
+	return l
+}
+
+// NewLUT3D returns a new identity 3D LUT with the given size and the domain [0.0, 1.0].
+// It panics if size is less than 2.
+func NewLUT3D(size int) *LUT3D {
+	checkSize(size, "NewLUT3D")
+	l := &LUT3D{DomainMin: [3]float64{0, 0, 0}, DomainMax: [3]float64{1, 1, 1}, Size: size, Table: make([][3]float64, size*size*size)}
+	for b := 0; b < size; b++ {
+		for g := 0; g < size; g++ {
+			for r := 0; r < size; r++ {
+				l.Table[l.index(r, g, b)] = [3]float64{l.latticeValue(0, r), l.latticeValue(1, g), l.latticeValue(2, b)}
+			}
+		}
+	}
+
+	return l
+}
+
+// Bake3D returns a 3D LUT of the given size sampling the color function f over the domain [0.0, 1.0].
+// The alpha value of the colors given to f is 1.0 and the alpha value returned by f is ignored.
+// It panics if size is less than 2.
+func Bake3D(size int, f func(c floatcolor.NRGBAF64) floatcolor.NRGBAF64) *LUT3D {
+	return Bake3DWithDomain(size, [3]float64{0, 0, 0}, [3]float64{1, 1, 1}, f)
+}
+
+// Bake3DWithDomain returns a 3D LUT of the given size sampling the color function f over the given domain.
+// The alpha value of the colors given to f is 1.0 and the alpha value returned by f is ignored.
+// It panics if size is less than 2.
+func Bake3DWithDomain(size int, domainMin, domainMax [3]float64, f func(c floatcolor.NRGBAF64) floatcolor.NRGBAF64) *LUT3D {
+	checkSize(size, "Bake3DWithDomain")
+	l := &LUT3D{DomainMin: domainMin, DomainMax: domainMax, Size: size, Table: make([][3]float64, size*size*size)}
+	for b := 0; b < size; b++ {
+		for g := 0; g < size; g++ {
+			for r := 0; r < size; r++ {
+				c := f(floatcolor.NRGBAF64{R: l.latticeValue(0, r), G: l.latticeValue(1, g), B: l.latticeValue(2, b), A: 1.0})
+				l.Table[l.index(r, g, b)] = [3]float64{c.R, c.G, c.B}
+			}
+		}
+	}
+
+	return l
+}
+
+// Bake1D returns a 1D LUT of the given size sampling the per channel function f over the domain [0.0, 1.0].
+// It panics if size is less than 2.
+func Bake1D(size int, f func(v float64, channel int) float64) *LUT1D {
+	l := NewLUT1D(size)
+	for i := range l.Table {
+		v := float64(i) / float64(size-1)
+		l.Table[i] = [3]float64{f(v, 0), f(v, 1), f(v, 2)}
+	}
+
+	return l
+}
+
+// checkSize panics if size is less than 2, the smallest LUT size with distinct entries for the domain minimum and maximum.
+func checkSize(size int, constructor string) {
+	if size < 2 {
+		panic("lut: " + constructor + " size " + strconv.Itoa(size) + " is less than 2")
+	}
+}
+
+func (l *LUT3D) index(r, g, b int) int {
+	return r + g*l.Size + b*l.Size*l.Size
+}
+
+// latticeValue returns the input value of a channel at the lattice index i.
+func (l *LUT3D) latticeValue(channel int, i int) float64 {
+	return l.DomainMin[channel] + (l.DomainMax[channel]-l.DomainMin[channel])*float64(i)/float64(l.Size-1)
+}
+
+// Lookup returns the LUT output for the red, green, and blue input values.
+// Input values outside the domain are clamped to the domain.
+func (l *LUT1D) Lookup(r, g, b float64) (float64, float64, float64) {
+	in := [3]float64{r, g, b}
+	var out [3]float64
+	n := len(l.Table)
+
+	for c := 0; c < 3; c++ {
+		f := normalize(in[c], l.DomainMin[c], l.DomainMax[c]) * float64(n-1)
+		i := int(f)
+		if i >= n-1 {
+			i = n - 2
+		}
+		t := f - float64(i)
+		out[c] = l.Table[i][c]*(1-t) + l.Table[i+1][c]*t
+	}
+
+	return out[0], out[1], out[2]
+}
+
+// Lookup returns the LUT output for the red, green, and blue input values.
+// Input values outside the domain are clamped to the domain.
+func (l *LUT3D) Lookup(r, g, b float64) (float64, float64, float64) {
+	n := float64(l.Size - 1)
+	fr := normalize(r, l.DomainMin[0], l.DomainMax[0]) * n
+	fg := normalize(g, l.DomainMin[1], l.DomainMax[1]) * n
+	fb := normalize(b, l.DomainMin[2], l.DomainMax[2]) * n
+
+	r0, tr := l.cell(fr)
+	g0, tg := l.cell(fg)
+	b0, tb := l.cell(fb)
+
+	if l.Interpolation == Tetrahedral {
+		return l.tetrahedral(r0, g0, b0, tr, tg, tb)
+	}
+	return l.trilinear(r0, g0, b0, tr, tg, tb)
+}
+
+// cell returns the lower lattice index and the fractional position within the lattice cell.
+func (l *LUT3D) cell(f float64) (int, float64) {
+	i := int(f)
+	if i >= l.Size-1 {
+		i = l.Size - 2
+	}
+	return i, f - float64(i)
+}
+
+func (l *LUT3D) trilinear(r, g, b int, tr, tg, tb float64) (float64, float64, float64) {
+	var out [3]float64
+	for c := 0; c < 3; c++ {
+		c000 := l.Table[l.index(r, g, b)][c]
+		c100 := l.Table[l.index(r+1, g, b)][c]
+		c010 := l.Table[l.index(r, g+1, b)][c]
+		c110 := l.Table[l.index(r+1, g+1, b)][c]
+		c001 := l.Table[l.index(r, g, b+1)][c]
+		c101 := l.Table[l.index(r+1, g, b+1)][c]
+		c011 := l.Table[l.index(r, g+1, b+1)][c]
+		c111 := l.Table[l.index(r+1, g+1, b+1)][c]
+
+		c00 := c000*(1-tr) + c100*tr
+		c10 := c010*(1-tr) + c110*tr
+		c01 := c001*(1-tr) + c101*tr
+		c11 := c011*(1-tr) + c111*tr
+
+		c0 := c00*(1-tg) + c10*tg
+		c1 := c01*(1-tg) + c11*tg
+
+		out[c] = c0*(1-tb) + c1*tb
+	}
+
+	return out[0], out[1], out[2]
+}
+
+func (l *LUT3D) tetrahedral(r, g, b int, tr, tg, tb float64) (float64, float64, float64) {
+	c000 := l.Table[l.index(r, g, b)]
+	c111 := l.Table[l.index(r+1, g+1, b+1)]
+
+	// Select the tetrahedron by the order of the fractional positions.
+	var c1, c2 [3]float64
+	var w0, w1, w2, w3 float64
+	switch {
+	case tr >= tg && tg >= tb:
+		c1, c2 = l.Table[l.index(r+1, g, b)], l.Table[l.index(r+1, g+1, b)]
+		w0, w1, w2, w3 = 1-tr, tr-tg, tg-tb, tb
+	case tr >= tb && tb >= tg:
+		c1, c2 = l.Table[l.index(r+1, g, b)], l.Table[l.index(r+1, g, b+1)]
+		w0, w1, w2, w3 = 1-tr, tr-tb, tb-tg, tg
+	case tb >= tr && tr >= tg:
+		c1, c2 = l.Table[l.index(r, g, b+1)], l.Table[l.index(r+1, g, b+1)]
+		w0, w1, w2, w3 = 1-tb, tb-tr, tr-tg, tg
+	case tg >= tr && tr >= tb:
+		c1, c2 = l.Table[l.index(r, g+1, b)], l.Table[l.index(r+1, g+1, b)]
+		w0, w1, w2, w3 = 1-tg, tg-tr, tr-tb, tb
+	case tg >= tb && tb >= tr:
+		c1, c2 = l.Table[l.index(r, g+1, b)], l.Table[l.index(r, g+1, b+1)]
+		w0, w1, w2, w3 = 1-tg, tg-tb, tb-tr, tr
+	default: // tb >= tg && tg >= tr
+		c1, c2 = l.Table[l.index(r, g, b+1)], l.Table[l.index(r, g+1, b+1)]
+		w0, w1, w2, w3 = 1-tb, tb-tg, tg-tr, tr
+	}
+
+	var out [3]float64
+	for c := 0; c < 3; c++ {
+		out[c] = w0*c000[c] + w1*c1[c] + w2*c2[c] + w3*c111[c]
+	}
+
+	return out[0], out[1], out[2]
+}
+
+// Lookup applies the LUTs of the cube (the 1D LUT first if both are present) to a color.
+// Alpha is kept as is.
+func (cube *Cube) Lookup(c floatcolor.NRGBAF64) floatcolor.NRGBAF64 {
+	r, g, b := c.R, c.G, c.B
+	if cube.LUT1D != nil {
+		r, g, b = cube.LUT1D.Lookup(r, g, b)
+	}
+	if cube.LUT3D != nil {
+		r, g, b = cube.LUT3D.Lookup(r, g, b)
+	}
+
+	return floatcolor.NRGBAF64{R: r, G: g, B: b, A: c.A, Precise: c.Precise, Quantization: c.Quantization}
+}
+
+// Apply applies the LUTs of the cube to every pixel of the image, in place.
+// The LUT is applied to the non premultiplied red, green, and blue values and alpha is kept as is.
+func (cube *Cube) Apply(img draw.Image) {
+	floatimage.Map(img, cube.Lookup)
+}
+
+// ApplyParallel is like Apply, but processes float images in bands of rows on up to workers goroutines
+// (see floatimage.MapParallel).
+func (cube *Cube) ApplyParallel(img draw.Image, workers int) {
+	floatimage.MapParallel(img, workers, cube.Lookup)
+}
+
+// Apply applies the 1D LUT to every pixel of the image, in place.
+func (l *LUT1D) Apply(img draw.Image) {
+	(&Cube{LUT1D: l}).Apply(img)
+}
+
+// ApplyParallel is like Apply, but processes float images in bands of rows on up to workers goroutines.
+func (l *LUT1D) ApplyParallel(img draw.Image, workers int) {
+	(&Cube{LUT1D: l}).ApplyParallel(img, workers)
+}
+
+// Apply applies the 3D LUT to every pixel of the image, in place.
+func (l *LUT3D) Apply(img draw.Image) {
+	(&Cube{LUT3D: l}).Apply(img)
+}
+
+// ApplyParallel is like Apply, but processes float images in bands of rows on up to workers goroutines.
+func (l *LUT3D) ApplyParallel(img draw.Image, workers int) {
+	(&Cube{LUT3D: l}).ApplyParallel(img, workers)
+}
+
+// normalize maps v from the range [min, max] to [0.0, 1.0], clamping values outside the range.
+func normalize(v, min, max float64) float64 {
+	if max == min || math.IsNaN(v) {
+		return 0.0
+	}
+
+	t := (v - min) / (max - min)
+	if t < 0.0 {
+		return 0.0
+	} else if t > 1.0 {
+		return 1.0
+	}
+	return t
+}
@@ -0,0 +1,212 @@
+package lut
+
+import (
+	"bytes"
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"math"
+	"strings"
+	"testing"
+)
+
+func affine(c floatcolor.NRGBAF64) floatcolor.NRGBAF64 {
+	return floatcolor.NRGBAF64{R: 0.5*c.R + 0.25*c.G, G: 1.0 - c.G, B: 0.1 + 0.8*c.B, A: c.A}
+}
+
+func TestInterpolationIsExactForAffineFunctions(t *testing.T) {
+	l := Bake3D(5, affine)
+
+	for _, interpolation := range []Interpolation{Trilinear, Tetrahedral} {
+		l.Interpolation = interpolation
+		for _, in := range [][3]float64{{0.1, 0.2, 0.3}, {0.9, 0.33, 0.5}, {0.0, 1.0, 0.77}, {1.0, 1.0, 1.0}} {
+			expected := affine(floatcolor.NRGBAF64{R: in[0], G: in[1], B: in[2]})
+			r, g, b := l.Lookup(in[0], in[1], in[2])
+			if math.Abs(r-expected.R) > 1e-12 || math.Abs(g-expected.G) > 1e-12 || math.Abs(b-expected.B) > 1e-12 {
+				t.Errorf("interpolation %d: expected %+v for %v but got (%v, %v, %v)", interpolation, expected, in, r, g, b)
+			}
+		}
+	}
+}
+
+func TestTetrahedralPreservesNeutralAxis(t *testing.T) {
+	// A LUT with a non linear response along the gray axis
+	l := Bake3D(3, func(c floatcolor.NRGBAF64) floatcolor.NRGBAF64 {
+		return floatcolor.NRGBAF64{R: c.R * c.G * c.B, G: c.R * c.G * c.B, B: c.R * c.G * c.B}
+	})
+	l.Interpolation = Tetrahedral
+
+	r, g, b := l.Lookup(0.25, 0.25, 0.25)
+	if r != g || g != b {
+		t.Errorf("expected gray output for gray input but got (%v, %v, %v)", r, g, b)
+	}
+}
+
+func TestDomainClamping(t *testing.T) {
+	l := NewLUT3D(2)
+	l.DomainMin = [3]float64{-1, -1, -1}
+	l.DomainMax = [3]float64{3, 3, 3}
+	for i := range l.Table {
+		l.Table[i] = [3]float64{l.Table[i][0] * 2, l.Table[i][1] * 2, l.Table[i][2] * 2}
+	}
+
+	r, g, b := l.Lookup(-5, 1, 10)
+	if r != 0 || g != 1 || b != 2 {
+		t.Errorf("unexpected output (%v, %v, %v)", r, g, b)
+	}
+}
+
+const resolveCube = `# Created by hand
+TITLE "Shaper and cube"
+LUT_1D_SIZE 3
+LUT_1D_INPUT_RANGE 0.0 2.0
+LUT_3D_SIZE 2
+LUT_3D_INPUT_RANGE 0.0 1.0
+
+0.0 0.0 0.0
+0.5 0.5 0.5
+1.0 1.0 1.0
+0 0 0
+1 0 0
+0 1 0
+1 1 0
+0 0 1
+1 0 1
+0 1 1
+1 1 1
+`
+
+func TestReadResolveCube(t *testing.T) {
+	cube, err := ReadCube(strings.NewReader(resolveCube))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cube.Title != "Shaper and cube" || cube.LUT1D == nil || cube.LUT3D == nil {
+		t.Fatalf("unexpected cube %+v", cube)
+	}
+	if cube.LUT1D.DomainMax != [3]float64{2, 2, 2} {
+		t.Errorf("unexpected 1D domain %v", cube.LUT1D.DomainMax)
+	}
+
+	c := cube.Lookup(floatcolor.NRGBAF64{R: 1.0, G: 0.5, B: 2.0, A: 0.5})
+	if c.R != 0.5 || c.G != 0.25 || c.B != 1.0 || c.A != 0.5 {
+		t.Errorf("unexpected lookup result %+v", c)
+	}
+}
+
+func TestWriteAndReadCube(t *testing.T) {
+	l := Bake3DWithDomain(4, [3]float64{0, 0, 0}, [3]float64{2, 4, 8}, affine)
+	cube := &Cube{Title: "Affine", LUT3D: l}
+
+	var buf bytes.Buffer
+	if err := cube.WriteCube(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "DOMAIN_MAX 2 4 8") {
+		t.Errorf("expected domain in written cube:\n%s", buf.String())
+	}
+
+	read, err := ReadCube(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if read.Title != cube.Title || read.LUT3D.Size != l.Size || read.LUT3D.DomainMax != l.DomainMax {
+		t.Errorf("unexpected cube header after round trip %+v", read)
+	}
+	for i := range l.Table {
+		if read.LUT3D.Table[i] != l.Table[i] {
+			t.Fatalf("table entry %d differs after round trip: %v != %v", i, read.LUT3D.Table[i], l.Table[i])
+		}
+	}
+}
+
+func TestCubeTitle(t *testing.T) {
+	for _, title := range []string{"", "Affine", " spaced ", "'single' quotes", "Ünïcode \\ slash"} {
+		var buf bytes.Buffer
+		if err := (&Cube{Title: title, LUT3D: NewLUT3D(2)}).WriteCube(&buf); err != nil {
+			t.Fatal(err)
+		}
+		read, err := ReadCube(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if read.Title != title {
+			t.Errorf("expected title %q after round trip but got %q", title, read.Title)
+		}
+	}
+
+	for _, title := range []string{`"quoted"`, `trailing "`, "two\nlines", "carriage\rreturn"} {
+		var buf bytes.Buffer
+		if err := (&Cube{Title: title, LUT3D: NewLUT3D(2)}).WriteCube(&buf); err == nil {
+			t.Errorf("expected error for title %q but wrote:\n%s", title, buf.String())
+		}
+	}
+}
+
+func TestReadCubeErrors(t *testing.T) {
+	tests := []string{
+		"0 0 0\n",
+		"LUT_3D_SIZE 2\n0 0 0\n",
+		"LUT_3D_SIZE 1\n0 0 0\n",
+		"LUT_1D_SIZE 2\n0 0\n1 1 1\n",
+		"LUT_1D_SIZE 2\n0 0 x\n1 1 1\n",
+		"LUT_1D_SIZE 2\n0 0 0\nDOMAIN_MIN 0 0 0\n1 1 1\n",
+	}
+
+	for _, test := range tests {
+		if _, err := ReadCube(strings.NewReader(test)); err == nil {
+			t.Errorf("expected error for cube %q", test)
+		}
+	}
+}
+
+func TestInvalidSize(t *testing.T) {
+	identity := func(c floatcolor.NRGBAF64) floatcolor.NRGBAF64 { return c }
+	for _, size := range []int{-1, 0, 1} {
+		for name, f := range map[string]func(){
+			"NewLUT1D": func() { NewLUT1D(size) },
+			"NewLUT3D": func() { NewLUT3D(size) },
+			"Bake1D":   func() { Bake1D(size, func(v float64, channel int) float64 { return v }) },
+			"Bake3D":   func() { Bake3D(size, identity) },
+		} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("%s: expected a panic for size %d", name, size)
+					}
+				}()
+				f()
+			}()
+		}
+	}
+
+	if r, g, b := NewLUT3D(2).Lookup(0.5, 0.25, 1.0); math.Abs(r-0.5) > 1e-12 || math.Abs(g-0.25) > 1e-12 || math.Abs(b-1.0) > 1e-12 {
+		t.Errorf("expected the smallest LUT to be an identity but got %v, %v, %v", r, g, b)
+	}
+}
+
+func TestApply(t *testing.T) {
+	l := Bake3D(9, affine)
+	expected := affine(floatcolor.NRGBAF64{R: 0.4, G: 0.6, B: 0.2, A: 0.5})
+
+	nrgbaf32 := floatimage.NewNRGBAF32(2, 2)
+	nrgbaf32.Set(1, 1, floatcolor.NRGBAF64{R: 0.4, G: 0.6, B: 0.2, A: 0.5})
+	l.Apply(nrgbaf32)
+	if c := nrgbaf32.At(1, 1).(floatcolor.NRGBAF32); math.Abs(float64(c.R)-expected.R) > 1e-6 || c.A != 0.5 {
+		t.Errorf("unexpected NRGBAF32 result %+v", c)
+	}
+
+	rgbaf64 := floatimage.NewRGBAF64(2, 2)
+	rgbaf64.Set(1, 1, floatcolor.NRGBAF64{R: 0.4, G: 0.6, B: 0.2, A: 0.5})
+	l.Apply(rgbaf64)
+	if c := rgbaf64.At(1, 1).(floatcolor.RGBAF64); math.Abs(c.G-expected.G*0.5) > 1e-12 || c.A != 0.5 {
+		t.Errorf("unexpected RGBAF64 result %+v", c)
+	}
+	parallel := floatimage.NewRGBAF64(2, 2)
+	parallel.Set(1, 1, floatcolor.NRGBAF64{R: 0.4, G: 0.6, B: 0.2, A: 0.5})
+	l.ApplyParallel(parallel, 2)
+	if parallel.At(1, 1) != rgbaf64.At(1, 1) {
+		t.Errorf("expected the parallel LUT to give the same result but got %+v", parallel.At(1, 1))
+	}
+}
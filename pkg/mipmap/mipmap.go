@@ -0,0 +1,238 @@
+package mipmap
+
+import (
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"floatimage/pkg/resample"
+	"floatimage/pkg/sampler"
+	"image"
+	"math"
+)
+
+// Downsample is the filter used to create each mip level from the level above.
+type Downsample int
+
+const (
+	// DownsampleBox averages the pixels of the level above (area average).
+	DownsampleBox Downsample = iota
+	// DownsampleKaiser uses a Kaiser windowed sinc filter, which keeps the levels sharper than a box filter.
+	DownsampleKaiser
+)
+
+// DefaultMaxAnisotropy is the default limit of the ratio between the major and minor axis of the EWA filter ellipse.
+const DefaultMaxAnisotropy = 8.0
+
+// ewaLookupTableSize is the size of the lookup table of the gaussian EWA filter weights.
+const ewaLookupTableSize = 128
+
+// MipMap is a pyramid of successively half sized (rounded down, at least one pixel) versions of an image,
+// used for filtered texture lookups where a pixel covers a larger area of the texture.
+// Level 0 is the image itself. Images of any size are supported, not only powers of two.
+type MipMap struct {
+	// MaxAnisotropy limits the eccentricity of the EWA filter ellipse.
+	// More eccentric ellipses are made rounder (blurrier) to keep the lookup cost bounded.
+	MaxAnisotropy float64
+
+	like     image.Image
+	levels   []*floatimage.RGBAF64
+	samplers []*sampler.Sampler
+}
+
+// New creates a new mip map of the image, downsampled with the given filter.
+// The wrap mode is used for lookups outside the image. The levels are computed in premultiplied float64 precision.
+func New(img image.Image, downsample Downsample, wrap sampler.Wrap) *MipMap {
+	filter := resample.Box
+	if downsample == DownsampleKaiser {
+		filter = resample.Kaiser(3, 4)
+	}
+
+	m := &MipMap{MaxAnisotropy: DefaultMaxAnisotropy, like: img}
+
+	level := floatimage.ToRGBAF64(img)
+	level.Rect = level.Rect.Sub(level.Rect.Min)
+	for {
+		s := sampler.New(level, sampler.Bilinear, wrap)
+		m.levels = append(m.levels, level)
+		m.samplers = append(m.samplers, s)
+
+		width, height := level.Rect.Dx(), level.Rect.Dy()
+		if width <= 1 && height <= 1 {
+			break
+		}
+
+		level = resample.Resize(level, maxInt(1, width/2), maxInt(1, height/2), filter).(*floatimage.RGBAF64)
+	}
+
+	return m
+}
+
+// Levels returns the number of levels of the mip map.
+func (m *MipMap) Levels() int {
+	return len(m.levels)
+}
+
+// Level returns the given level as a new float image of the same type as the image the mip map was created from,
+// with bounds starting at (0, 0).
+func (m *MipMap) Level(level int) floatimage.FloatImage {
+	return floatimage.FromRGBAF64(cloneRGBAF64(m.levels[level]), m.like)
+}
+
+// LevelSize returns the width and height of the given level.
+func (m *MipMap) LevelSize(level int) (width, height int) {
+	return m.levels[level].Rect.Dx(), m.levels[level].Rect.Dy()
+}
+
+// Sample returns the premultiplied color at normalized coordinates (u, v) at the given level of detail.
+// Levels are sampled bilinearly and fractional levels of detail are interpolated linearly between two levels (trilinear filtering).
+func (m *MipMap) Sample(u, v, lod float64) floatcolor.RGBAF64 {
+	last := float64(len(m.levels) - 1)
+	switch {
+	case !(lod > 0):
+		return m.samplers[0].AtUV(u, v)
+	case lod >= last:
+		return m.samplers[len(m.levels)-1].AtUV(u, v)
+	}
+
+	l0 := math.Floor(lod)
+	t := lod - l0
+	c0, c1 := m.samplers[int(l0)].AtUV(u, v), m.samplers[int(l0)+1].AtUV(u, v)
+	return lerp(c0, c1, t)
+}
+
+// Trilinear returns the trilinearly filtered premultiplied color at normalized coordinates (u, v),
+// where width is the width of the filter footprint in normalized coordinates.
+func (m *MipMap) Trilinear(u, v, width float64) floatcolor.RGBAF64 {
+	w, h := m.LevelSize(0)
+	return m.Sample(u, v, math.Log2(width*float64(maxInt(w, h))))
+}
+
+// Anisotropic returns the premultiplied color at normalized coordinates (u, v) filtered with an elliptically weighted average (EWA)
+// over the footprint given by the screen space derivatives of the coordinates, (dudx, dvdx) and (dudy, dvdy).
+// Elongated footprints, such as on surfaces seen at grazing angles, are filtered along their major axis
+// instead of being blurred along both axes as with trilinear filtering. Infinite derivatives give the coarsest level,
+// NaN derivatives are ignored.
+func (m *MipMap) Anisotropic(u, v, dudx, dvdx, dudy, dvdy float64) floatcolor.RGBAF64 {
+	major, minor := [2]float64{nanToZero(dudx), nanToZero(dvdx)}, [2]float64{nanToZero(dudy), nanToZero(dvdy)}
+	if major[0]*major[0]+major[1]*major[1] < minor[0]*minor[0]+minor[1]*minor[1] {
+		major, minor = minor, major
+	}
+	majorLength := math.Hypot(major[0], major[1])
+	minorLength := math.Hypot(minor[0], minor[1])
+	if math.IsInf(majorLength, 0) {
+		return m.samplers[len(m.levels)-1].AtUV(u, v)
+	}
+
+	// Clamp the eccentricity of the ellipse.
+	maxAnisotropy := math.Max(m.MaxAnisotropy, 1.0)
+	if minorLength*maxAnisotropy < majorLength && minorLength > 0 {
+		scale := majorLength / (minorLength * maxAnisotropy)
+		minor[0], minor[1] = minor[0]*scale, minor[1]*scale
+		minorLength *= scale
+	}
+	if minorLength == 0 {
+		return m.samplers[0].AtUV(u, v)
+	}
+
+	w, h := m.LevelSize(0)
+	lod := math.Max(0, math.Log2(minorLength*float64(maxInt(w, h))))
+	if !(lod < float64(len(m.levels)-1)) {
+		return m.samplers[len(m.levels)-1].AtUV(u, v)
+	}
+	l0 := int(math.Floor(lod))
+
+	c0 := m.ewa(l0, u, v, major, minor)
+	c1 := m.ewa(l0+1, u, v, major, minor)
+	return lerp(c0, c1, lod-float64(l0))
+}
+
+// nanToZero returns v, or zero if v is NaN, so undefined derivatives do not widen the filter ellipse.
+func nanToZero(v float64) float64 {
+	if math.IsNaN(v) {
+		return 0
+	}
+	return v
+}
+
+// ewa returns the gaussian weighted average of the pixels of the level within the ellipse with axes major and minor around (u, v).
+// See "Physically Based Rendering", section 10.4.5.
+func (m *MipMap) ewa(level int, u, v float64, major, minor [2]float64) floatcolor.RGBAF64 {
+	w, h := m.LevelSize(level)
+	s, t := u*float64(w)-0.5, v*float64(h)-0.5
+	major = [2]float64{major[0] * float64(w), major[1] * float64(h)}
+	minor = [2]float64{minor[0] * float64(w), minor[1] * float64(h)}
+
+	// Implicit ellipse coefficients, with a minimum size of one pixel.
+	a := major[1]*major[1] + minor[1]*minor[1] + 1
+	b := -2 * (major[0]*major[1] + minor[0]*minor[1])
+	c := major[0]*major[0] + minor[0]*minor[0] + 1
+	invF := 1 / (a*c - b*b*0.25)
+	a, b, c = a*invF, b*invF, c*invF
+
+	// Bounding box of the ellipse in pixel space.
+	det := -b*b + 4*a*c
+	invDet := 1 / det
+	uSqrt, vSqrt := math.Sqrt(det*c), math.Sqrt(a*det)
+	s0, s1 := int(math.Ceil(s-2*invDet*uSqrt)), int(math.Floor(s+2*invDet*uSqrt))
+	t0, t1 := int(math.Ceil(t-2*invDet*vSqrt)), int(math.Floor(t+2*invDet*vSqrt))
+
+	var sum [4]float64
+	weightSum := 0.0
+	for it := t0; it <= t1; it++ {
+		tt := float64(it) - t
+		for is := s0; is <= s1; is++ {
+			ss := float64(is) - s
+			r2 := a*ss*ss + b*ss*tt + c*tt*tt
+			if r2 < 1 {
+				weight := ewaWeights[minInt(int(r2*ewaLookupTableSize), ewaLookupTableSize-1)]
+				p := m.samplers[level].Pixel(is, it)
+				sum[0], sum[1], sum[2], sum[3] = sum[0]+weight*p.R, sum[1]+weight*p.G, sum[2]+weight*p.B, sum[3]+weight*p.A
+				weightSum += weight
+			}
+		}
+	}
+
+	if weightSum == 0 {
+		return m.samplers[level].AtUV(u, v)
+	}
+	return floatcolor.RGBAF64{R: sum[0] / weightSum, G: sum[1] / weightSum, B: sum[2] / weightSum, A: sum[3] / weightSum}
+}
+
+// ewaWeights is the gaussian filter weights of the EWA filter indexed by squared radius (in [0, 1)).
+var ewaWeights = func() [ewaLookupTableSize]float64 {
+	const alpha = 2.0
+	var weights [ewaLookupTableSize]float64
+	for i := range weights {
+		r2 := float64(i) / float64(ewaLookupTableSize-1)
+		weights[i] = math.Exp(-alpha*r2) - math.Exp(-alpha)
+	}
+	return weights
+}()
+
+func lerp(c0, c1 floatcolor.RGBAF64, t float64) floatcolor.RGBAF64 {
+	return floatcolor.RGBAF64{
+		R: c0.R + (c1.R-c0.R)*t,
+		G: c0.G + (c1.G-c0.G)*t,
+		B: c0.B + (c1.B-c0.B)*t,
+		A: c0.A + (c1.A-c0.A)*t,
+	}
+}
+
+func cloneRGBAF64(img *floatimage.RGBAF64) *floatimage.RGBAF64 {
+	clone := *img
+	clone.Pix = append([]float64(nil), img.Pix...)
+	return &clone
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,106 @@
+package mipmap
+
+import (
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"floatimage/pkg/sampler"
+	"math"
+	"testing"
+)
+
+func TestLevels(t *testing.T) {
+	img := floatimage.NewNRGBAF32WithBounds(3, 3, 16, 8)
+	for y := 3; y < 8; y++ {
+		for x := 3; x < 16; x++ {
+			img.Set(x, y, floatcolor.NRGBAF32{R: 3.0, G: 0.5, B: 0.25, A: 0.5})
+		}
+	}
+
+	for _, downsample := range []Downsample{DownsampleBox, DownsampleKaiser} {
+		m := New(img, downsample, sampler.WrapClamp)
+
+		expectedSizes := [][2]int{{13, 5}, {6, 2}, {3, 1}, {1, 1}}
+		if m.Levels() != len(expectedSizes) {
+			t.Fatalf("expected %d levels but got %d", len(expectedSizes), m.Levels())
+		}
+
+		for level, size := range expectedSizes {
+			if w, h := m.LevelSize(level); w != size[0] || h != size[1] {
+				t.Errorf("expected level %d to be %dx%d but got %dx%d", level, size[0], size[1], w, h)
+			}
+
+			levelImage, ok := m.Level(level).(*floatimage.NRGBAF32)
+			if !ok {
+				t.Fatalf("expected level of the same type as the source image")
+			}
+			if c := levelImage.At(0, 0).(floatcolor.NRGBAF32); math.Abs(float64(c.R-3.0)) > 1e-5 || math.Abs(float64(c.A-0.5)) > 1e-5 {
+				t.Errorf("expected constant image levels to be constant but got %+v at level %d", c, level)
+			}
+		}
+
+		if c := m.Trilinear(0.3, 0.6, 0.2); math.Abs(c.R-1.5) > 1e-9 || math.Abs(c.A-0.5) > 1e-9 {
+			t.Errorf("expected constant premultiplied lookup but got %+v", c)
+		}
+		if c := m.Anisotropic(0.3, 0.6, 0.01, 0.0, 0.0, 0.3); math.Abs(c.R-1.5) > 1e-9 || math.Abs(c.A-0.5) > 1e-9 {
+			t.Errorf("expected constant premultiplied lookup but got %+v", c)
+		}
+	}
+}
+
+func TestAnisotropicKeepsDetailAlongMinorAxis(t *testing.T) {
+	// Vertical one pixel wide stripes
+	img := floatimage.NewGrayF64(64, 64)
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x += 2 {
+			img.Set(x, y, floatcolor.GrayF64{Y: 1.0})
+		}
+	}
+
+	m := New(img, DownsampleBox, sampler.WrapRepeat)
+	u, v := 10.5/64, 0.5
+
+	// A footprint stretched along the stripes keeps the stripe, trilinear filtering of the same footprint blurs it away.
+	if c := m.Anisotropic(u, v, 0.25/64, 0, 0, 2.0/64); c.R < 0.65 {
+		t.Errorf("expected anisotropic lookup to keep the stripe but got %v", c.R)
+	}
+	if c := m.Trilinear(u, v, 2.0/64); math.Abs(c.R-0.5) > 1e-9 {
+		t.Errorf("expected trilinear lookup to blur the stripes to 0.5 but got %v", c.R)
+	}
+
+	if c := m.Sample(u, v, float64(m.Levels())); math.Abs(c.R-0.5) > 1e-9 {
+		t.Errorf("expected the last level to be the average 0.5 but got %v", c.R)
+	}
+	if c := m.Sample(u, v, -1); c.R != 1.0 {
+		t.Errorf("expected negative level of detail to sample level 0 but got %v", c.R)
+	}
+}
+
+func TestAnisotropicDegenerateFootprints(t *testing.T) {
+	img := floatimage.NewGrayF64(8, 8)
+	img.Set(4, 4, floatcolor.GrayF64{Y: 1.0})
+	m := New(img, DownsampleBox, sampler.WrapClamp)
+	coarsest := m.Sample(0.5, 0.5, float64(m.Levels()))
+
+	inf, nan := math.Inf(1), math.NaN()
+	for _, d := range [][4]float64{
+		{inf, 0, 0, inf},
+		{inf, 0, 0, 0},
+		{0, 0.01, inf, 0},
+		{-inf, nan, nan, inf},
+	} {
+		if c := m.Anisotropic(0.5, 0.5, d[0], d[1], d[2], d[3]); c != coarsest {
+			t.Errorf("expected the coarsest level for derivatives %v but got %+v", d, c)
+		}
+	}
+
+	for _, d := range [][4]float64{
+		{nan, nan, nan, nan},
+		{nan, 0, 0, 1.0 / 8},
+		{1.0 / 8, nan, 0, 1.0 / 8},
+	} {
+		c := m.Anisotropic(0.5, 0.5, d[0], d[1], d[2], d[3])
+		if math.IsNaN(c.R) || math.IsNaN(c.A) || c.A != 1.0 {
+			t.Errorf("expected NaN derivatives to be ignored for %v but got %+v", d, c)
+		}
+	}
+}
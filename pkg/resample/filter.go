@@ -0,0 +1,112 @@
+package resample
+
+import (
+	"fmt"
+	"math"
+)
+
+// Filter is a resampling filter, a kernel function that is non zero within [-Support, Support].
+// A filter with zero support picks the nearest source pixel.
+type Filter struct {
+	Name    string
+	Support float64
+	Kernel  func(x float64) float64
+}
+
+var (
+	// Nearest picks the nearest source pixel. It is fast but aliases when downsampling.
+	Nearest = Filter{Name: "nearest"}
+
+	// Box averages the source pixels covered by a destination pixel (area average) when downsampling,
+	// and behaves like nearest neighbour when upsampling.
+	Box = Filter{Name: "box", Support: 0.5, Kernel: func(x float64) float64 {
+		if x >= -0.5 && x < 0.5 {
+			return 1.0
+		}
+		return 0.0
+	}}
+
+	// Bilinear is the triangle (tent) filter, linear interpolation between neighbouring pixels.
+	Bilinear = Filter{Name: "bilinear", Support: 1.0, Kernel: func(x float64) float64 {
+		x = math.Abs(x)
+		if x < 1.0 {
+			return 1.0 - x
+		}
+		return 0.0
+	}}
+
+	// CatmullRom is the bicubic Catmull-Rom spline (B=0, C=0.5), sharp and interpolating.
+	CatmullRom = Bicubic("catmull-rom", 0.0, 0.5)
+
+	// MitchellNetravali is the bicubic filter recommended by Mitchell and Netravali (B=1/3, C=1/3),
+	// a good compromise between blurring and ringing.
+	MitchellNetravali = Bicubic("mitchell-netravali", 1.0/3.0, 1.0/3.0)
+
+	// BSpline is the cubic B-spline (B=1, C=0), smooth without any ringing but blurry.
+	BSpline = Bicubic("b-spline", 1.0, 0.0)
+
+	// Lanczos2 is the Lanczos windowed sinc filter with two lobes.
+	Lanczos2 = Lanczos(2)
+
+	// Lanczos3 is the Lanczos windowed sinc filter with three lobes. It is sharp with some ringing at hard edges.
+	Lanczos3 = Lanczos(3)
+)
+
+// Bicubic returns the bicubic filter of the Mitchell-Netravali family with parameters b and c.
+func Bicubic(name string, b, c float64) Filter {
+	return Filter{Name: name, Support: 2.0, Kernel: func(x float64) float64 {
+		x = math.Abs(x)
+		switch {
+		case x < 1.0:
+			return ((12-9*b-6*c)*x*x*x + (-18+12*b+6*c)*x*x + (6 - 2*b)) / 6
+		case x < 2.0:
+			return ((-b-6*c)*x*x*x + (6*b+30*c)*x*x + (-12*b-48*c)*x + (8*b + 24*c)) / 6
+		default:
+			return 0.0
+		}
+	}}
+}
+
+// Lanczos returns the Lanczos windowed sinc filter with the given number of lobes.
+func Lanczos(lobes int) Filter {
+	a := float64(lobes)
+	return Filter{Name: fmt.Sprintf("lanczos%d", lobes), Support: a, Kernel: func(x float64) float64 {
+		if x <= -a || x >= a {
+			return 0.0
+		}
+		return sinc(x) * sinc(x/a)
+	}}
+}
+
+// Kaiser returns a Kaiser windowed sinc filter with the given support, where alpha is the shape parameter
+// of the window. Higher alpha values give less ringing but a softer result.
+func Kaiser(support, alpha float64) Filter {
+	scale := 1.0 / besselI0(alpha)
+	return Filter{Name: "kaiser", Support: support, Kernel: func(x float64) float64 {
+		if x <= -support || x >= support {
+			return 0.0
+		}
+		t := x / support
+		return sinc(x) * besselI0(alpha*math.Sqrt(1-t*t)) * scale
+	}}
+}
+
+// besselI0 returns the zeroth order modified Bessel function of the first kind.
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	halfX := x / 2
+	for k := 1; k < 50 && term > sum*1e-17; k++ {
+		f := halfX / float64(k)
+		term *= f * f
+		sum += term
+	}
+	return sum
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1.0
+	}
+	x *= math.Pi
+	return math.Sin(x) / x
+}
@@ -0,0 +1,149 @@
+package resample
+
+import (
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"image"
+	"math"
+	"testing"
+)
+
+var filters = []Filter{Nearest, Box, Bilinear, CatmullRom, MitchellNetravali, BSpline, Lanczos2, Lanczos3, Kaiser(3, 4)}
+
+func TestResizeConstantImage(t *testing.T) {
+	img := floatimage.NewNRGBAF32WithBounds(5, 5, 25, 17)
+	for y := 5; y < 17; y++ {
+		for x := 5; x < 25; x++ {
+			img.Set(x, y, floatcolor.NRGBAF32{R: 4.0, G: 0.5, B: 0.25, A: 0.5})
+		}
+	}
+
+	for _, filter := range filters {
+		for _, size := range [][2]int{{7, 5}, {53, 31}, {1, 1}, {20, 1}} {
+			result, ok := Resize(img, size[0], size[1], filter).(*floatimage.NRGBAF32)
+			if !ok {
+				t.Fatalf("expected result of the same type as the source")
+			}
+			if result.Rect != image.Rect(0, 0, size[0], size[1]) {
+				t.Errorf("%s: expected bounds %v but got %v", filter.Name, image.Rect(0, 0, size[0], size[1]), result.Rect)
+			}
+
+			for i, v := range result.Pix {
+				expected := []float32{4.0, 0.5, 0.25, 0.5}[i%4]
+				if math.Abs(float64(v-expected)) > 1e-5 {
+					t.Fatalf("%s: expected constant image to stay constant but got %v for %v", filter.Name, v, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestResizeTiledAndViews(t *testing.T) {
+	img := floatimage.NewNRGBAF32(20, 12)
+	for i := range img.Pix {
+		img.Pix[i] = 0.5
+	}
+	expected := Resize(img, 7, 5, Lanczos3).(*floatimage.NRGBAF32)
+
+	tiled, ok := Resize(floatimage.NewTiledFrom(img, 8, 8), 7, 5, Lanczos3).(*floatimage.Tiled)
+	if !ok || tiled.TileWidth != 8 || tiled.TileHeight != 8 {
+		t.Fatalf("expected a tiled result with the tile size of the source but got %T", tiled)
+	}
+	if flat := tiled.Flatten().(*floatimage.NRGBAF32); !equalPix(flat.Pix, expected.Pix) {
+		t.Errorf("expected tiled result to be the same as the row-major result")
+	}
+
+	aov := floatimage.NewMultiChannelF32(20, 12, "R", "G", "B", "A")
+	for i := range aov.Pix {
+		aov.Pix[i] = 0.5
+	}
+	view, _ := aov.ViewNRGBAF32("R", "G", "B", "A")
+	if result, ok := Resize(view, 7, 5, Lanczos3).(*floatimage.NRGBAF32); !ok || !equalPix(result.Pix, expected.Pix) {
+		t.Errorf("expected an NRGBAF32 result for a channel view but got %T", result)
+	}
+}
+
+func equalPix(a, b []float32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestResizeSameSizeWithInterpolatingFilters(t *testing.T) {
+	img := floatimage.NewRGBAF64(9, 7)
+	for i := range img.Pix {
+		img.Pix[i] = float64(i%13) / 13.0
+	}
+
+	for _, filter := range []Filter{Nearest, Box, Bilinear, CatmullRom, Lanczos2, Lanczos3} {
+		result := Resize(img, 9, 7, filter).(*floatimage.RGBAF64)
+		for i := range img.Pix {
+			if math.Abs(result.Pix[i]-img.Pix[i]) > 1e-12 {
+				t.Fatalf("%s: expected same size resize to keep the image", filter.Name)
+			}
+		}
+	}
+}
+
+func TestBoxDownsampleIsAreaAverage(t *testing.T) {
+	img := floatimage.NewNRGBAF64(4, 2)
+	img.Set(0, 0, floatcolor.NRGBAF64{R: 1.0, A: 1.0})
+	img.Set(1, 0, floatcolor.NRGBAF64{G: 1.0, A: 0.0}) // Invisible green
+	img.Set(0, 1, floatcolor.NRGBAF64{R: 1.0, A: 1.0})
+	img.Set(1, 1, floatcolor.NRGBAF64{B: 8.0, A: 0.5})
+
+	result := Resize(img, 2, 1, Box).(*floatimage.NRGBAF64)
+	c := result.At(0, 0).(floatcolor.NRGBAF64)
+
+	expected := floatcolor.NRGBAF64{R: 2.0 / 2.5, G: 0.0, B: 4.0 / 2.5, A: 2.5 / 4.0}
+	if math.Abs(c.R-expected.R) > 1e-12 || c.G != 0 || math.Abs(c.B-expected.B) > 1e-12 || math.Abs(c.A-expected.A) > 1e-12 {
+		t.Errorf("expected area average %+v but got %+v", expected, c)
+	}
+}
+
+func TestResizeIgnoresTransparentColor(t *testing.T) {
+	previous, previousEpsilon := floatcolor.CurrentZeroAlphaPolicy()
+	defer floatcolor.SetZeroAlphaPolicy(previous, previousEpsilon)
+
+	for _, policy := range []floatcolor.ZeroAlphaPolicy{floatcolor.ZeroColor, floatcolor.PreserveHiddenColor, floatcolor.EpsilonClamp} {
+		floatcolor.SetZeroAlphaPolicy(policy, 0)
+
+		img := floatimage.NewNRGBAF64(2, 1)
+		img.Set(0, 0, floatcolor.NRGBAF64{R: 1.0, A: 0.0}) // Transparent red
+		img.Set(1, 0, floatcolor.NRGBAF64{B: 1.0, A: 1.0})
+
+		c := Resize(img, 1, 1, Box).(*floatimage.NRGBAF64).NRGBAF64At(0, 0)
+		if c.R != 0 || math.Abs(c.B-1.0) > 1e-12 || math.Abs(c.A-0.5) > 1e-12 {
+			t.Errorf("policy %d: expected half transparent blue but got %+v", policy, c)
+		}
+	}
+}
+
+func TestFilterKernels(t *testing.T) {
+	for _, filter := range filters[1:] {
+		// Normalized filters sum up to one over all integer offsets.
+		sum := 0.0
+		for x := -filter.Support; x <= filter.Support; x++ {
+			sum += filter.Kernel(x)
+		}
+		if filter.Name != "box" && math.Abs(sum-1.0) > 1e-12 {
+			t.Errorf("%s: expected kernel to sum up to 1.0 but got %v", filter.Name, sum)
+		}
+	}
+
+	if v := CatmullRom.Kernel(1.0); v != 0 {
+		t.Errorf("expected interpolating Catmull-Rom to be zero at 1.0 but got %v", v)
+	}
+	if v := besselI0(1.0); math.Abs(v-1.2660658777520082) > 1e-15 {
+		t.Errorf("expected I0(1) to be 1.2660658777520082 but got %v", v)
+	}
+	if v := BSpline.Kernel(0.0); math.Abs(v-2.0/3.0) > 1e-12 {
+		t.Errorf("expected B-spline value 2/3 at 0.0 but got %v", v)
+	}
+}
@@ -0,0 +1,130 @@
+package resample
+
+import (
+	"floatimage/pkg/floatimage"
+	"image"
+	"math"
+)
+
+// Resize returns the image scaled to width x height pixels as a new float image of the same type as img
+// (see floatimage.NewLike), with bounds starting at (0, 0).
+//
+// The image is resampled separably, first horizontally and then vertically, in float64 precision
+// on premultiplied values so that transparent pixels do not bleed their color into their neighbours.
+// When downsampling, the filter is widened by the scale factor to avoid aliasing.
+// Pixels outside the source image are taken as the nearest edge pixel.
+func Resize(img image.Image, width, height int, filter Filter) floatimage.FloatImage {
+	if width < 0 {
+		width = 0
+	}
+	if height < 0 {
+		height = 0
+	}
+
+	src := floatimage.ToRGBAF64(img)
+	srcWidth, srcHeight := src.Rect.Dx(), src.Rect.Dy()
+
+	var result *floatimage.RGBAF64
+	if srcWidth == 0 || srcHeight == 0 {
+		result = floatimage.NewRGBAF64(width, height)
+	} else {
+		temp := resample(src, width, srcHeight, contributions(srcWidth, width, filter), false)
+		result = resample(temp, width, height, contributions(srcHeight, height, filter), true)
+	}
+
+	return floatimage.FromRGBAF64(result, img)
+}
+
+// contribution is the weights of the source pixels start, start+1, ... for a destination pixel.
+type contribution struct {
+	start   int
+	weights []float64
+}
+
+// contributions returns the filter weights of the source pixels for each destination pixel,
+// when resampling a line of srcLength pixels to dstLength pixels.
+func contributions(srcLength, dstLength int, filter Filter) []contribution {
+	result := make([]contribution, dstLength)
+	scale := float64(srcLength) / float64(dstLength)
+
+	filterScale := math.Max(scale, 1.0)
+	support := filter.Support * filterScale
+
+	for i := range result {
+		center := (float64(i) + 0.5) * scale
+
+		if filter.Support == 0 || filter.Kernel == nil {
+			result[i] = contribution{start: clampInt(int(math.Floor(center)), 0, srcLength-1), weights: []float64{1.0}}
+			continue
+		}
+
+		first := int(math.Floor(center - support))
+		last := int(math.Ceil(center + support))
+		start, end := clampInt(first, 0, srcLength-1), clampInt(last, 0, srcLength-1)
+
+		weights := make([]float64, end-start+1)
+		total := 0.0
+		for j := first; j <= last; j++ {
+			w := filter.Kernel((float64(j) + 0.5 - center) / filterScale)
+			if w == 0 {
+				continue
+			}
+			weights[clampInt(j, 0, srcLength-1)-start] += w
+			total += w
+		}
+
+		if total == 0 {
+			result[i] = contribution{start: clampInt(int(math.Floor(center)), 0, srcLength-1), weights: []float64{1.0}}
+			continue
+		}
+
+		for j := range weights {
+			weights[j] /= total
+		}
+		result[i] = contribution{start: start, weights: weights}
+	}
+
+	return result
+}
+
+// resample resamples every line of src into a new width x height image using the contributions,
+// along the rows or (if vertical) along the columns. The other dimension is the same for src and the new image.
+func resample(src *floatimage.RGBAF64, width, height int, contributions []contribution, vertical bool) *floatimage.RGBAF64 {
+	dst := floatimage.NewRGBAF64(width, height)
+
+	step, lineStep, dstStep, dstLineStep, lines := 4, src.Stride, 4, dst.Stride, height
+	if vertical {
+		step, lineStep, dstStep, dstLineStep, lines = src.Stride, 4, dst.Stride, 4, width
+	}
+
+	floatimage.ParallelFor(lines, 0, func(start, end int) {
+		for line := start; line < end; line++ {
+			srcBase, dstBase := line*lineStep, line*dstLineStep
+
+			for p, c := range contributions {
+				var acc [4]float64
+				for k, w := range c.weights {
+					i := srcBase + (c.start+k)*step
+					s := src.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+					acc[0], acc[1], acc[2], acc[3] = acc[0]+w*s[0], acc[1]+w*s[1], acc[2]+w*s[2], acc[3]+w*s[3]
+				}
+
+				i := dstBase + p*dstStep
+				d := dst.Pix[i : i+4 : i+4]
+				d[0], d[1], d[2], d[3] = acc[0], acc[1], acc[2], acc[3]
+			}
+		}
+	})
+
+	return dst
+}
+
+func clampInt(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,198 @@
+package sampler
+
+import (
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"image"
+	"math"
+)
+
+// Filter is the texture filter used between pixel centers.
+type Filter int
+
+const (
+	// Nearest returns the pixel the sample position falls within.
+	Nearest Filter = iota
+	// Bilinear interpolates linearly between the four nearest pixel centers.
+	Bilinear
+	// Bicubic interpolates between the sixteen nearest pixel centers with a Catmull-Rom spline.
+	Bicubic
+)
+
+// Wrap decides what is sampled outside the image.
+type Wrap int
+
+const (
+	// WrapRepeat tiles the image.
+	WrapRepeat Wrap = iota
+	// WrapClamp repeats the outermost pixels of the image.
+	WrapClamp
+	// WrapMirror tiles the image mirrored every other time.
+	WrapMirror
+	// WrapBorder returns the border color outside the image.
+	WrapBorder
+)
+
+// Sampler samples an image at continuous positions, with filtering and wrapping.
+// Pixel (x, y) covers the area [x, x+1) x [y, y+1) in pixel coordinates, with its center at (x+0.5, y+0.5).
+// In normalized (u, v) coordinates the image covers [0, 1] x [0, 1].
+//
+// Samples are returned as float colors directly (no color.Color values are created),
+// premultiplied or ordinary alpha colors depending on method.
+type Sampler struct {
+	Filter Filter
+	WrapU  Wrap
+	WrapV  Wrap
+	// Border is the color outside the image for wrap mode WrapBorder.
+	Border floatcolor.RGBAF64
+
+	img           *floatimage.RGBAF64
+	width, height int
+}
+
+// New creates a new sampler for the image, with the same wrap mode in both directions.
+// The sampler works on premultiplied float64 values. An RGBAF64 image is sampled as is,
+// any other image is copied into an RGBAF64 image when the sampler is created.
+func New(img image.Image, filter Filter, wrap Wrap) *Sampler {
+	rgbaf64, ok := img.(*floatimage.RGBAF64)
+	if !ok {
+		rgbaf64 = floatimage.ToRGBAF64(img)
+	}
+
+	return &Sampler{Filter: filter, WrapU: wrap, WrapV: wrap, img: rgbaf64, width: rgbaf64.Rect.Dx(), height: rgbaf64.Rect.Dy()}
+}
+
+// Bounds returns the bounds of the sampled image.
+func (s *Sampler) Bounds() image.Rectangle {
+	return s.img.Rect
+}
+
+// At returns the premultiplied color at pixel coordinates (x, y), in the coordinate space of the image bounds.
+func (s *Sampler) At(x, y float64) floatcolor.RGBAF64 {
+	x -= float64(s.img.Rect.Min.X)
+	y -= float64(s.img.Rect.Min.Y)
+	return s.sample(x, y)
+}
+
+// AtUV returns the premultiplied color at normalized coordinates (u, v), where (0, 0) is the top left corner
+// and (1, 1) the bottom right corner of the image.
+func (s *Sampler) AtUV(u, v float64) floatcolor.RGBAF64 {
+	return s.sample(u*float64(s.width), v*float64(s.height))
+}
+
+// NRGBAF64At returns the color with ordinary (non premultiplied) alpha at pixel coordinates (x, y).
+func (s *Sampler) NRGBAF64At(x, y float64) floatcolor.NRGBAF64 {
+	return unpremultiply(s.At(x, y))
+}
+
+// NRGBAF64AtUV returns the color with ordinary (non premultiplied) alpha at normalized coordinates (u, v).
+func (s *Sampler) NRGBAF64AtUV(u, v float64) floatcolor.NRGBAF64 {
+	return unpremultiply(s.AtUV(u, v))
+}
+
+// Pixel returns the premultiplied color of pixel (x, y), relative to the top left corner of the image,
+// with the wrap modes of the sampler applied.
+func (s *Sampler) Pixel(x, y int) floatcolor.RGBAF64 {
+	c := s.texel(x, y)
+	return floatcolor.RGBAF64{R: c[0], G: c[1], B: c[2], A: c[3]}
+}
+
+// sample returns the filtered color at (x, y), relative to the top left corner of the image.
+func (s *Sampler) sample(x, y float64) floatcolor.RGBAF64 {
+	if s.width == 0 || s.height == 0 {
+		return s.Border
+	}
+
+	var c [4]float64
+
+	switch s.Filter {
+	case Nearest:
+		c = s.texel(int(math.Floor(x)), int(math.Floor(y)))
+
+	case Bilinear:
+		x, y = x-0.5, y-0.5
+		x0, y0 := math.Floor(x), math.Floor(y)
+		fx, fy := x-x0, y-y0
+		ix, iy := int(x0), int(y0)
+
+		c00, c10 := s.texel(ix, iy), s.texel(ix+1, iy)
+		c01, c11 := s.texel(ix, iy+1), s.texel(ix+1, iy+1)
+		for i := range c {
+			c[i] = (c00[i]*(1-fx)+c10[i]*fx)*(1-fy) + (c01[i]*(1-fx)+c11[i]*fx)*fy
+		}
+
+	case Bicubic:
+		x, y = x-0.5, y-0.5
+		x0, y0 := math.Floor(x), math.Floor(y)
+		wx, wy := catmullRomWeights(x-x0), catmullRomWeights(y-y0)
+		ix, iy := int(x0)-1, int(y0)-1
+
+		for j := 0; j < 4; j++ {
+			var row [4]float64
+			for i := 0; i < 4; i++ {
+				t := s.texel(ix+i, iy+j)
+				row[0], row[1], row[2], row[3] = row[0]+wx[i]*t[0], row[1]+wx[i]*t[1], row[2]+wx[i]*t[2], row[3]+wx[i]*t[3]
+			}
+			c[0], c[1], c[2], c[3] = c[0]+wy[j]*row[0], c[1]+wy[j]*row[1], c[2]+wy[j]*row[2], c[3]+wy[j]*row[3]
+		}
+	}
+
+	return floatcolor.RGBAF64{R: c[0], G: c[1], B: c[2], A: c[3]}
+}
+
+// texel returns the premultiplied values of pixel (x, y), relative to the top left corner of the image,
+// with the wrap modes of the sampler applied.
+func (s *Sampler) texel(x, y int) [4]float64 {
+	x, insideX := wrap(x, s.width, s.WrapU)
+	y, insideY := wrap(y, s.height, s.WrapV)
+	if !insideX || !insideY {
+		return [4]float64{s.Border.R, s.Border.G, s.Border.B, s.Border.A}
+	}
+
+	i := y*s.img.Stride + x*4
+	p := s.img.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+	return [4]float64{p[0], p[1], p[2], p[3]}
+}
+
+// wrap maps the index i to an index within [0, n) according to the wrap mode.
+// It returns false if the index is outside and the border color is to be used instead.
+func wrap(i, n int, mode Wrap) (int, bool) {
+	if i >= 0 && i < n {
+		return i, true
+	}
+
+	switch mode {
+	case WrapRepeat:
+		return ((i % n) + n) % n, true
+	case WrapClamp:
+		if i < 0 {
+			return 0, true
+		}
+		return n - 1, true
+	case WrapMirror:
+		period := 2 * n
+		i = ((i % period) + period) % period
+		if i >= n {
+			i = period - 1 - i
+		}
+		return i, true
+	default:
+		return 0, false
+	}
+}
+
+// catmullRomWeights returns the weights of the four pixels around a position at fraction t between the middle two.
+func catmullRomWeights(t float64) [4]float64 {
+	t2, t3 := t*t, t*t*t
+	return [4]float64{
+		(-t3 + 2*t2 - t) / 2,
+		(3*t3 - 5*t2 + 2) / 2,
+		(-3*t3 + 4*t2 + t) / 2,
+		(t3 - t2) / 2,
+	}
+}
+
+func unpremultiply(c floatcolor.RGBAF64) floatcolor.NRGBAF64 {
+	r, g, b := floatcolor.Unpremultiply(c.R, c.G, c.B, c.A)
+	return floatcolor.NRGBAF64{R: r, G: g, B: b, A: c.A}
+}
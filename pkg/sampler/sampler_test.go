@@ -0,0 +1,99 @@
+package sampler
+
+import (
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"math"
+	"testing"
+)
+
+func testImage() *floatimage.NRGBAF32 {
+	img := floatimage.NewNRGBAF32WithBounds(10, 20, 14, 23)
+	for y := 20; y < 23; y++ {
+		for x := 10; x < 14; x++ {
+			img.Set(x, y, floatcolor.NRGBAF32{R: float32(x - 10), G: float32(y - 20), B: 2.0, A: 1.0})
+		}
+	}
+	return img
+}
+
+func almostEqual(c1, c2 floatcolor.RGBAF64) bool {
+	const tolerance = 1e-12
+	return math.Abs(c1.R-c2.R) < tolerance && math.Abs(c1.G-c2.G) < tolerance && math.Abs(c1.B-c2.B) < tolerance && math.Abs(c1.A-c2.A) < tolerance
+}
+
+func TestFiltersAtPixelCenters(t *testing.T) {
+	img := testImage()
+
+	for _, filter := range []Filter{Nearest, Bilinear, Bicubic} {
+		s := New(img, filter, WrapClamp)
+		for y := 20; y < 23; y++ {
+			for x := 10; x < 14; x++ {
+				expected := floatcolor.RGBAF64{R: float64(x - 10), G: float64(y - 20), B: 2.0, A: 1.0}
+				if c := s.At(float64(x)+0.5, float64(y)+0.5); !almostEqual(c, expected) {
+					t.Errorf("filter %d: expected %+v at (%d, %d) but got %+v", filter, expected, x, y, c)
+				}
+			}
+		}
+	}
+}
+
+func TestInterpolation(t *testing.T) {
+	img := testImage()
+
+	bilinear := New(img, Bilinear, WrapClamp)
+	if c := bilinear.At(11.75, 21.0); !almostEqual(c, floatcolor.RGBAF64{R: 1.25, G: 0.5, B: 2.0, A: 1.0}) {
+		t.Errorf("unexpected bilinear sample %+v", c)
+	}
+
+	// Catmull-Rom reproduces linear ramps exactly away from the edges.
+	bicubic := New(img, Bicubic, WrapClamp)
+	if c := bicubic.At(11.8, 21.5); !almostEqual(c, floatcolor.RGBAF64{R: 1.3, G: 1.0, B: 2.0, A: 1.0}) {
+		t.Errorf("unexpected bicubic sample %+v", c)
+	}
+
+	nearest := New(img, Nearest, WrapClamp)
+	if c := nearest.AtUV(0.49, 0.99); !almostEqual(c, floatcolor.RGBAF64{R: 1.0, G: 2.0, B: 2.0, A: 1.0}) {
+		t.Errorf("unexpected nearest sample %+v", c)
+	}
+}
+
+func TestWrapModes(t *testing.T) {
+	img := testImage()
+
+	s := New(img, Nearest, WrapRepeat)
+	if c1, c2 := s.AtUV(1.3, -0.2), s.AtUV(0.3, 0.8); !almostEqual(c1, c2) {
+		t.Errorf("expected repeated samples to be equal but got %+v and %+v", c1, c2)
+	}
+
+	s.WrapU, s.WrapV = WrapMirror, WrapMirror
+	if c1, c2 := s.Pixel(-1, 4), s.Pixel(0, 1); !almostEqual(c1, c2) {
+		t.Errorf("expected mirrored samples to be equal but got %+v and %+v", c1, c2)
+	}
+
+	s.WrapU, s.WrapV = WrapClamp, WrapClamp
+	if c1, c2 := s.Pixel(7, -3), s.Pixel(3, 0); !almostEqual(c1, c2) {
+		t.Errorf("expected clamped samples to be equal but got %+v and %+v", c1, c2)
+	}
+
+	s.WrapU, s.WrapV = WrapBorder, WrapClamp
+	s.Border = floatcolor.RGBAF64{R: 0.5, A: 0.5}
+	if c := s.AtUV(-0.1, 0.5); !almostEqual(c, s.Border) {
+		t.Errorf("expected border color but got %+v", c)
+	}
+	if c := s.NRGBAF64AtUV(-0.1, 0.5); c != (floatcolor.NRGBAF64{R: 1.0, A: 0.5}) {
+		t.Errorf("expected non premultiplied border color but got %+v", c)
+	}
+}
+
+func TestSamplingDoesNotAllocate(t *testing.T) {
+	s := New(testImage(), Bicubic, WrapRepeat)
+
+	allocations := testing.AllocsPerRun(100, func() {
+		s.AtUV(0.3, 0.7)
+		s.NRGBAF64At(11.2, 21.9)
+	})
+	if allocations != 0 {
+		t.Errorf("expected no allocations when sampling but got %v", allocations)
+	}
+}
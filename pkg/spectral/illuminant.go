@@ -0,0 +1,154 @@
+package spectral
+
+import "math"
+
+var (
+	// IlluminantD65 is the CIE standard illuminant D65 (average daylight, about 6504 K),
+	// 380 nm to 780 nm in 5 nm steps, normalized to 100 at 560 nm.
+	IlluminantD65 = NewRegularSpectrum(380, 5, d65Table)
+
+	// IlluminantD50 is the CIE illuminant D50 (horizon daylight, about 5003 K),
+	// 380 nm to 780 nm in 5 nm steps, normalized to 100 at 560 nm.
+	IlluminantD50 = Daylight(5000 * 1.4388 / 1.4380)
+
+	// IlluminantA is the CIE standard illuminant A (tungsten filament, 2856 K),
+	// 380 nm to 780 nm in 5 nm steps, normalized to 100 at 560 nm.
+	IlluminantA = illuminantA()
+
+	// IlluminantE is the equal energy illuminant, 380 nm to 780 nm in 5 nm steps, with the constant value 100.
+	IlluminantE = illuminantE()
+)
+
+// Daylight returns the CIE daylight illuminant (D-series) for the given correlated color temperature in Kelvin.
+// The temperature is valid in the range [4000, 25000].
+// The spectrum covers 380 nm to 780 nm in 5 nm steps and is normalized to 100 at 560 nm.
+func Daylight(temperature float64) Spectrum {
+	t := temperature
+	var xD float64
+	if t <= 7000 {
+		xD = 0.244063 + 0.09911e3/t + 2.9678e6/(t*t) - 4.6070e9/(t*t*t)
+	} else {
+		xD = 0.237040 + 0.24748e3/t + 1.9018e6/(t*t) - 2.0064e9/(t*t*t)
+	}
+	yD := -3.000*xD*xD + 2.870*xD - 0.275
+
+	// The CIE recommends rounding the factors to three decimals.
+	m := 0.0241 + 0.2562*xD - 0.7341*yD
+	m1 := math.Round(1000*(-1.3515-1.7703*xD+5.9114*yD)/m) / 1000
+	m2 := math.Round(1000*(0.0300-31.4424*xD+30.0717*yD)/m) / 1000
+
+	values := make([]float64, 81)
+	for i := range values {
+		// The basis functions are tabulated in 10 nm steps and interpolated linearly in between.
+		j := i / 2
+		s := daylightBasis[j]
+		if i%2 == 1 {
+			s1 := daylightBasis[j+1]
+			s = [3]float64{(s[0] + s1[0]) / 2, (s[1] + s1[1]) / 2, (s[2] + s1[2]) / 2}
+		}
+		values[i] = s[0] + m1*s[1] + m2*s[2]
+	}
+
+	return NewRegularSpectrum(380, 5, values)
+}
+
+// Blackbody returns the spectral radiance of a black body (Planck's law) of the given temperature in Kelvin,
+// 380 nm to 780 nm in 5 nm steps, normalized to 100 at 560 nm.
+func Blackbody(temperature float64) Spectrum {
+	const c2 = 1.4388e7 // Second radiation constant in nm·K
+
+	planck := func(wavelength float64) float64 {
+		return 1.0 / (math.Pow(wavelength, 5) * (math.Exp(c2/(wavelength*temperature)) - 1.0))
+	}
+
+	norm := 100.0 / planck(560)
+	values := make([]float64, 81)
+	for i := range values {
+		values[i] = planck(380+5*float64(i)) * norm
+	}
+
+	return NewRegularSpectrum(380, 5, values)
+}
+
+func illuminantA() Spectrum {
+	// Illuminant A is defined by the CIE using the (older) radiation constant 1.435e7 nm·K at 2848 K.
+	const c2 = 1.435e7
+	const t = 2848.0
+
+	values := make([]float64, 81)
+	for i := range values {
+		wavelength := 380 + 5*float64(i)
+		values[i] = 100 * math.Pow(560/wavelength, 5) * (math.Exp(c2/(t*560)) - 1) / (math.Exp(c2/(t*wavelength)) - 1)
+	}
+
+	return NewRegularSpectrum(380, 5, values)
+}
+
+func illuminantE() Spectrum {
+	values := make([]float64, 81)
+	for i := range values {
+		values[i] = 100.0
+	}
+
+	return NewRegularSpectrum(380, 5, values)
+}
+
+// d65Table holds the relative spectral power distribution of CIE illuminant D65 from 380 nm to 780 nm in 5 nm steps.
+var d65Table = []float64{
+	49.9755, 52.3118, 54.6482, 68.7015, 82.7549, 87.1204, 91.4860, 92.4589, // 380 - 415
+	93.4318, 90.0570, 86.6823, 95.7736, 104.865, 110.936, 117.008, 117.410, // 420 - 455
+	117.812, 116.336, 114.861, 115.392, 115.923, 112.367, 108.811, 109.082, // 460 - 495
+	109.354, 108.578, 107.802, 106.296, 104.790, 106.239, 107.689, 106.047, // 500 - 535
+	104.405, 104.225, 104.046, 102.023, 100.000, 98.1671, 96.3342, 96.0611, // 540 - 575
+	95.7880, 92.2368, 88.6856, 89.3459, 90.0062, 89.8026, 89.5991, 88.6489, // 580 - 615
+	87.6987, 85.4936, 83.2886, 83.4939, 83.6992, 81.8630, 80.0268, 80.1207, // 620 - 655
+	80.2146, 81.2462, 82.2778, 80.2810, 78.2842, 74.0027, 69.7213, 70.6652, // 660 - 695
+	71.6091, 72.9790, 74.3490, 67.9765, 61.6040, 65.7448, 69.8856, 72.4863, // 700 - 735
+	75.0870, 69.3398, 63.5927, 55.0054, 46.4182, 56.6118, 66.8054, 65.0941, // 740 - 775
+	63.3828, // 780
+}
+
+// daylightBasis holds the CIE daylight basis functions S0, S1, and S2 from 380 nm to 780 nm in 10 nm steps.
+var daylightBasis = [][3]float64{
+	{63.4, 38.5, 3.0}, // 380
+	{65.8, 35.0, 1.2},
+	{94.8, 43.4, -1.1},
+	{104.8, 46.3, -0.5},
+	{105.9, 43.9, -0.7},
+	{96.8, 37.1, -1.2},
+	{113.9, 36.7, -2.6},
+	{125.6, 35.9, -2.9},
+	{125.5, 32.6, -2.8},
+	{121.3, 27.9, -2.6},
+	{121.3, 24.3, -2.6}, // 480
+	{113.5, 20.1, -1.8},
+	{113.1, 16.2, -1.5},
+	{110.8, 13.2, -1.3},
+	{106.5, 8.6, -1.2},
+	{108.8, 6.1, -1.0},
+	{105.3, 4.2, -0.5},
+	{104.4, 1.9, -0.3},
+	{100.0, 0.0, 0.0},
+	{96.0, -1.6, 0.2},
+	{95.1, -3.5, 0.5}, // 580
+	{89.1, -3.5, 2.1},
+	{90.5, -5.8, 3.2},
+	{90.3, -7.2, 4.1},
+	{88.4, -8.6, 4.7},
+	{84.0, -9.5, 5.1},
+	{85.1, -10.9, 6.7},
+	{81.9, -10.7, 7.3},
+	{82.6, -12.0, 8.6},
+	{84.9, -14.0, 9.8},
+	{81.3, -13.6, 10.2}, // 680
+	{71.9, -12.0, 8.3},
+	{74.3, -13.3, 9.6},
+	{76.4, -12.9, 8.5},
+	{63.3, -10.6, 7.0},
+	{71.7, -11.6, 7.6},
+	{77.0, -12.2, 8.0},
+	{65.2, -10.2, 6.7},
+	{47.7, -7.8, 5.2},
+	{68.6, -11.2, 7.4},
+	{65.0, -10.4, 6.8}, // 780
+}
@@ -0,0 +1,198 @@
+package spectral
+
+import (
+	"floatimage/pkg/floatcolor"
+	"image"
+	"image/draw"
+	"sort"
+)
+
+// Spectrum is a sampled spectral distribution (radiance, reflectance, power etc.).
+// Wavelengths are given in nm, in increasing order, and do not need to be evenly spaced.
+type Spectrum struct {
+	Wavelengths []float64
+	Values      []float64
+}
+
+// XYZ is a color in the CIE XYZ color space.
+type XYZ struct {
+	X, Y, Z float64
+}
+
+// NewSpectrum creates a new spectrum from wavelength (in nm) and value samples.
+// The samples are sorted by wavelength.
+func NewSpectrum(wavelengths []float64, values []float64) Spectrum {
+	if len(wavelengths) != len(values) {
+		panic("spectral: NewSpectrum wavelengths and values have different lengths")
+	}
+
+	s := Spectrum{Wavelengths: append([]float64(nil), wavelengths...), Values: append([]float64(nil), values...)}
+	sort.Sort(byWavelength(s))
+	return s
+}
+
+// NewRegularSpectrum creates a new spectrum from values sampled at regular wavelength intervals,
+// starting at wavelength start (in nm) with the interval step (in nm).
+func NewRegularSpectrum(start, step float64, values []float64) Spectrum {
+	wavelengths := make([]float64, len(values))
+	for i := range wavelengths {
+		wavelengths[i] = start + float64(i)*step
+	}
+
+	return Spectrum{Wavelengths: wavelengths, Values: append([]float64(nil), values...)}
+}
+
+// At returns the spectrum value at the given wavelength (in nm).
+// Values between samples are linearly interpolated and wavelengths outside the sampled range give zero.
+func (s Spectrum) At(wavelength float64) float64 {
+	n := len(s.Wavelengths)
+	if n == 0 || wavelength < s.Wavelengths[0] || wavelength > s.Wavelengths[n-1] {
+		return 0.0
+	}
+
+	i := sort.SearchFloat64s(s.Wavelengths, wavelength)
+	if s.Wavelengths[i] == wavelength {
+		return s.Values[i]
+	}
+
+	w0, w1 := s.Wavelengths[i-1], s.Wavelengths[i]
+	t := (wavelength - w0) / (w1 - w0)
+	return s.Values[i-1]*(1.0-t) + s.Values[i]*t
+}
+
+// Scale returns a copy of the spectrum with all values multiplied by factor.
+func (s Spectrum) Scale(factor float64) Spectrum {
+	values := make([]float64, len(s.Values))
+	for i, v := range s.Values {
+		values[i] = v * factor
+	}
+
+	return Spectrum{Wavelengths: append([]float64(nil), s.Wavelengths...), Values: values}
+}
+
+// Multiply returns the product of two spectra, sampled at the wavelengths of s.
+func (s Spectrum) Multiply(s2 Spectrum) Spectrum {
+	values := make([]float64, len(s.Values))
+	for i, v := range s.Values {
+		values[i] = v * s2.At(s.Wavelengths[i])
+	}
+
+	return Spectrum{Wavelengths: append([]float64(nil), s.Wavelengths...), Values: values}
+}
+
+// ToXYZ integrates an emission spectrum (e.g. radiance) against the observer color matching functions.
+// The result is normalized so that a constant spectrum of value 1.0 gives Y = 1.0.
+func (o *Observer) ToXYZ(s Spectrum) XYZ {
+	var xyz XYZ
+	for i := range o.Y {
+		v := s.At(o.Wavelength(i))
+		xyz.X += v * o.X[i]
+		xyz.Y += v * o.Y[i]
+		xyz.Z += v * o.Z[i]
+	}
+
+	conv := o.Step / o.yIntegral
+	return XYZ{X: xyz.X * conv, Y: xyz.Y * conv, Z: xyz.Z * conv}
+}
+
+// ReflectanceToXYZ integrates a reflectance spectrum lit by an illuminant against the observer color matching functions.
+// The result is normalized so that a perfect white reflector (constant reflectance 1.0) gives Y = 1.0.
+func (o *Observer) ReflectanceToXYZ(reflectance Spectrum, illuminant Spectrum) XYZ {
+	var xyz XYZ
+	var norm float64
+	for i := range o.Y {
+		wavelength := o.Wavelength(i)
+		e := illuminant.At(wavelength)
+		v := reflectance.At(wavelength) * e
+		xyz.X += v * o.X[i]
+		xyz.Y += v * o.Y[i]
+		xyz.Z += v * o.Z[i]
+		norm += e * o.Y[i]
+	}
+
+	if norm == 0 {
+		return XYZ{}
+	}
+
+	conv := 1.0 / norm
+	return XYZ{X: xyz.X * conv, Y: xyz.Y * conv, Z: xyz.Z * conv}
+}
+
+// WhitePoint returns the XYZ color of an illuminant, normalized to Y = 1.0.
+func (o *Observer) WhitePoint(illuminant Spectrum) XYZ {
+	xyz := o.ToXYZ(illuminant)
+	if xyz.Y == 0 {
+		return XYZ{}
+	}
+
+	conv := 1.0 / xyz.Y
+	return XYZ{X: xyz.X * conv, Y: 1.0, Z: xyz.Z * conv}
+}
+
+// WavelengthToXYZ returns the XYZ contribution of a single wavelength sample (in nm) with the given radiance.
+// It is normalized the same way as ToXYZ, i.e. integrating the contribution over all wavelengths
+// gives the same result as ToXYZ for the corresponding spectrum.
+//
+// For wavelengths sampled uniformly in the observer range, the estimate of a spectrum color is
+// the mean of all sample contributions multiplied with the length of the range (MaxWavelength - MinWavelength).
+func (o *Observer) WavelengthToXYZ(wavelength float64, radiance float64) XYZ {
+	cmf := o.At(wavelength)
+	conv := radiance / o.yIntegral
+	return XYZ{X: cmf.X * conv, Y: cmf.Y * conv, Z: cmf.Z * conv}
+}
+
+// Chromaticity returns the xy chromaticity coordinates of the color.
+func (xyz XYZ) Chromaticity() (x, y float64) {
+	sum := xyz.X + xyz.Y + xyz.Z
+	if sum == 0 {
+		return 0.0, 0.0
+	}
+
+	return xyz.X / sum, xyz.Y / sum
+}
+
+// LinearRGB returns the linear (not gamma encoded) sRGB red, green, and blue values of the color.
+// The sRGB primaries use the D65 white point, so XYZ values for other white points should be adapted first.
+func (xyz XYZ) LinearRGB() (r, g, b float64) {
+	r = 3.2404542*xyz.X - 1.5371385*xyz.Y - 0.4985314*xyz.Z
+	g = -0.9692660*xyz.X + 1.8760108*xyz.Y + 0.0415560*xyz.Z
+	b = 0.0556434*xyz.X - 0.2040259*xyz.Y + 1.0572252*xyz.Z
+	return r, g, b
+}
+
+// AsNRGBAF64 returns the color as an opaque floatcolor.NRGBAF64 with linear sRGB red, green, and blue values.
+// Values are not clamped, colors outside the sRGB gamut gives negative channel values.
+func (xyz XYZ) AsNRGBAF64() floatcolor.NRGBAF64 {
+	r, g, b := xyz.LinearRGB()
+	return floatcolor.NewNRGBAF64(r, g, b, 1.0)
+}
+
+// SpectrumToNRGBAF64 integrates an emission spectrum using the CIE 1931 observer
+// and returns it as an opaque floatcolor.NRGBAF64 with linear sRGB red, green, and blue values.
+func SpectrumToNRGBAF64(s Spectrum) floatcolor.NRGBAF64 {
+	return CIE1931.ToXYZ(s).AsNRGBAF64()
+}
+
+// Splat adds the contribution of a single wavelength sample (in nm) with the given radiance
+// to the pixel at (x, y) of a float image. The contribution is converted to linear sRGB
+// using the observer color matching functions (see WavelengthToXYZ) and is added to the
+// red, green, and blue values of the pixel. The alpha of the pixel is set to 1.0.
+// Pixels outside the image bounds are ignored.
+func (o *Observer) Splat(img draw.Image, x, y int, wavelength float64, radiance float64) {
+	if !(image.Point{X: x, Y: y}.In(img.Bounds())) {
+		return
+	}
+
+	r, g, b := o.WavelengthToXYZ(wavelength, radiance).LinearRGB()
+	c := floatcolor.RGBAF64Model.Convert(img.At(x, y)).(floatcolor.RGBAF64)
+	img.Set(x, y, floatcolor.RGBAF64{R: c.R + r, G: c.G + g, B: c.B + b, A: 1.0, Precise: c.Precise, Quantization: c.Quantization})
+}
+
+type byWavelength Spectrum
+
+func (s byWavelength) Len() int           { return len(s.Wavelengths) }
+func (s byWavelength) Less(i, j int) bool { return s.Wavelengths[i] < s.Wavelengths[j] }
+func (s byWavelength) Swap(i, j int) {
+	s.Wavelengths[i], s.Wavelengths[j] = s.Wavelengths[j], s.Wavelengths[i]
+	s.Values[i], s.Values[j] = s.Values[j], s.Values[i]
+}
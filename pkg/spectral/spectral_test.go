@@ -0,0 +1,85 @@
+package spectral
+
+import (
+	"floatimage/pkg/floatimage"
+	"math"
+	"testing"
+)
+
+func TestWhitePoints(t *testing.T) {
+	tests := []struct {
+		name       string
+		observer   *Observer
+		illuminant Spectrum
+		x, y       float64
+	}{
+		{name: "D65 2°", observer: CIE1931, illuminant: IlluminantD65, x: 0.31271, y: 0.32902},
+		{name: "D50 2°", observer: CIE1931, illuminant: IlluminantD50, x: 0.34567, y: 0.35850},
+		{name: "A 2°", observer: CIE1931, illuminant: IlluminantA, x: 0.44757, y: 0.40745},
+		{name: "E 2°", observer: CIE1931, illuminant: IlluminantE, x: 1.0 / 3.0, y: 1.0 / 3.0},
+		{name: "D65 10°", observer: CIE1964, illuminant: IlluminantD65, x: 0.31382, y: 0.33100},
+		{name: "E 10°", observer: CIE1964, illuminant: IlluminantE, x: 1.0 / 3.0, y: 1.0 / 3.0},
+	}
+
+	for _, test := range tests {
+		x, y := test.observer.WhitePoint(test.illuminant).Chromaticity()
+		if math.Abs(x-test.x) > 0.0005 || math.Abs(y-test.y) > 0.0005 {
+			t.Errorf("%s: expected white point (%.5f, %.5f) but got (%.5f, %.5f)", test.name, test.x, test.y, x, y)
+		}
+	}
+}
+
+func TestD65IsWhiteInSRGB(t *testing.T) {
+	c := CIE1931.WhitePoint(IlluminantD65).AsNRGBAF64()
+	for _, v := range []float64{c.R, c.G, c.B} {
+		if math.Abs(v-1.0) > 0.005 {
+			t.Errorf("expected D65 to be white in sRGB but got %+v", c)
+		}
+	}
+}
+
+func TestReflectanceToXYZ(t *testing.T) {
+	white := NewSpectrum([]float64{300, 900}, []float64{1.0, 1.0})
+	xyz := CIE1931.ReflectanceToXYZ(white, IlluminantD65)
+	if math.Abs(xyz.Y-1.0) > 1e-9 {
+		t.Errorf("expected perfect reflector to give Y = 1.0 but got %v", xyz.Y)
+	}
+
+	grey := white.Scale(0.18)
+	xyz = CIE1931.ReflectanceToXYZ(grey, IlluminantD65)
+	if math.Abs(xyz.Y-0.18) > 1e-9 {
+		t.Errorf("expected 18%% grey reflector to give Y = 0.18 but got %v", xyz.Y)
+	}
+}
+
+func TestSplatMatchesIntegration(t *testing.T) {
+	img := floatimage.NewNRGBAF64(1, 1)
+
+	// Splat every tabulated wavelength, weighted as a rectangle rule integration of a constant spectrum.
+	for i := 0; i < CIE1931.Len(); i++ {
+		CIE1931.Splat(img, 0, 0, CIE1931.Wavelength(i), CIE1931.Step)
+	}
+
+	expected := CIE1931.ToXYZ(IlluminantE.Scale(0.01)).AsNRGBAF64()
+	s := img.Pix[0:4]
+	if math.Abs(s[0]-expected.R) > 1e-9 || math.Abs(s[1]-expected.G) > 1e-9 || math.Abs(s[2]-expected.B) > 1e-9 || s[3] != 1.0 {
+		t.Errorf("expected splatted pixel %+v but got %v", expected, s)
+	}
+
+	// Samples outside the image are ignored
+	CIE1931.Splat(img, 1, 0, 555, 1.0)
+}
+
+func TestSpectrumAt(t *testing.T) {
+	s := NewSpectrum([]float64{500, 400, 600}, []float64{2.0, 1.0, 4.0})
+
+	tests := []struct{ wavelength, expected float64 }{
+		{399, 0.0}, {400, 1.0}, {450, 1.5}, {500, 2.0}, {550, 3.0}, {600, 4.0}, {601, 0.0},
+	}
+
+	for _, test := range tests {
+		if v := s.At(test.wavelength); math.Abs(v-test.expected) > 1e-12 {
+			t.Errorf("expected %v at %v nm but got %v", test.expected, test.wavelength, v)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package transform
+
+import (
+	"floatimage/pkg/floatimage"
+	"image"
+	"image/draw"
+)
+
+// Rotate90 returns the image rotated 90 degrees clockwise, as a new float image of the same type as img
+// (see floatimage.NewLike) with bounds starting at (0, 0). Pixel values are copied exactly.
+func Rotate90(img image.Image) floatimage.FloatImage {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	return remap(img, image.Rect(0, 0, h, w), func(x, y int) (int, int) { return y, h - 1 - x })
+}
+
+// Rotate180 returns the image rotated 180 degrees, as a new float image of the same type as img.
+func Rotate180(img image.Image) floatimage.FloatImage {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	return remap(img, image.Rect(0, 0, w, h), func(x, y int) (int, int) { return w - 1 - x, h - 1 - y })
+}
+
+// Rotate270 returns the image rotated 270 degrees clockwise (90 degrees counterclockwise), as a new float image of the same type as img.
+func Rotate270(img image.Image) floatimage.FloatImage {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	return remap(img, image.Rect(0, 0, h, w), func(x, y int) (int, int) { return w - 1 - y, x })
+}
+
+// FlipHorizontal returns the image mirrored left to right, as a new float image of the same type as img.
+func FlipHorizontal(img image.Image) floatimage.FloatImage {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	return remap(img, image.Rect(0, 0, w, h), func(x, y int) (int, int) { return w - 1 - x, y })
+}
+
+// FlipVertical returns the image mirrored top to bottom, as a new float image of the same type as img.
+func FlipVertical(img image.Image) floatimage.FloatImage {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	return remap(img, image.Rect(0, 0, w, h), func(x, y int) (int, int) { return x, h - 1 - y })
+}
+
+// Transpose returns the image mirrored along its main diagonal (x and y swapped), as a new float image of the same type as img.
+func Transpose(img image.Image) floatimage.FloatImage {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	return remap(img, image.Rect(0, 0, h, w), func(x, y int) (int, int) { return y, x })
+}
+
+// Crop returns a copy of the part of the image within r, as a new float image of the same type as img.
+// Unlike SubImage, the result does not share pixels with the image. The bounds of the result are r intersected with the image bounds.
+func Crop(img image.Image, r image.Rectangle) floatimage.FloatImage {
+	bounds := img.Bounds()
+	r = r.Intersect(bounds)
+	dx, dy := r.Min.X-bounds.Min.X, r.Min.Y-bounds.Min.Y
+	return remap(img, r, func(x, y int) (int, int) { return x + dx, y + dy })
+}
+
+// remap returns a new image of the same type as img with bounds r, where each pixel (x, y) is a copy of pixel source(x, y) of img.
+// Coordinates are relative to the top left corner of the images.
+func remap(img image.Image, r image.Rectangle, source func(x, y int) (sx, sy int)) floatimage.FloatImage {
+	result := floatimage.NewLike(img, r)
+	width, height := r.Dx(), r.Dy()
+
+	switch src := img.(type) {
+	case *floatimage.NRGBAF64:
+		dst := result.(*floatimage.NRGBAF64)
+		copyPixels(dst.Pix, dst.Stride, src.Pix, src.Stride, 4, width, height, source)
+	case *floatimage.NRGBAF32:
+		dst := result.(*floatimage.NRGBAF32)
+		copyPixels(dst.Pix, dst.Stride, src.Pix, src.Stride, 4, width, height, source)
+	case *floatimage.RGBAF64:
+		dst := result.(*floatimage.RGBAF64)
+		copyPixels(dst.Pix, dst.Stride, src.Pix, src.Stride, 4, width, height, source)
+		if src.Hidden != nil {
+			dst.Hidden = make([]float64, len(dst.Pix)/4*3)
+			copyPixels(dst.Hidden, dst.Stride/4*3, src.Hidden, src.Stride/4*3, 3, width, height, source)
+		}
+	case *floatimage.RGBAF32:
+		dst := result.(*floatimage.RGBAF32)
+		copyPixels(dst.Pix, dst.Stride, src.Pix, src.Stride, 4, width, height, source)
+		if src.Hidden != nil {
+			dst.Hidden = make([]float32, len(dst.Pix)/4*3)
+			copyPixels(dst.Hidden, dst.Stride/4*3, src.Hidden, src.Stride/4*3, 3, width, height, source)
+		}
+	case *floatimage.GrayF64:
+		dst := result.(*floatimage.GrayF64)
+		copyPixels(dst.Pix, dst.Stride, src.Pix, src.Stride, 1, width, height, source)
+	default:
+		dst := result.(draw.Image)
+		min := img.Bounds().Min
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				sx, sy := source(x, y)
+				dst.Set(r.Min.X+x, r.Min.Y+y, img.At(min.X+sx, min.Y+sy))
+			}
+		}
+	}
+
+	return result
+}
+
+// copyPixels copies the pixel source(x, y) of src to pixel (x, y) of dst, for every pixel of the width x height dst.
+func copyPixels[T float32 | float64](dst []T, dstStride int, src []T, srcStride int, channels int, width, height int, source func(x, y int) (sx, sy int)) {
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sx, sy := source(x, y)
+			i, j := y*dstStride+x*channels, sy*srcStride+sx*channels
+			copy(dst[i:i+channels], src[j:j+channels])
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package transform
+
+import (
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"floatimage/pkg/sampler"
+	"image"
+	"math"
+	"testing"
+)
+
+func testImage() *floatimage.NRGBAF32 {
+	img := floatimage.NewNRGBAF32WithBounds(-2, 3, 3, 6)
+	for i := range img.Pix {
+		img.Pix[i] = float32(i) / 7
+	}
+	img.Pix[3] = 0.0 // Transparent pixel with hidden color, kept by exact transforms
+	return img
+}
+
+func equal(img1, img2 *floatimage.NRGBAF32) bool {
+	if img1.Rect.Size() != img2.Rect.Size() {
+		return false
+	}
+	for y := 0; y < img1.Rect.Dy(); y++ {
+		for x := 0; x < img1.Rect.Dx(); x++ {
+			i, j := y*img1.Stride+x*4, y*img2.Stride+x*4
+			for c := 0; c < 4; c++ {
+				if img1.Pix[i+c] != img2.Pix[j+c] {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+func TestExactTransforms(t *testing.T) {
+	img := testImage()
+
+	rotated := Rotate90(img).(*floatimage.NRGBAF32)
+	if rotated.Rect != image.Rect(0, 0, 3, 5) {
+		t.Fatalf("expected rotated bounds %v but got %v", image.Rect(0, 0, 3, 5), rotated.Rect)
+	}
+	if rotated.At(2, 0) != img.At(-2, 3) || rotated.At(0, 4) != img.At(2, 5) {
+		t.Errorf("expected top left corner to be rotated to the top right corner")
+	}
+
+	tests := []struct {
+		name   string
+		result floatimage.FloatImage
+	}{
+		{"rotate 90 and 270", Rotate270(Rotate90(img))},
+		{"rotate 180 twice", Rotate180(Rotate180(img))},
+		{"rotate 90 twice and 180", Rotate180(Rotate90(Rotate90(img)))},
+		{"flip horizontal twice", FlipHorizontal(FlipHorizontal(img))},
+		{"flip vertical twice", FlipVertical(FlipVertical(img))},
+		{"flips and 180", Rotate180(FlipVertical(FlipHorizontal(img)))},
+		{"transpose twice", Transpose(Transpose(img))},
+		{"transpose and flip", Rotate270(FlipHorizontal(Transpose(img)))},
+	}
+
+	for _, test := range tests {
+		if !equal(test.result.(*floatimage.NRGBAF32), img) {
+			t.Errorf("%s: expected the original image", test.name)
+		}
+	}
+}
+
+func TestTransformTiledAndViews(t *testing.T) {
+	img := testImage()
+	expected := Rotate90(img).(*floatimage.NRGBAF32)
+
+	tiled, ok := Rotate90(floatimage.NewTiledFrom(img, 2, 2)).(*floatimage.Tiled)
+	if !ok || tiled.TileWidth != 2 || tiled.TileHeight != 2 {
+		t.Fatalf("expected a tiled result with the tile size of the source but got %T", tiled)
+	}
+	if !equal(tiled.Flatten().(*floatimage.NRGBAF32), expected) {
+		t.Errorf("expected tiled result to be the same as the row-major result")
+	}
+
+	aov := floatimage.NewMultiChannelF32WithBounds(-2, 3, 3, 6, "R", "G", "B", "A")
+	copy(aov.Pix, img.Pix)
+	view, _ := aov.ViewNRGBAF32("R", "G", "B", "A")
+	if result, ok := Rotate90(view).(*floatimage.NRGBAF32); !ok || !equal(result, expected) {
+		t.Errorf("expected an NRGBAF32 result for a channel view but got %T", result)
+	}
+}
+
+func TestCrop(t *testing.T) {
+	img := testImage()
+
+	cropped := Crop(img, image.Rect(0, 4, 10, 10)).(*floatimage.NRGBAF32)
+	if cropped.Rect != image.Rect(0, 4, 3, 6) {
+		t.Fatalf("expected cropped bounds %v but got %v", image.Rect(0, 4, 3, 6), cropped.Rect)
+	}
+	if !equal(cropped, img.SubImage(cropped.Rect).(*floatimage.NRGBAF32)) {
+		t.Errorf("expected cropped pixels to be the same as the sub image")
+	}
+
+	cropped.Pix[0] = 100
+	if img.Pix[img.PixOffset(0, 4)] == 100 {
+		t.Errorf("expected cropped image to not share pixels with the image")
+	}
+}
+
+func TestTransformHiddenColor(t *testing.T) {
+	previous, previousEpsilon := floatcolor.CurrentZeroAlphaPolicy()
+	defer floatcolor.SetZeroAlphaPolicy(previous, previousEpsilon)
+	floatcolor.SetZeroAlphaPolicy(floatcolor.PreserveHiddenColor, 0)
+
+	hidden := floatcolor.NRGBAF64{R: 0.25, G: 0.5, B: 1.0}
+	img := floatimage.NewRGBAF32(3, 2)
+	img.SetNRGBAF64(2, 1, hidden)
+
+	if c := Transpose(img).(*floatimage.RGBAF32).NRGBAF64At(1, 2); c != hidden {
+		t.Errorf("expected transposed hidden color but got %+v", c)
+	}
+	if c := Crop(img, image.Rect(1, 1, 3, 2)).(*floatimage.RGBAF32).NRGBAF64At(2, 1); c != hidden {
+		t.Errorf("expected cropped hidden color but got %+v", c)
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	m := Translation(3, -2).Multiply(RotationAround(0.3, 1, 2)).Multiply(Scaling(2, 0.5))
+	inverse, ok := m.Inverse()
+	if !ok {
+		t.Fatalf("expected invertible matrix")
+	}
+	if x, y := inverse.Transform(m.Transform(1.5, -4)); math.Abs(x-1.5) > 1e-12 || math.Abs(y+4) > 1e-12 {
+		t.Errorf("expected inverse to transform back to (1.5, -4) but got (%v, %v)", x, y)
+	}
+
+	if x, y := Rotation(math.Pi/2).Transform(1, 0); math.Abs(x) > 1e-12 || math.Abs(y-1) > 1e-12 {
+		t.Errorf("expected clockwise rotation of (1, 0) to (0, 1) but got (%v, %v)", x, y)
+	}
+
+	src := [4][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	dst := [4][2]float64{{2, 1}, {9, 3}, {12, 11}, {-1, 8}}
+	q, ok := QuadToQuad(src, dst)
+	if !ok {
+		t.Fatalf("expected quad to quad transformation")
+	}
+	for i := range src {
+		if x, y := q.Transform(src[i][0], src[i][1]); math.Abs(x-dst[i][0]) > 1e-9 || math.Abs(y-dst[i][1]) > 1e-9 {
+			t.Errorf("expected corner %v to map to %v but got (%v, %v)", src[i], dst[i], x, y)
+		}
+	}
+
+	if _, ok := QuadToQuad([4][2]float64{{0, 0}, {1, 1}, {2, 2}, {3, 3}}, dst); ok {
+		t.Errorf("expected degenerate quad to fail")
+	}
+}
+
+func TestWarp(t *testing.T) {
+	img := floatimage.NewRGBAF64(4, 4)
+	for i := range img.Pix {
+		img.Pix[i] = 1.0
+	}
+	background := floatcolor.NRGBAF64{R: 0.5, A: 0.5}
+
+	translated := Warp(img, Translation(2, 1), image.Rect(0, 0, 8, 8), sampler.Nearest, background).(*floatimage.RGBAF64)
+	if c := translated.At(2, 1).(floatcolor.RGBAF64); c.A != 1.0 {
+		t.Errorf("expected translated image at (2, 1) but got %+v", c)
+	}
+	if c := translated.At(1, 1).(floatcolor.RGBAF64); c != (floatcolor.RGBAF64{R: 0.25, A: 0.5}) {
+		t.Errorf("expected background color at (1, 1) but got %+v", c)
+	}
+
+	identity := Warp(img, Identity(), img.Rect, sampler.Bilinear, background).(*floatimage.RGBAF64)
+	if c := identity.At(1, 1).(floatcolor.RGBAF64); c.R != 1.0 || c.A != 1.0 {
+		t.Errorf("expected identity warp to keep the image but got %+v", c)
+	}
+
+	// Bilinear filtering blends the edge with the background.
+	if c := identity.At(0, 0).(floatcolor.RGBAF64); c.A != 1.0 {
+		t.Errorf("expected pixel center sample to be exact but got %+v", c)
+	}
+	upscaled := Warp(img, Scaling(2, 2), image.Rect(0, 0, 8, 8), sampler.Bilinear, background).(*floatimage.RGBAF64)
+	if c := upscaled.At(0, 0).(floatcolor.RGBAF64); c.A <= 0.5 || c.A >= 1.0 {
+		t.Errorf("expected edge blended with the background but got %+v", c)
+	}
+	singular := Warp(img, Scaling(0, 2), image.Rect(0, 0, 3, 3), sampler.Bilinear, background).(*floatimage.RGBAF64)
+	for i := 0; i < len(singular.Pix); i += 4 {
+		if c := singular.Pix[i : i+4]; c[0] != 0.25 || c[1] != 0 || c[3] != 0.5 {
+			t.Fatalf("expected background color for a singular warp but got %v", c)
+		}
+	}
+}
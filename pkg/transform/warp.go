@@ -0,0 +1,170 @@
+package transform
+
+import (
+	"floatimage/pkg/floatcolor"
+	"floatimage/pkg/floatimage"
+	"floatimage/pkg/sampler"
+	"image"
+	"math"
+)
+
+// Matrix is a 3x3 projective transformation matrix of 2D points, stored row by row.
+// A point (x, y) is transformed to ((m0*x + m1*y + m2) / w, (m3*x + m4*y + m5) / w) where w = m6*x + m7*y + m8.
+// Affine transformations have the last row (0, 0, 1).
+type Matrix [9]float64
+
+// Identity returns the identity transformation.
+func Identity() Matrix {
+	return Matrix{1, 0, 0, 0, 1, 0, 0, 0, 1}
+}
+
+// Translation returns a translation by (tx, ty).
+func Translation(tx, ty float64) Matrix {
+	return Matrix{1, 0, tx, 0, 1, ty, 0, 0, 1}
+}
+
+// Scaling returns a scaling by (sx, sy) around the origin.
+func Scaling(sx, sy float64) Matrix {
+	return Matrix{sx, 0, 0, 0, sy, 0, 0, 0, 1}
+}
+
+// Rotation returns a rotation by angle radians around the origin.
+// Positive angles rotate clockwise on screen, as the y axis of images points down.
+func Rotation(angle float64) Matrix {
+	sin, cos := math.Sincos(angle)
+	return Matrix{cos, -sin, 0, sin, cos, 0, 0, 0, 1}
+}
+
+// RotationAround returns a rotation by angle radians around the point (cx, cy).
+func RotationAround(angle, cx, cy float64) Matrix {
+	return Translation(cx, cy).Multiply(Rotation(angle)).Multiply(Translation(-cx, -cy))
+}
+
+// Multiply returns the matrix product m * n, the transformation that applies n first and then m.
+func (m Matrix) Multiply(n Matrix) Matrix {
+	var result Matrix
+	for row := 0; row < 3; row++ {
+		for column := 0; column < 3; column++ {
+			result[row*3+column] = m[row*3]*n[column] + m[row*3+1]*n[3+column] + m[row*3+2]*n[6+column]
+		}
+	}
+	return result
+}
+
+// Inverse returns the inverse transformation. It returns false if the matrix is singular.
+func (m Matrix) Inverse() (Matrix, bool) {
+	a, b, c, d, e, f, g, h, i := m[0], m[1], m[2], m[3], m[4], m[5], m[6], m[7], m[8]
+
+	det := a*(e*i-f*h) - b*(d*i-f*g) + c*(d*h-e*g)
+	if det == 0 {
+		return Matrix{}, false
+	}
+
+	invDet := 1 / det
+	return Matrix{
+		(e*i - f*h) * invDet, (c*h - b*i) * invDet, (b*f - c*e) * invDet,
+		(f*g - d*i) * invDet, (a*i - c*g) * invDet, (c*d - a*f) * invDet,
+		(d*h - e*g) * invDet, (b*g - a*h) * invDet, (a*e - b*d) * invDet,
+	}, true
+}
+
+// Transform returns the transformed point (x, y).
+func (m Matrix) Transform(x, y float64) (float64, float64) {
+	w := m.w(x, y)
+	return (m[0]*x + m[1]*y + m[2]) / w, (m[3]*x + m[4]*y + m[5]) / w
+}
+
+// w returns the homogeneous coordinate of the transformed point (x, y).
+func (m Matrix) w(x, y float64) float64 {
+	return m[6]*x + m[7]*y + m[8]
+}
+
+// QuadToQuad returns the perspective transformation that maps the four corners of the quadrilateral src
+// to the corresponding corners of dst. It returns false if any of the quadrilaterals is degenerate.
+func QuadToQuad(src, dst [4][2]float64) (Matrix, bool) {
+	srcToSquare, ok := squareToQuad(src).Inverse()
+	if !ok {
+		return Matrix{}, false
+	}
+
+	m := squareToQuad(dst).Multiply(srcToSquare)
+	if m[8] == 0 {
+		return Matrix{}, false
+	}
+	for i := range m {
+		m[i] /= m[8]
+	}
+	return m, true
+}
+
+// squareToQuad returns the perspective transformation mapping the corners (0, 0), (1, 0), (1, 1) and (0, 1)
+// of the unit square to the corners of q, see "Fundamentals of Texture Mapping and Image Warping" by Paul Heckbert.
+func squareToQuad(q [4][2]float64) Matrix {
+	x0, y0, x1, y1, x2, y2, x3, y3 := q[0][0], q[0][1], q[1][0], q[1][1], q[2][0], q[2][1], q[3][0], q[3][1]
+
+	sx, sy := x0-x1+x2-x3, y0-y1+y2-y3
+	if sx == 0 && sy == 0 {
+		return Matrix{x1 - x0, x2 - x1, x0, y1 - y0, y2 - y1, y0, 0, 0, 1}
+	}
+
+	dx1, dx2, dy1, dy2 := x1-x2, x3-x2, y1-y2, y3-y2
+	det := dx1*dy2 - dx2*dy1
+	if det == 0 {
+		return Matrix{}
+	}
+
+	g := (sx*dy2 - dx2*sy) / det
+	h := (dx1*sy - sx*dy1) / det
+	return Matrix{x1 - x0 + g*x1, x3 - x0 + h*x3, x0, y1 - y0 + g*y1, y3 - y0 + h*y3, y0, g, h, 1}
+}
+
+// Warp returns the image transformed by m (an affine or perspective transformation from image coordinates to
+// the coordinates of the result) as a new float image of the same type as img (see floatimage.NewLike) with bounds r.
+//
+// Each pixel of the result is sampled from the image with the given filter, at the position its center maps back to.
+// Pixels that map outside the image get the background color, and filtered pixels along the edges
+// of the image are blended with it. Pixels are not filtered over their whole footprint, so warps
+// that shrink the image by much more than half alias and are better preceded by a resample.Resize.
+func Warp(img image.Image, m Matrix, r image.Rectangle, filter sampler.Filter, background floatcolor.NRGBAF64) floatimage.FloatImage {
+	result := floatimage.NewRGBAF64WithBounds(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+	border := floatcolor.RGBAF64{R: background.R * background.A, G: background.G * background.A, B: background.B * background.A, A: background.A}
+
+	// A singular transformation maps the image onto a line or point, which covers no pixel of the result.
+	inverse, ok := m.Inverse()
+	if !ok {
+		floatimage.ForEachBand(r, 0, func(band image.Rectangle) {
+			for y := band.Min.Y; y < band.Max.Y; y++ {
+				for x := r.Min.X; x < r.Max.X; x++ {
+					result.SetRGBAF64(x, y, border)
+				}
+			}
+		})
+		return floatimage.FromRGBAF64(result, img)
+	}
+
+	s := sampler.New(img, filter, sampler.WrapBorder)
+	s.Border = border
+
+	// Source points on the other side of the horizon of a perspective transformation than the image center
+	// are mapped to the result "from behind" and are not part of the warped image.
+	bounds := img.Bounds()
+	centerW := m.w(float64(bounds.Min.X+bounds.Max.X)/2, float64(bounds.Min.Y+bounds.Max.Y)/2)
+
+	floatimage.ForEachBand(r, 0, func(band image.Rectangle) {
+		for y := band.Min.Y; y < band.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				c := s.Border
+				sx, sy := inverse.Transform(float64(x)+0.5, float64(y)+0.5)
+				if m.w(sx, sy)*centerW > 0 {
+					c = s.At(sx, sy)
+				}
+
+				i := result.PixOffset(x, y)
+				p := result.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+				p[0], p[1], p[2], p[3] = c.R, c.G, c.B, c.A
+			}
+		}
+	})
+
+	return floatimage.FromRGBAF64(result, img)
+}